@@ -0,0 +1,204 @@
+package lruchal
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// Scope is a permission granted to a Token
+type Scope string
+
+const (
+	ScopeRead  Scope = "read"  // get keys and stream changes
+	ScopeWrite Scope = "write" // put and remove keys
+	ScopeAdmin Scope = "admin" // manage namespaces, implies read and write
+)
+
+// Token grants the bearer of a secret access to the server's http api
+type Token struct {
+	Name        string   `json:"name"`  // identifies the token in logs
+	Token       string   `json:"token"` // the secret sent as "Authorization: Bearer {token}"
+	Scopes      []Scope  `json:"scopes"`
+	Namespaces  []string `json:"namespaces,omitempty"`   // optional, if set the token may only be used with these namespaces
+	KeyPrefixes []string `json:"key_prefixes,omitempty"` // optional, if set the token may only be used with keys beginning with one of these
+}
+
+// tokenFile is the format of ServerConfig.TokenFile
+type tokenFile struct {
+	Tokens []Token `json:"tokens"`
+}
+
+// LoadTokens reads tokens from a json file in the form {"tokens": [{"name": ..., "token": ..., "scopes": [...]}]}
+func LoadTokens(name string) ([]Token, error) {
+	b, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read \"%s\": %s", name, err)
+	}
+	tf := new(tokenFile)
+	if err := json.Unmarshal(b, tf); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal \"%s\": %s", name, err)
+	}
+	return tf.Tokens, nil
+}
+
+func (t *Token) hasScope(scope Scope) bool {
+	for _, s := range t.Scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *Token) allowsNamespace(name string) bool {
+	if len(t.Namespaces) == 0 {
+		return true
+	}
+	for _, ns := range t.Namespaces {
+		if ns == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *Token) allowsKey(key string) bool {
+	if len(t.KeyPrefixes) == 0 {
+		return true
+	}
+	for _, prefix := range t.KeyPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// tokenHash is the key tokens are looked up by, so the lookup does not leak how much of a secret was guessed
+type tokenHash [sha256.Size]byte
+
+func newTokenIndex(tokens []Token) (map[tokenHash]*Token, error) {
+	index := make(map[tokenHash]*Token, len(tokens))
+	for i := range tokens {
+		t := tokens[i]
+		if t.Token == "" {
+			return nil, fmt.Errorf("token \"%s\" has no secret", t.Name)
+		}
+		if len(t.Scopes) == 0 {
+			return nil, fmt.Errorf("token \"%s\" has no scopes", t.Name)
+		}
+		for _, s := range t.Scopes {
+			if s != ScopeRead && s != ScopeWrite && s != ScopeAdmin {
+				return nil, fmt.Errorf("token \"%s\" has unknown scope \"%s\"", t.Name, s)
+			}
+		}
+		h := tokenHash(sha256.Sum256([]byte(t.Token)))
+		if _, ok := index[h]; ok {
+			return nil, fmt.Errorf("token \"%s\" has the same secret as another token", t.Name)
+		}
+		index[h] = &t
+	}
+	return index, nil
+}
+
+type tokenContextKey struct{}
+
+// requestToken returns the token a request was authenticated with, or nil if authentication is disabled
+func requestToken(r *http.Request) *Token {
+	t, _ := r.Context().Value(tokenContextKey{}).(*Token)
+	return t
+}
+
+// authenticate returns the request with it's token attached, writing a 401 if the request does not carry a known
+// token.  Requests are always allowed if the server has no tokens.
+func (srv *Server) authenticate(w http.ResponseWriter, r *http.Request) (*http.Request, bool) {
+	if srv.tokens == nil {
+		return r, true
+	}
+	auth := r.Header.Get("Authorization")
+	if len(auth) > len("Bearer ") && strings.EqualFold(auth[:len("Bearer ")], "Bearer ") {
+		if t, ok := srv.tokens[sha256.Sum256([]byte(auth[len("Bearer "):]))]; ok {
+			return r.WithContext(context.WithValue(r.Context(), tokenContextKey{}, t)), true
+		}
+	}
+	w.Header().Set("WWW-Authenticate", `Bearer realm="lruchal"`)
+	writeError(w, http.StatusUnauthorized, ErrorCodeUnauthorized, "A valid bearer token is required")
+	return r, false
+}
+
+// authorize checks the request's token may use the route split from a namespace's path, writing a 403 if not.  The
+// key of a /put is only known once it's body is decoded, so is checked by put.
+func (srv *Server) authorize(w http.ResponseWriter, r *http.Request, ns string, split []string) bool {
+	t := requestToken(r)
+	if t == nil {
+		return true
+	}
+	scope := ScopeWrite
+	if r.Method == "GET" {
+		scope = ScopeRead
+	}
+	if !t.hasScope(scope) {
+		return forbid(w, t, fmt.Sprintf("requires the %s scope", scope))
+	}
+	if !t.allowsNamespace(ns) {
+		return forbid(w, t, fmt.Sprintf("may not use namespace \"%s\"", ns))
+	}
+	if len(t.KeyPrefixes) == 0 {
+		return true
+	}
+
+	switch split[1] {
+	case "get", "has", "remove", "key":
+		if len(split) == 3 && !t.allowsKey(split[2]) {
+			return forbid(w, t, fmt.Sprintf("may not use key \"%s\"", split[2]))
+		}
+		return true
+	case "put", "len", "stats":
+		return true
+	case "watch":
+		// the watched prefix must be within one of the token's
+		if prefix := r.URL.Query().Get("prefix"); !t.allowsKey(prefix) {
+			return forbid(w, t, fmt.Sprintf("may not watch prefix \"%s\"", prefix))
+		}
+		return true
+	}
+	// anything else may touch keys outside the token's prefixes
+	return forbid(w, t, "is restricted to key prefixes")
+}
+
+// authorizeAdmin checks the request's token may use the admin api for the namespace, or to list all namespaces if
+// name is empty, writing a 403 if not
+func (srv *Server) authorizeAdmin(w http.ResponseWriter, r *http.Request, name string) bool {
+	t := requestToken(r)
+	if t == nil {
+		return true
+	}
+	if !t.hasScope(ScopeAdmin) {
+		return forbid(w, t, "requires the admin scope")
+	}
+	if name == "" && len(t.Namespaces) > 0 {
+		return forbid(w, t, "is restricted to namespaces")
+	}
+	if name != "" && !t.allowsNamespace(name) {
+		return forbid(w, t, fmt.Sprintf("may not use namespace \"%s\"", name))
+	}
+	return true
+}
+
+// authorizeKey checks the request's token may use key, writing a 403 if not
+func authorizeKey(w http.ResponseWriter, r *http.Request, key string) bool {
+	if t := requestToken(r); t != nil && !t.allowsKey(key) {
+		return forbid(w, t, fmt.Sprintf("may not use key \"%s\"", key))
+	}
+	return true
+}
+
+func forbid(w http.ResponseWriter, t *Token, reason string) bool {
+	writeError(w, http.StatusForbidden, ErrorCodeForbidden, fmt.Sprintf("Token \"%s\" %s", t.Name, reason))
+	return false
+}
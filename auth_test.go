@@ -0,0 +1,176 @@
+package lruchal_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dcarbone/lruchal"
+)
+
+const testTokenFile = `{"tokens": [
+	{"name": "reader", "token": "read-secret", "scopes": ["read"]},
+	{"name": "sessions", "token": "sessions-secret", "scopes": ["read", "write"], "namespaces": ["sessions"], "key_prefixes": ["user:"]},
+	{"name": "admin", "token": "admin-secret", "scopes": ["admin"]}
+]}`
+
+func TestAuth(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lruchal")
+	if err != nil {
+		t.Logf("Unable to create temp dir: %s", err)
+		t.FailNow()
+	}
+	defer os.RemoveAll(dir)
+	tokenFile := filepath.Join(dir, "tokens.json")
+	if err := ioutil.WriteFile(tokenFile, []byte(testTokenFile), 0600); err != nil {
+		t.Logf("Unable to write token file: %s", err)
+		t.FailNow()
+	}
+
+	srv := newTestServer(t, &lruchal.ServerConfig{
+		Port:        lruchal.RandomPort,
+		BindAddress: "127.0.0.1",
+		Namespaces:  []lruchal.NamespaceConfig{{Name: "sessions"}},
+		TokenFile:   tokenFile,
+	})
+	if err := srv.Listen(); err != nil {
+		t.Logf("Unable to listen: %s", err)
+		t.FailNow()
+	}
+	go srv.Serve()
+	defer srv.Close()
+
+	newClient := func(t *testing.T, token, namespace string) *lruchal.Client {
+		client, err := lruchal.NewClient(&lruchal.ClientConfig{Address: srv.Addr().String(), Token: token, Namespace: namespace})
+		if err != nil {
+			t.Logf("Unable to create client: %s", err)
+			t.FailNow()
+		}
+		t.Cleanup(func() { client.Close() })
+		return client
+	}
+	expect := func(t *testing.T, err, expected error) {
+		if !errors.Is(err, expected) {
+			t.Logf("Expected %v, saw %v", expected, err)
+			t.FailNow()
+		}
+	}
+
+	t.Run("Unauthenticated", func(t *testing.T) {
+		_, err := newClient(t, "", "").Len()
+		expect(t, err, lruchal.ErrUnauthorized)
+		_, err = newClient(t, "wrong-secret", "").Len()
+		expect(t, err, lruchal.ErrUnauthorized)
+	})
+
+	t.Run("Scopes", func(t *testing.T) {
+		admin, reader := newClient(t, "admin-secret", ""), newClient(t, "read-secret", "")
+
+		expect(t, admin.Put(lruchal.Item{Key: "key1", Value: "value1", TTL: "1m"}), nil)
+		v, err := reader.Get("key1")
+		expect(t, err, nil)
+		if v != "value1" {
+			t.Logf("Expected value1, saw %v", v)
+			t.FailNow()
+		}
+		expect(t, reader.Put(lruchal.Item{Key: "key1", Value: "value2", TTL: "1m"}), lruchal.ErrForbidden)
+		_, err = reader.Remove("key1")
+		expect(t, err, lruchal.ErrForbidden)
+	})
+
+	t.Run("Restrictions", func(t *testing.T) {
+		sessions := newClient(t, "sessions-secret", "sessions")
+
+		expect(t, sessions.Put(lruchal.Item{Key: "user:1", Value: 1, TTL: "1m"}), nil)
+		_, err := sessions.Get("user:1")
+		expect(t, err, nil)
+		expect(t, sessions.Put(lruchal.Item{Key: "other", Value: 1, TTL: "1m"}), lruchal.ErrForbidden)
+		_, err = sessions.Get("other")
+		expect(t, err, lruchal.ErrForbidden)
+		_, err = sessions.InvalidateTag("tag1")
+		expect(t, err, lruchal.ErrForbidden)
+
+		_, err = newClient(t, "sessions-secret", "").Get("user:1")
+		expect(t, err, lruchal.ErrForbidden)
+	})
+
+	t.Run("Stats", func(t *testing.T) {
+		stats := func(token, path string) lruchal.ServerStats {
+			req, _ := http.NewRequest("GET", "http://"+srv.Addr().String()+path, nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Logf("Unable to get stats: %s", err)
+				t.FailNow()
+			}
+			defer resp.Body.Close()
+			var out lruchal.ServerStats
+			if err := json.NewDecoder(resp.Body).Decode(&out); err != nil || resp.StatusCode != http.StatusOK {
+				t.Logf("Unable to decode stats: %d %v", resp.StatusCode, err)
+				t.FailNow()
+			}
+			return out
+		}
+		if s := stats("admin-secret", "/stats"); len(s.Namespaces) != 2 {
+			t.Logf("Expected admin to see every namespace, saw %+v", s.Namespaces)
+			t.FailNow()
+		}
+		if s := stats("sessions-secret", "/ns/sessions/stats"); len(s.Namespaces) != 1 || s.Namespaces[0].Name != "sessions" {
+			t.Logf("Expected restricted token to only see it's namespace, saw %+v", s.Namespaces)
+			t.FailNow()
+		}
+	})
+
+	t.Run("Admin", func(t *testing.T) {
+		status := func(token string) int {
+			req, _ := http.NewRequest("GET", "http://"+srv.Addr().String()+"/admin/ns", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Logf("Unable to list namespaces: %s", err)
+				t.FailNow()
+			}
+			resp.Body.Close()
+			return resp.StatusCode
+		}
+		if code := status("admin-secret"); code != http.StatusOK {
+			t.Logf("Expected 200 for admin, saw %d", code)
+			t.FailNow()
+		}
+		if code := status("read-secret"); code != http.StatusForbidden {
+			t.Logf("Expected 403 for reader, saw %d", code)
+			t.FailNow()
+		}
+	})
+
+	t.Run("InvalidTokens", func(t *testing.T) {
+		for _, tokens := range [][]lruchal.Token{
+			{{Name: "empty", Scopes: []lruchal.Scope{lruchal.ScopeRead}}},
+			{{Name: "noscopes", Token: "secret"}},
+			{{Name: "unknown", Token: "secret", Scopes: []lruchal.Scope{"root"}}},
+			{{Name: "a", Token: "secret", Scopes: []lruchal.Scope{lruchal.ScopeRead}}, {Name: "b", Token: "secret", Scopes: []lruchal.Scope{lruchal.ScopeRead}}},
+		} {
+			if _, err := lruchal.NewServer(&lruchal.ServerConfig{Tokens: tokens}); err == nil {
+				t.Logf("Expected error with %+v", tokens)
+				t.FailNow()
+			}
+		}
+
+		tokens := []lruchal.Token{{Name: "reader", Token: "secret", Scopes: []lruchal.Scope{lruchal.ScopeRead}}}
+		for _, config := range []*lruchal.ServerConfig{
+			{Tokens: tokens, RESPPort: lruchal.RandomPort},
+			{Tokens: tokens, MemcachedPort: lruchal.RandomPort},
+			{Tokens: tokens, BinaryPort: lruchal.RandomPort},
+			{Tokens: tokens, GRPCPort: lruchal.RandomPort},
+		} {
+			if _, err := lruchal.NewServer(config); err == nil {
+				t.Logf("Expected error with tokens and an unauthenticated listener: %+v", config)
+				t.FailNow()
+			}
+		}
+	})
+}
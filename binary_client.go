@@ -0,0 +1,566 @@
+package lruchal
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultBinaryClientDialTimeout = 5 * time.Second
+
+	// binaryClientQueueSize is the number of requests that may be waiting to be written to the connection
+	binaryClientQueueSize = 128
+)
+
+var errBinaryClientClosed = errors.New("client closed")
+
+type BinaryClientConfig struct {
+	Address      string
+	DialTimeout  time.Duration
+	ReadTimeout  time.Duration    // default timeout for calls that do not modify the cache
+	WriteTimeout time.Duration    // default timeout for calls that modify the cache
+	Codec        Codec            // encoding used for values on the wire, defaults to json
+	UseNumber    bool             // if true and using the default json codec, numbers are decoded as json.Number
+	Namespace    string           // optional, if set all calls operate on this namespace rather than the default
+	TLS          *ClientTLSConfig // optional, if set the connection uses tls
+	Logger       Logger
+}
+
+func NewDefaultBinaryClientConfig() *BinaryClientConfig {
+	c := &BinaryClientConfig{
+		Address:      fmt.Sprintf("127.0.0.1:%d", DefaultBinaryPort),
+		DialTimeout:  DefaultBinaryClientDialTimeout,
+		ReadTimeout:  DefaultClientReadTimeout,
+		WriteTimeout: DefaultClientWriteTimeout,
+		Logger:       DefaultLogger("binary-client"),
+	}
+	return c
+}
+
+// BinaryClient talks to a server's binary protocol listener, offering the same calls as Client.  All calls share a
+// single connection, with requests from concurrent callers pipelined rather than waiting on one another.  If the
+// connection fails, calls in flight return an error and the next call will reconnect.  Calls are not retried, as
+// the server may or may not have seen them.
+type BinaryClient struct {
+	log Logger
+
+	addr         string
+	namespace    []byte
+	dialTimeout  time.Duration
+	readTimeout  time.Duration
+	writeTimeout time.Duration
+	codec        Codec
+	contentType  []byte
+	tls          *tls.Config
+
+	mu     *sync.Mutex
+	conn   *binaryClientConn
+	closed bool
+}
+
+func NewDefaultBinaryClient() (*BinaryClient, error) {
+	return newBinaryClient(NewDefaultBinaryClientConfig())
+}
+
+func NewBinaryClient(config *BinaryClientConfig) (*BinaryClient, error) {
+	return newBinaryClient(config)
+}
+
+func newBinaryClient(config *BinaryClientConfig) (*BinaryClient, error) {
+	def := NewDefaultBinaryClientConfig()
+	if config.Address != "" {
+		def.Address = config.Address
+	}
+	if config.DialTimeout > 0 {
+		def.DialTimeout = config.DialTimeout
+	}
+	if config.ReadTimeout > 0 {
+		def.ReadTimeout = config.ReadTimeout
+	}
+	if config.WriteTimeout > 0 {
+		def.WriteTimeout = config.WriteTimeout
+	}
+	if config.Logger != nil {
+		def.Logger = config.Logger
+	}
+	if config.Codec != nil {
+		def.Codec = config.Codec
+	} else {
+		def.Codec = &JSONCodec{UseNumber: config.UseNumber}
+	}
+
+	var tc *tls.Config
+	if config.TLS != nil {
+		var err error
+		if tc, err = config.TLS.tlsConfig(def.Address); err != nil {
+			return nil, fmt.Errorf("unable to configure tls: %s", err)
+		}
+	}
+
+	c := &BinaryClient{
+		log:          def.Logger,
+		tls:          tc,
+		addr:         def.Address,
+		dialTimeout:  def.DialTimeout,
+		readTimeout:  def.ReadTimeout,
+		writeTimeout: def.WriteTimeout,
+		codec:        def.Codec,
+		contentType:  []byte(def.Codec.ContentType()),
+		mu:           new(sync.Mutex),
+	}
+
+	if config.Namespace != "" && config.Namespace != DefaultNamespace {
+		c.namespace = []byte(config.Namespace)
+	}
+
+	return c, nil
+}
+
+// Close will close the client's connection.  Calls in flight return an error, and the client must not be used
+// afterwards.
+func (c *BinaryClient) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	if c.conn != nil {
+		c.conn.fail(errBinaryClientClosed)
+		c.conn = nil
+	}
+	return nil
+}
+
+// binaryClientConn is a single connection to the server, shared by all callers
+type binaryClientConn struct {
+	conn   net.Conn
+	writes chan *binaryFrame
+	done   chan struct{} // closed once the connection has failed
+
+	mu      *sync.Mutex
+	nextID  uint32
+	pending map[uint32]chan *binaryFrame
+	err     error
+}
+
+// connect returns the current connection, dialing a new one if there is none or the last has failed
+func (c *BinaryClient) connect(ctx context.Context) (*binaryClientConn, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil, errBinaryClientClosed
+	}
+	if c.conn != nil {
+		select {
+		case <-c.conn.done:
+		default:
+			return c.conn, nil
+		}
+	}
+
+	dialer := &net.Dialer{Timeout: c.dialTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", c.addr)
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect: %s", err)
+	}
+	if c.tls != nil {
+		tlsConn := tls.Client(conn, c.tls)
+		hctx, cancel := context.WithTimeout(ctx, c.dialTimeout)
+		err = tlsConn.HandshakeContext(hctx)
+		cancel()
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("unable to complete tls handshake: %s", err)
+		}
+		conn = tlsConn
+	}
+
+	bc := &binaryClientConn{
+		conn:    conn,
+		writes:  make(chan *binaryFrame, binaryClientQueueSize),
+		done:    make(chan struct{}),
+		mu:      new(sync.Mutex),
+		pending: make(map[uint32]chan *binaryFrame),
+	}
+	go bc.readLoop()
+	go bc.writeLoop()
+
+	c.conn = bc
+	return bc, nil
+}
+
+// fail closes the connection, causing every pending and future call on it to return err
+func (bc *binaryClientConn) fail(err error) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	if bc.err == nil {
+		bc.err = err
+		close(bc.done)
+		bc.conn.Close()
+	}
+}
+
+func (bc *binaryClientConn) readLoop() {
+	r := bufio.NewReader(bc.conn)
+	for {
+		resp, err := readBinaryFrame(r)
+		if err != nil {
+			bc.fail(fmt.Errorf("unable to read response: %s", err))
+			return
+		}
+
+		bc.mu.Lock()
+		ch, ok := bc.pending[resp.id]
+		delete(bc.pending, resp.id)
+		bc.mu.Unlock()
+
+		// responses to abandoned calls are dropped
+		if ok {
+			ch <- resp
+		}
+	}
+}
+
+// writeLoop writes queued requests, flushing once the queue is empty so that concurrent calls share syscalls
+func (bc *binaryClientConn) writeLoop() {
+	w := bufio.NewWriter(bc.conn)
+	for {
+		select {
+		case req := <-bc.writes:
+			if err := req.writeTo(w); err != nil {
+				bc.fail(fmt.Errorf("unable to write request: %s", err))
+				return
+			}
+			if len(bc.writes) == 0 {
+				if err := w.Flush(); err != nil {
+					bc.fail(fmt.Errorf("unable to write request: %s", err))
+					return
+				}
+			}
+		case <-bc.done:
+			return
+		}
+	}
+}
+
+func (bc *binaryClientConn) register(req *binaryFrame) (chan *binaryFrame, error) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	if bc.err != nil {
+		return nil, bc.err
+	}
+	bc.nextID++
+	req.id = bc.nextID
+	ch := make(chan *binaryFrame, 1)
+	bc.pending[req.id] = ch
+	return ch, nil
+}
+
+func (bc *binaryClientConn) unregister(id uint32) {
+	bc.mu.Lock()
+	delete(bc.pending, id)
+	bc.mu.Unlock()
+}
+
+func (bc *binaryClientConn) error() error {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	return bc.err
+}
+
+// do sends a request and waits for it's response, or for ctx or the timeout to expire
+func (c *BinaryClient) do(ctx context.Context, op uint16, timeout time.Duration, fields ...[]byte) (*binaryFrame, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	bc, err := c.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &binaryFrame{code: op, fields: append([][]byte{c.namespace}, fields...)}
+	if length := req.length(); length > binaryMaxFrameSize {
+		// checked here as failing to write it would fail every call sharing the connection
+		return nil, fmt.Errorf("%w: request of %d bytes exceeds maximum frame size of %d", ErrBodyTooLarge, length, binaryMaxFrameSize)
+	}
+	ch, err := bc.register(req)
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case bc.writes <- req:
+	case <-bc.done:
+		return nil, bc.error()
+	case <-ctx.Done():
+		bc.unregister(req.id)
+		return nil, ctx.Err()
+	}
+
+	select {
+	case resp := <-ch:
+		return resp, nil
+	case <-bc.done:
+		return nil, bc.error()
+	case <-ctx.Done():
+		bc.unregister(req.id)
+		return nil, ctx.Err()
+	}
+}
+
+// binaryServerError converts an unexpected response into a ServerError, as Client does for http responses
+func binaryServerError(resp *binaryFrame) error {
+	var body []byte
+	if len(resp.fields) > 0 {
+		body = resp.fields[0]
+	}
+	return newServerError(int(resp.code), body)
+}
+
+func (c *BinaryClient) Get(key string) (interface{}, error) {
+	return c.GetContext(context.Background(), key)
+}
+
+func (c *BinaryClient) GetContext(ctx context.Context, key string) (interface{}, error) {
+	var data interface{}
+	if err := c.GetIntoContext(ctx, key, &data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// GetInto will decode the value of key directly into v, which must be a pointer
+func (c *BinaryClient) GetInto(key string, v interface{}) error {
+	return c.GetIntoContext(context.Background(), key, v)
+}
+
+func (c *BinaryClient) GetIntoContext(ctx context.Context, key string, v interface{}) error {
+	resp, err := c.do(ctx, binaryOpGet, c.readTimeout, c.contentType, []byte(key))
+	if err != nil {
+		return err
+	}
+
+	if resp.code == 200 {
+		b, err := resp.field(0)
+		if err != nil {
+			return err
+		}
+		return c.decode(b, v)
+	}
+
+	return binaryServerError(resp)
+}
+
+// decode will unmarshal an encoded value into v using this client's codec
+func (c *BinaryClient) decode(b []byte, v interface{}) error {
+	if err := c.codec.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("unable to unmarshal data: %s", err)
+	}
+	return nil
+}
+
+func (c *BinaryClient) Put(item Item) error {
+	return c.PutContext(context.Background(), item)
+}
+
+func (c *BinaryClient) PutContext(ctx context.Context, item Item) error {
+	// an empty ttl is left to the server, which will apply the namespace's default ttl if it has one
+	if item.TTL != "" {
+		if _, err := time.ParseDuration(item.TTL); err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidTTL, err)
+		}
+	}
+
+	b, err := c.codec.Marshal(item)
+	if err != nil {
+		return fmt.Errorf("unable to serialize: %s", err)
+	}
+
+	resp, err := c.do(ctx, binaryOpPut, c.writeTimeout, c.contentType, b)
+	if err != nil {
+		return err
+	}
+
+	if resp.code == 204 {
+		return nil
+	}
+
+	return binaryServerError(resp)
+}
+
+// Has will return true if the server has the specified key
+func (c *BinaryClient) Has(key string) (bool, error) {
+	return c.HasContext(context.Background(), key)
+}
+
+func (c *BinaryClient) HasContext(ctx context.Context, key string) (bool, error) {
+	resp, err := c.do(ctx, binaryOpHas, c.readTimeout, []byte(key))
+	if err != nil {
+		return false, err
+	}
+
+	switch resp.code {
+	case 204:
+		return true, nil
+	case 404:
+		return false, nil
+	}
+
+	return false, binaryServerError(resp)
+}
+
+// Remove will attempt to remove a key from the server, returning it's value.  Returns ErrNotFound if key not found.
+func (c *BinaryClient) Remove(key string) (interface{}, error) {
+	return c.RemoveContext(context.Background(), key)
+}
+
+func (c *BinaryClient) RemoveContext(ctx context.Context, key string) (interface{}, error) {
+	resp, err := c.do(ctx, binaryOpRemove, c.writeTimeout, c.contentType, []byte(key))
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.code == 200 {
+		b, err := resp.field(0)
+		if err != nil {
+			return nil, err
+		}
+		var data interface{}
+		if err := c.decode(b, &data); err != nil {
+			return nil, err
+		}
+		return data, nil
+	}
+
+	return nil, binaryServerError(resp)
+}
+
+// InvalidateTag will remove every key put with tag, returning the removed keys
+func (c *BinaryClient) InvalidateTag(tag string) ([]string, error) {
+	return c.InvalidateTagContext(context.Background(), tag)
+}
+
+func (c *BinaryClient) InvalidateTagContext(ctx context.Context, tag string) ([]string, error) {
+	resp, err := c.do(ctx, binaryOpInvalidateTag, c.writeTimeout, c.contentType, []byte(tag))
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.code == 200 {
+		b, err := resp.field(0)
+		if err != nil {
+			return nil, err
+		}
+		var keys []string
+		if err := c.decode(b, &keys); err != nil {
+			return nil, err
+		}
+		return keys, nil
+	}
+
+	return nil, binaryServerError(resp)
+}
+
+// Len will return the number of keys currently held by the server, including expired keys not yet expunged
+func (c *BinaryClient) Len() (int, error) {
+	return c.LenContext(context.Background())
+}
+
+func (c *BinaryClient) LenContext(ctx context.Context) (int, error) {
+	resp, err := c.do(ctx, binaryOpLen, c.readTimeout)
+	if err != nil {
+		return 0, err
+	}
+
+	if resp.code == 200 {
+		b, err := resp.field(0)
+		if err != nil {
+			return 0, err
+		}
+		if len(b) != 8 {
+			return 0, fmt.Errorf("%w: expected 8 byte len, saw %d bytes", errBinaryProtocol, len(b))
+		}
+		return int(binary.BigEndian.Uint64(b)), nil
+	}
+
+	return 0, binaryServerError(resp)
+}
+
+// Expunge will ask the server to remove all expired keys
+func (c *BinaryClient) Expunge() error {
+	return c.ExpungeContext(context.Background())
+}
+
+func (c *BinaryClient) ExpungeContext(ctx context.Context) error {
+	resp, err := c.do(ctx, binaryOpExpunge, c.writeTimeout)
+	if err != nil {
+		return err
+	}
+
+	if resp.code == 204 {
+		return nil
+	}
+
+	return binaryServerError(resp)
+}
+
+// PutBytes will store data verbatim under key, to be returned with the provided content type by GetBytes.  A ttl of 0
+// uses the default ttl of the client's namespace.
+func (c *BinaryClient) PutBytes(key string, data []byte, contentType string, ttl time.Duration) error {
+	return c.PutBytesContext(context.Background(), key, data, contentType, ttl)
+}
+
+func (c *BinaryClient) PutBytesContext(ctx context.Context, key string, data []byte, contentType string, ttl time.Duration) error {
+	if ttl < 0 {
+		return fmt.Errorf("%w: ttl cannot be negative", ErrInvalidTTL)
+	}
+	if contentType == "" {
+		contentType = DefaultBinaryContentType
+	}
+
+	var ttlField []byte
+	if ttl > 0 {
+		ttlField = []byte(ttl.String())
+	}
+
+	resp, err := c.do(ctx, binaryOpPutBytes, c.writeTimeout, []byte(key), ttlField, []byte(contentType), data)
+	if err != nil {
+		return err
+	}
+
+	if resp.code == 204 {
+		return nil
+	}
+
+	return binaryServerError(resp)
+}
+
+// GetBytes will return the value of key along with it's content type.  Values stored with Put are returned as json.
+func (c *BinaryClient) GetBytes(key string) ([]byte, string, error) {
+	return c.GetBytesContext(context.Background(), key)
+}
+
+func (c *BinaryClient) GetBytesContext(ctx context.Context, key string) ([]byte, string, error) {
+	resp, err := c.do(ctx, binaryOpGetBytes, c.readTimeout, []byte(key))
+	if err != nil {
+		return nil, "", err
+	}
+
+	if resp.code == 200 {
+		if len(resp.fields) != 2 {
+			return nil, "", fmt.Errorf("%w: expected 2 fields in response, saw %d", errBinaryProtocol, len(resp.fields))
+		}
+		return resp.fields[1], string(resp.fields[0]), nil
+	}
+
+	return nil, "", binaryServerError(resp)
+}
@@ -0,0 +1,338 @@
+package lruchal_test
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dcarbone/lruchal"
+)
+
+var (
+	binaryTestServerOnce = new(sync.Once)
+	binaryTestServer     *lruchal.Server
+	binaryTestServerErr  error
+)
+
+// startBinaryTestServer starts a single server shared by the binary client tests and benchmarks, with both it's http
+// and binary listeners open
+func startBinaryTestServer(tb testing.TB) {
+	binaryTestServerOnce.Do(func() {
+		srv, err := lruchal.NewServer(&lruchal.ServerConfig{
+			Port:        lruchal.RandomPort,
+			BinaryPort:  lruchal.RandomPort,
+			BindAddress: "127.0.0.1",
+			CacheSize:   10000,
+			Namespaces:  []lruchal.NamespaceConfig{{Name: "binary", DefaultTTL: time.Minute}},
+			Logger:      log.New(ioutil.Discard, "", 0),
+		})
+		if err == nil {
+			err = srv.Listen()
+		}
+		if err == nil {
+			go srv.Serve()
+		}
+		binaryTestServer, binaryTestServerErr = srv, err
+	})
+	if binaryTestServerErr != nil {
+		tb.Logf("Unable to start server: %s", binaryTestServerErr)
+		tb.FailNow()
+	}
+}
+
+func newTestBinaryClient(tb testing.TB, config *lruchal.BinaryClientConfig) *lruchal.BinaryClient {
+	startBinaryTestServer(tb)
+	if config == nil {
+		config = new(lruchal.BinaryClientConfig)
+	}
+	config.Address = binaryTestServer.BinaryAddr().String()
+	client, err := lruchal.NewBinaryClient(config)
+	if err != nil {
+		tb.Logf("Unable to create binary client: %s", err)
+		tb.FailNow()
+	}
+	return client
+}
+
+func newTestHTTPClient(tb testing.TB) *lruchal.Client {
+	startBinaryTestServer(tb)
+	client, err := lruchal.NewClient(&lruchal.ClientConfig{Address: binaryTestServer.Addr().String()})
+	if err != nil {
+		tb.Logf("Unable to create client: %s", err)
+		tb.FailNow()
+	}
+	return client
+}
+
+func TestBinaryClient(t *testing.T) {
+	t.Run("RoundTrip", func(t *testing.T) {
+		client := newTestBinaryClient(t, &lruchal.BinaryClientConfig{UseNumber: true})
+		defer client.Close()
+
+		if err := client.Put(lruchal.Item{Key: "binary-key1", Value: map[string]interface{}{"a": 1}, TTL: "1m"}); err != nil {
+			t.Logf("Unable to put: %s", err)
+			t.FailNow()
+		}
+
+		var v struct{ A int }
+		if err := client.GetInto("binary-key1", &v); err != nil || v.A != 1 {
+			t.Logf("Expected {A: 1}, saw %+v (err=%v)", v, err)
+			t.FailNow()
+		}
+		if ok, err := client.Has("binary-key1"); err != nil || !ok {
+			t.Logf("Expected Has to return true, saw %t (err=%v)", ok, err)
+			t.FailNow()
+		}
+		if l, err := client.Len(); err != nil || l < 1 {
+			t.Logf("Expected Len of at least 1, saw %d (err=%v)", l, err)
+			t.FailNow()
+		}
+		if _, err := client.Remove("binary-key1"); err != nil {
+			t.Logf("Unable to remove: %s", err)
+			t.FailNow()
+		}
+		if _, err := client.Get("binary-key1"); !errors.Is(err, lruchal.ErrNotFound) {
+			t.Logf("Expected ErrNotFound, saw %v", err)
+			t.FailNow()
+		}
+		if ok, err := client.Has("binary-key1"); err != nil || ok {
+			t.Logf("Expected Has to return false, saw %t (err=%v)", ok, err)
+			t.FailNow()
+		}
+		if err := client.Expunge(); err != nil {
+			t.Logf("Unable to expunge: %s", err)
+			t.FailNow()
+		}
+	})
+
+	t.Run("SharedWithHTTP", func(t *testing.T) {
+		client := newTestBinaryClient(t, nil)
+		defer client.Close()
+		httpClient := newTestHTTPClient(t)
+		defer httpClient.Close()
+
+		if err := httpClient.Put(lruchal.Item{Key: "binary-key2", Value: "value2", TTL: "1m"}); err != nil {
+			t.Logf("Unable to put: %s", err)
+			t.FailNow()
+		}
+		if v, err := client.Get("binary-key2"); err != nil || v != "value2" {
+			t.Logf("Expected \"value2\", saw %v (err=%v)", v, err)
+			t.FailNow()
+		}
+
+		if err := client.PutBytes("binary-key3", []byte("<p>hi</p>"), "text/html", time.Minute); err != nil {
+			t.Logf("Unable to put bytes: %s", err)
+			t.FailNow()
+		}
+		if b, ct, err := httpClient.GetBytes("binary-key3"); err != nil || string(b) != "<p>hi</p>" || ct != "text/html" {
+			t.Logf("Expected html, saw %q %q (err=%v)", b, ct, err)
+			t.FailNow()
+		}
+		if b, ct, err := client.GetBytes("binary-key3"); err != nil || string(b) != "<p>hi</p>" || ct != "text/html" {
+			t.Logf("Expected html, saw %q %q (err=%v)", b, ct, err)
+			t.FailNow()
+		}
+	})
+
+	t.Run("Tags", func(t *testing.T) {
+		client := newTestBinaryClient(t, nil)
+		defer client.Close()
+
+		if err := client.Put(lruchal.Item{Key: "binary-tagged", Value: 1, TTL: "1m", Tags: []string{"binary-tag"}}); err != nil {
+			t.Logf("Unable to put: %s", err)
+			t.FailNow()
+		}
+		keys, err := client.InvalidateTag("binary-tag")
+		if err != nil || len(keys) != 1 || keys[0] != "binary-tagged" {
+			t.Logf("Expected [binary-tagged], saw %v (err=%v)", keys, err)
+			t.FailNow()
+		}
+	})
+
+	t.Run("Namespace", func(t *testing.T) {
+		client := newTestBinaryClient(t, &lruchal.BinaryClientConfig{Namespace: "binary"})
+		defer client.Close()
+		def := newTestBinaryClient(t, nil)
+		defer def.Close()
+
+		// the namespace's default ttl applies
+		if err := client.Put(lruchal.Item{Key: "binary-key4", Value: "value4"}); err != nil {
+			t.Logf("Unable to put: %s", err)
+			t.FailNow()
+		}
+		if ok, _ := def.Has("binary-key4"); ok {
+			t.Log("Expected key to not be visible in default namespace")
+			t.FailNow()
+		}
+
+		missing := newTestBinaryClient(t, &lruchal.BinaryClientConfig{Namespace: "missing"})
+		defer missing.Close()
+		if _, err := missing.Get("binary-key4"); !errors.Is(err, lruchal.ErrNotFound) {
+			t.Logf("Expected ErrNotFound for missing namespace, saw %v", err)
+			t.FailNow()
+		}
+	})
+
+	t.Run("Pipelining", func(t *testing.T) {
+		client := newTestBinaryClient(t, nil)
+		defer client.Close()
+
+		wg := new(sync.WaitGroup)
+		errs := make(chan error, 100)
+		for i := 0; i < 100; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				key := fmt.Sprintf("binary-pipelined-%d", i)
+				if err := client.Put(lruchal.Item{Key: key, Value: key, TTL: "1m"}); err != nil {
+					errs <- err
+					return
+				}
+				if v, err := client.Get(key); err != nil {
+					errs <- err
+				} else if v != key {
+					errs <- fmt.Errorf("expected %q, saw %v", key, v)
+				}
+			}(i)
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			t.Logf("Pipelined call failed: %s", err)
+			t.FailNow()
+		}
+	})
+
+	t.Run("OversizedRequest", func(t *testing.T) {
+		client := newTestBinaryClient(t, nil)
+		defer client.Close()
+
+		if err := client.Put(lruchal.Item{Key: "binary-key6", Value: 1, TTL: "1m"}); err != nil {
+			t.Logf("Unable to put: %s", err)
+			t.FailNow()
+		}
+		err := client.PutBytes("binary-key7", make([]byte, 64<<20), "application/octet-stream", time.Minute)
+		if !errors.Is(err, lruchal.ErrBodyTooLarge) {
+			t.Logf("Expected ErrBodyTooLarge, saw %v", err)
+			t.FailNow()
+		}
+		// the connection is shared by every call, so must survive the oversized one
+		if v, err := client.Get("binary-key6"); err != nil || v != float64(1) {
+			t.Logf("Expected 1 after oversized request, saw %v (err=%v)", v, err)
+			t.FailNow()
+		}
+	})
+
+	t.Run("TruncatedFrame", func(t *testing.T) {
+		startBinaryTestServer(t)
+		conn, err := net.Dial("tcp", binaryTestServer.BinaryAddr().String())
+		if err != nil {
+			t.Logf("Unable to dial: %s", err)
+			t.FailNow()
+		}
+		defer conn.Close()
+
+		// a header claiming a frame near the maximum size, followed by far less data
+		header := make([]byte, 10)
+		binary.BigEndian.PutUint32(header[0:4], 60<<20)
+		binary.BigEndian.PutUint32(header[4:8], 1)
+		binary.BigEndian.PutUint16(header[8:10], 1)
+		conn.Write(append(header, 1, 2, 3, 4))
+		conn.(*net.TCPConn).CloseWrite()
+
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		if n, err := conn.Read(make([]byte, 1)); err != io.EOF {
+			t.Logf("Expected server to close the connection, saw %d bytes (err=%v)", n, err)
+			t.FailNow()
+		}
+	})
+
+	t.Run("InvalidTTL", func(t *testing.T) {
+		client := newTestBinaryClient(t, nil)
+		defer client.Close()
+
+		if err := client.Put(lruchal.Item{Key: "binary-key5", Value: 1, TTL: "nope"}); !errors.Is(err, lruchal.ErrInvalidTTL) {
+			t.Logf("Expected ErrInvalidTTL, saw %v", err)
+			t.FailNow()
+		}
+	})
+}
+
+func benchmarkGet(b *testing.B, get func(key string) (interface{}, error)) {
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := get("bench-key"); err != nil {
+			b.Logf("Unable to get: %s", err)
+			b.FailNow()
+		}
+	}
+}
+
+func benchmarkGetParallel(b *testing.B, get func(key string) (interface{}, error)) {
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := get("bench-key"); err != nil {
+				b.Errorf("Unable to get: %s", err)
+				return
+			}
+		}
+	})
+}
+
+func benchmarkPut(b *testing.B, put func(item lruchal.Item) error) {
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := put(lruchal.Item{Key: fmt.Sprintf("bench-key-%d", i%1000), Value: i, TTL: "1m"}); err != nil {
+			b.Logf("Unable to put: %s", err)
+			b.FailNow()
+		}
+	}
+}
+
+func BenchmarkClientGet(b *testing.B) {
+	client := newTestHTTPClient(b)
+	defer client.Close()
+	client.Put(lruchal.Item{Key: "bench-key", Value: "value", TTL: "1h"})
+	benchmarkGet(b, client.Get)
+}
+
+func BenchmarkBinaryClientGet(b *testing.B) {
+	client := newTestBinaryClient(b, nil)
+	defer client.Close()
+	client.Put(lruchal.Item{Key: "bench-key", Value: "value", TTL: "1h"})
+	benchmarkGet(b, client.Get)
+}
+
+func BenchmarkClientGetParallel(b *testing.B) {
+	client := newTestHTTPClient(b)
+	defer client.Close()
+	client.Put(lruchal.Item{Key: "bench-key", Value: "value", TTL: "1h"})
+	benchmarkGetParallel(b, client.Get)
+}
+
+func BenchmarkBinaryClientGetParallel(b *testing.B) {
+	client := newTestBinaryClient(b, nil)
+	defer client.Close()
+	client.Put(lruchal.Item{Key: "bench-key", Value: "value", TTL: "1h"})
+	benchmarkGetParallel(b, client.Get)
+}
+
+func BenchmarkClientPut(b *testing.B) {
+	client := newTestHTTPClient(b)
+	defer client.Close()
+	benchmarkPut(b, client.Put)
+}
+
+func BenchmarkBinaryClientPut(b *testing.B) {
+	client := newTestBinaryClient(b, nil)
+	defer client.Close()
+	benchmarkPut(b, client.Put)
+}
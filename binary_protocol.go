@@ -0,0 +1,378 @@
+package lruchal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+)
+
+// The binary protocol is a compact alternative to the http api.  Every request and response is a single frame:
+//
+//	uint32  length of the remainder of the frame
+//	uint32  request id, chosen by the client and echoed in the response
+//	uint16  op for requests, http status code for responses
+//	fields  zero or more of: uint32 length followed by that many bytes
+//
+// All integers are big endian.  Requests are answered in the order they are read, but as every response carries the
+// id of it's request clients may have any number of requests in flight on one connection.  Error responses carry a
+// single field holding an ErrorResponse encoded as json, as in the body of an http error.
+const (
+	DefaultBinaryPort = 8183
+
+	binaryHeaderLen    = 10
+	binaryMaxFrameSize = 64 << 20
+
+	// binaryReadChunk is the most allocated for a frame's body before it's data has actually arrived, so a header
+	// claiming a large frame cannot by itself make the reader allocate it
+	binaryReadChunk = 64 << 10
+)
+
+// Request ops and their fields.  The first field of every request is the namespace, which may be empty to use the
+// default namespace.
+const (
+	binaryOpGet           uint16 = iota + 1 // namespace, accept, key -> value
+	binaryOpPut                             // namespace, content type, item
+	binaryOpHas                             // namespace, key
+	binaryOpRemove                          // namespace, accept, key -> value
+	binaryOpInvalidateTag                   // namespace, accept, tag -> keys
+	binaryOpLen                             // namespace -> uint64 len
+	binaryOpExpunge                         // namespace
+	binaryOpGetBytes                        // namespace, key -> content type, data
+	binaryOpPutBytes                        // namespace, key, ttl, content type, data, tags...
+)
+
+var errBinaryProtocol = errors.New("protocol error")
+
+// binaryFrame is a single request or response
+type binaryFrame struct {
+	id     uint32
+	code   uint16
+	fields [][]byte
+}
+
+// readBinaryFrame reads the next frame from r.  The returned fields share a single buffer owned by the frame.
+func readBinaryFrame(r io.Reader) (*binaryFrame, error) {
+	var header [binaryHeaderLen]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+	length := binary.BigEndian.Uint32(header[0:4])
+	if length < binaryHeaderLen-4 || length > binaryMaxFrameSize {
+		return nil, fmt.Errorf("%w: invalid frame length %d", errBinaryProtocol, length)
+	}
+
+	f := &binaryFrame{
+		id:   binary.BigEndian.Uint32(header[4:8]),
+		code: binary.BigEndian.Uint16(header[8:10]),
+	}
+
+	n := int64(length - (binaryHeaderLen - 4))
+	buf := new(bytes.Buffer)
+	if n > binaryReadChunk {
+		buf.Grow(binaryReadChunk)
+	} else {
+		buf.Grow(int(n))
+	}
+	if _, err := io.CopyN(buf, r, n); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	body := buf.Bytes()
+	for len(body) > 0 {
+		if len(body) < 4 {
+			return f, fmt.Errorf("%w: truncated field length", errBinaryProtocol)
+		}
+		l := binary.BigEndian.Uint32(body)
+		if uint64(l) > uint64(len(body)-4) {
+			return f, fmt.Errorf("%w: field length %d exceeds frame", errBinaryProtocol, l)
+		}
+		f.fields = append(f.fields, body[4:4+l:4+l])
+		body = body[4+l:]
+	}
+	return f, nil
+}
+
+// length returns the value of the frame's length prefix
+func (f *binaryFrame) length() int {
+	length := binaryHeaderLen - 4
+	for _, field := range f.fields {
+		length += 4 + len(field)
+	}
+	return length
+}
+
+// writeTo writes the frame to w in a single call
+func (f *binaryFrame) writeTo(w io.Writer) error {
+	length := f.length()
+	if length > binaryMaxFrameSize {
+		return fmt.Errorf("%w: frame of %d bytes exceeds maximum of %d", errBinaryProtocol, length, binaryMaxFrameSize)
+	}
+
+	b := make([]byte, 4+length)
+	binary.BigEndian.PutUint32(b[0:4], uint32(length))
+	binary.BigEndian.PutUint32(b[4:8], f.id)
+	binary.BigEndian.PutUint16(b[8:10], f.code)
+	off := binaryHeaderLen
+	for _, field := range f.fields {
+		binary.BigEndian.PutUint32(b[off:], uint32(len(field)))
+		off += 4
+		off += copy(b[off:], field)
+	}
+	_, err := w.Write(b)
+	return err
+}
+
+// field returns the i'th field of the frame, or an error if there are too few
+func (f *binaryFrame) field(i int) ([]byte, error) {
+	if i >= len(f.fields) {
+		return nil, fmt.Errorf("%w: expected at least %d fields in frame, saw %d", errBinaryProtocol, i+1, len(f.fields))
+	}
+	return f.fields[i], nil
+}
+
+// serveBinary accepts binary protocol connections on l until it is closed
+func (srv *Server) serveBinary(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go srv.handleBinary(conn)
+	}
+}
+
+func (srv *Server) handleBinary(conn net.Conn) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+
+	for {
+		req, err := readBinaryFrame(r)
+		if err != nil {
+			if errors.Is(err, errBinaryProtocol) {
+				// the client is not speaking the protocol, so the offending request is answered and the connection closed
+				resp := binaryError(http.StatusBadRequest, ErrorCodeInvalidBody, err.Error())
+				if req != nil {
+					resp.id = req.id
+				}
+				resp.writeTo(w)
+				w.Flush()
+			} else if err != io.EOF {
+				srv.log.Printf("binary: unable to read from %s: %s", conn.RemoteAddr(), err)
+			}
+			return
+		}
+
+		resp := srv.execBinary(req)
+		resp.id = req.id
+		if err := resp.writeTo(w); err != nil {
+			srv.log.Printf("binary: unable to write to %s: %s", conn.RemoteAddr(), err)
+			return
+		}
+
+		// responses to pipelined requests are buffered until there are no more requests waiting to be read
+		if r.Buffered() == 0 {
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// binaryError builds an error response with the same body as an http error
+func binaryError(status int, code, message string) *binaryFrame {
+	b, _ := json.Marshal(ErrorResponse{Code: code, Message: message})
+	return &binaryFrame{code: uint16(status), fields: [][]byte{b}}
+}
+
+// binaryStoreError returns the error response for an error returned by store, with the same status and code as http
+func binaryStoreError(err error) *binaryFrame {
+	if errors.Is(err, ErrQuotaExceeded) {
+		return binaryError(http.StatusInsufficientStorage, ErrorCodeQuota, err.Error())
+	}
+	return binaryError(http.StatusInternalServerError, ErrorCodeStoreFailed, err.Error())
+}
+
+// binaryArity returns an error response if req does not have exactly n fields
+func binaryArity(req *binaryFrame, n int) *binaryFrame {
+	if len(req.fields) != n {
+		return binaryError(http.StatusBadRequest, ErrorCodeInvalidBody, fmt.Sprintf("Expected %d fields, saw %d", n, len(req.fields)))
+	}
+	return nil
+}
+
+// binaryValue encodes v with the codec negotiated from accept
+func binaryValue(accept string, v interface{}) *binaryFrame {
+	b, err := negotiateCodec(accept).Marshal(v)
+	if err != nil {
+		return binaryError(http.StatusUnprocessableEntity, ErrorCodeUnprocessable, fmt.Sprintf("Unable to marshal value: %s", err))
+	}
+	return &binaryFrame{code: http.StatusOK, fields: [][]byte{b}}
+}
+
+// execBinary runs a single request, returning it's response
+func (srv *Server) execBinary(req *binaryFrame) *binaryFrame {
+	if len(req.fields) == 0 {
+		return binaryError(http.StatusBadRequest, ErrorCodeInvalidBody, "Missing namespace field")
+	}
+
+	name := string(req.fields[0])
+	if name == "" {
+		name = DefaultNamespace
+	}
+	ns, ok := srv.namespace(name)
+	if !ok {
+		return binaryError(http.StatusNotFound, ErrorCodeNotFound, fmt.Sprintf("Namespace \"%s\" not found", name))
+	}
+
+	switch req.code {
+	case binaryOpGet:
+		if resp := binaryArity(req, 3); resp != nil {
+			return resp
+		}
+		key := string(req.fields[2])
+		if value := srv.load(ns, key); value != nil {
+			return binaryValue(string(req.fields[1]), value)
+		}
+		return binaryError(http.StatusNotFound, ErrorCodeNotFound, fmt.Sprintf("Key \"%s\" not found", key))
+
+	case binaryOpPut:
+		if resp := binaryArity(req, 3); resp != nil {
+			return resp
+		}
+		return srv.binaryPut(ns, string(req.fields[1]), req.fields[2])
+
+	case binaryOpHas:
+		if resp := binaryArity(req, 2); resp != nil {
+			return resp
+		}
+		key := string(req.fields[1])
+		if ns.cache.Has(key) {
+			return &binaryFrame{code: http.StatusNoContent}
+		}
+		return binaryError(http.StatusNotFound, ErrorCodeNotFound, fmt.Sprintf("Key \"%s\" not found", key))
+
+	case binaryOpRemove:
+		if resp := binaryArity(req, 3); resp != nil {
+			return resp
+		}
+		key := string(req.fields[2])
+		if value := srv.unload(ns, key); value != nil {
+			return binaryValue(string(req.fields[1]), value)
+		}
+		return binaryError(http.StatusNotFound, ErrorCodeNotFound, fmt.Sprintf("Key \"%s\" not found", key))
+
+	case binaryOpInvalidateTag:
+		if resp := binaryArity(req, 3); resp != nil {
+			return resp
+		}
+		removed := ns.cache.InvalidateTag(string(req.fields[2]))
+		keys := make([]string, len(removed))
+		for i, key := range removed {
+			keys[i] = remoteKey(key)
+		}
+		return binaryValue(string(req.fields[1]), keys)
+
+	case binaryOpLen:
+		if resp := binaryArity(req, 1); resp != nil {
+			return resp
+		}
+		b := make([]byte, 8)
+		binary.BigEndian.PutUint64(b, uint64(ns.cache.Len()))
+		return &binaryFrame{code: http.StatusOK, fields: [][]byte{b}}
+
+	case binaryOpExpunge:
+		if resp := binaryArity(req, 1); resp != nil {
+			return resp
+		}
+		ns.cache.Expunge()
+		return &binaryFrame{code: http.StatusNoContent}
+
+	case binaryOpGetBytes:
+		if resp := binaryArity(req, 2); resp != nil {
+			return resp
+		}
+		key := string(req.fields[1])
+		switch value := srv.load(ns, key).(type) {
+		case nil:
+			return binaryError(http.StatusNotFound, ErrorCodeNotFound, fmt.Sprintf("Key \"%s\" not found", key))
+		case *BinaryValue:
+			return &binaryFrame{code: http.StatusOK, fields: [][]byte{[]byte(value.ContentType), value.Data}}
+		default:
+			resp := binaryValue(ContentTypeJSON, value)
+			if resp.code == http.StatusOK {
+				resp.fields = [][]byte{[]byte(ContentTypeJSON), resp.fields[0]}
+			}
+			return resp
+		}
+
+	case binaryOpPutBytes:
+		if len(req.fields) < 5 {
+			return binaryError(http.StatusBadRequest, ErrorCodeInvalidBody, fmt.Sprintf("Expected at least 5 fields, saw %d", len(req.fields)))
+		}
+		return srv.binaryPutBytes(ns, req.fields[1:])
+	}
+
+	return binaryError(http.StatusNotFound, ErrorCodeUnknownRoute, fmt.Sprintf("Unknown op %d", req.code))
+}
+
+func (srv *Server) binaryPut(ns *namespace, contentType string, b []byte) *binaryFrame {
+	codec, ok := codecForContentType(contentType)
+	if !ok {
+		return binaryError(http.StatusUnsupportedMediaType, ErrorCodeUnsupported, fmt.Sprintf("Unsupported content type \"%s\"", contentType))
+	}
+
+	item := new(Item)
+	if err := codec.Unmarshal(b, item); err != nil {
+		return binaryError(http.StatusUnprocessableEntity, ErrorCodeInvalidBody, fmt.Sprintf("Unable to unmarshal item: %s", err))
+	}
+
+	duration, err := ns.ttl(item.TTL)
+	if err != nil {
+		return binaryError(http.StatusNotAcceptable, ErrorCodeInvalidTTL, fmt.Sprintf("Invalid TTL format specified: %s", err))
+	}
+
+	if err := srv.store(ns, item.Key, item.Value, duration, item.Tags); err != nil {
+		return binaryStoreError(err)
+	}
+	return &binaryFrame{code: http.StatusNoContent}
+}
+
+// binaryPutBytes handles the fields of a binaryOpPutBytes request following the namespace
+func (srv *Server) binaryPutBytes(ns *namespace, fields [][]byte) *binaryFrame {
+	key := string(fields[0])
+
+	duration, err := ns.ttl(string(fields[1]))
+	if err != nil {
+		return binaryError(http.StatusNotAcceptable, ErrorCodeInvalidTTL, fmt.Sprintf("Invalid TTL format specified: %s", err))
+	}
+
+	value := &BinaryValue{
+		ContentType: string(fields[2]),
+		Data:        fields[3],
+	}
+	if value.ContentType == "" {
+		value.ContentType = DefaultBinaryContentType
+	}
+
+	var tags []string
+	for _, tag := range fields[4:] {
+		if len(tag) > 0 {
+			tags = append(tags, string(tag))
+		}
+	}
+
+	if err := srv.store(ns, key, value, duration, tags); err != nil {
+		return binaryStoreError(err)
+	}
+	return &binaryFrame{code: http.StatusNoContent}
+}
@@ -10,3 +10,10 @@ type Cache interface {
 	Len() int
 	Expunge()
 }
+
+// TaggedCache is implemented by caches that can group keys by tag, allowing all keys with a tag to be removed at once
+type TaggedCache interface {
+	Cache
+	PutWithTags(key, value interface{}, ttl time.Duration, tags ...string)
+	InvalidateTag(tag string) []interface{}
+}
@@ -0,0 +1,266 @@
+package lruchal
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// KeyProvider supplies the keys used by EncryptedCache.  Keys must be 16, 24 or 32 bytes, selecting AES-128, AES-192
+// or AES-256.
+type KeyProvider interface {
+	// CurrentKey returns the key that new values are encrypted with, along with it's id
+	CurrentKey() (id string, key []byte, err error)
+	// Key returns the key with the provided id, allowing values encrypted before a rotation to be decrypted
+	Key(id string) ([]byte, error)
+}
+
+// KeyRing is a KeyProvider holding keys in memory.  After Rotate, values encrypted with any key still in the ring
+// remain readable.
+type KeyRing struct {
+	mu      *sync.RWMutex
+	keys    map[string][]byte
+	current string
+}
+
+// NewKeyRing creates a KeyRing using the provided key as the current key
+func NewKeyRing(id string, key []byte) (*KeyRing, error) {
+	kr := &KeyRing{
+		mu:   new(sync.RWMutex),
+		keys: make(map[string][]byte),
+	}
+	if err := kr.AddKey(id, key); err != nil {
+		return nil, err
+	}
+	kr.current = id
+	return kr, nil
+}
+
+// AddKey adds a key to the ring without making it current
+func (kr *KeyRing) AddKey(id string, key []byte) error {
+	switch len(key) {
+	case 16, 24, 32:
+	default:
+		return fmt.Errorf("key \"%s\" must be 16, 24 or 32 bytes, saw %d", id, len(key))
+	}
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+	kr.keys[id] = append([]byte(nil), key...)
+	return nil
+}
+
+// Rotate makes the key with the provided id current.  The previous key is kept for decryption.
+func (kr *KeyRing) Rotate(id string) error {
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+	if _, ok := kr.keys[id]; !ok {
+		return fmt.Errorf("key \"%s\" not found", id)
+	}
+	kr.current = id
+	return nil
+}
+
+// RemoveKey removes a key from the ring.  Values encrypted with it can no longer be read.  The current key cannot be
+// removed.
+func (kr *KeyRing) RemoveKey(id string) error {
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+	if id == kr.current {
+		return errors.New("cannot remove current key")
+	}
+	delete(kr.keys, id)
+	return nil
+}
+
+func (kr *KeyRing) CurrentKey() (string, []byte, error) {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+	return kr.current, kr.keys[kr.current], nil
+}
+
+func (kr *KeyRing) Key(id string) ([]byte, error) {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+	if key, ok := kr.keys[id]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("key \"%s\" not found", id)
+}
+
+// encryptedValue is held by the underlying cache in place of a value
+type encryptedValue struct {
+	keyID      string
+	nonce      []byte
+	ciphertext []byte
+}
+
+// EncryptedCache wraps another Cache, encrypting every value with AES-GCM before it reaches the underlying cache so
+// that plaintext values never sit in it's memory, or in anything persisted from it.  Keys are bound to their values
+// as additional data, so a ciphertext cannot be moved to another key.
+//
+// Values are serialized with GobCodec prior to encryption, so any type other than the basic types and those used by
+// this package must be registered with gob.Register.  As the Cache interface does not return errors, values that
+// cannot be encrypted are not stored and values that cannot be decrypted are treated as missing, with the error
+// logged.
+type EncryptedCache struct {
+	cache Cache
+	keys  KeyProvider
+	log   Logger
+}
+
+// NewEncryptedCache wraps cache.  If logger is nil, DefaultLogger is used.
+func NewEncryptedCache(cache Cache, keys KeyProvider, logger Logger) *EncryptedCache {
+	if cache == nil {
+		panic("cache cannot be nil")
+	}
+	if keys == nil {
+		panic("keys cannot be nil")
+	}
+	if logger == nil {
+		logger = DefaultLogger("encrypted-cache")
+	}
+	ec := &EncryptedCache{
+		cache: cache,
+		keys:  keys,
+		log:   logger,
+	}
+	return ec
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// keyAAD is the additional data binding a ciphertext to it's key.  The key's type is included so that keys printing
+// the same, such as 1 and "1", cannot have their values swapped.
+func keyAAD(key interface{}) []byte {
+	return []byte(fmt.Sprintf("%T:%#v", key, key))
+}
+
+func (ec *EncryptedCache) encrypt(key, value interface{}) (*encryptedValue, error) {
+	plaintext, err := new(GobCodec).Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("unable to serialize: %s", err)
+	}
+	id, k, err := ec.keys.CurrentKey()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get current key: %s", err)
+	}
+	gcm, err := newGCM(k)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("unable to generate nonce: %s", err)
+	}
+	ev := &encryptedValue{
+		keyID:      id,
+		nonce:      nonce,
+		ciphertext: gcm.Seal(nil, nonce, plaintext, keyAAD(key)),
+	}
+	return ev, nil
+}
+
+func (ec *EncryptedCache) decrypt(key, value interface{}) (interface{}, error) {
+	ev, ok := value.(*encryptedValue)
+	if !ok {
+		return nil, fmt.Errorf("unexpected value type %T", value)
+	}
+	k, err := ec.keys.Key(ev.keyID)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(k)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := gcm.Open(nil, ev.nonce, ev.ciphertext, keyAAD(key))
+	if err != nil {
+		return nil, err
+	}
+	var out interface{}
+	if err := new(GobCodec).Unmarshal(plaintext, &out); err != nil {
+		return nil, fmt.Errorf("unable to deserialize: %s", err)
+	}
+	return out, nil
+}
+
+func (ec *EncryptedCache) Has(key interface{}) bool {
+	return ec.cache.Has(key)
+}
+
+// Remove will attempt to remove a key from this cache, returning it's value.  Returns nil if key not found.
+func (ec *EncryptedCache) Remove(key interface{}) interface{} {
+	value := ec.cache.Remove(key)
+	if value == nil {
+		return nil
+	}
+	out, err := ec.decrypt(key, value)
+	if err != nil {
+		ec.log.Printf("unable to decrypt key \"%v\": %s", key, err)
+	}
+	return out
+}
+
+func (ec *EncryptedCache) Put(key, value interface{}, ttl time.Duration) {
+	ev, err := ec.encrypt(key, value)
+	if err != nil {
+		ec.log.Printf("unable to encrypt key \"%v\": %s", key, err)
+		return
+	}
+	ec.cache.Put(key, ev, ttl)
+}
+
+// PutWithTags will encrypt and put value with tags.  If the underlying cache is not a TaggedCache, the tags are
+// dropped.
+func (ec *EncryptedCache) PutWithTags(key, value interface{}, ttl time.Duration, tags ...string) {
+	ev, err := ec.encrypt(key, value)
+	if err != nil {
+		ec.log.Printf("unable to encrypt key \"%v\": %s", key, err)
+		return
+	}
+	if tc, ok := ec.cache.(TaggedCache); ok {
+		tc.PutWithTags(key, ev, ttl, tags...)
+	} else {
+		ec.cache.Put(key, ev, ttl)
+	}
+}
+
+// InvalidateTag will remove every key put with tag, returning the removed keys.  If the underlying cache is not a
+// TaggedCache, nothing is removed.
+func (ec *EncryptedCache) InvalidateTag(tag string) []interface{} {
+	if tc, ok := ec.cache.(TaggedCache); ok {
+		return tc.InvalidateTag(tag)
+	}
+	return nil
+}
+
+func (ec *EncryptedCache) Get(key interface{}) interface{} {
+	value := ec.cache.Get(key)
+	if value == nil {
+		return nil
+	}
+	out, err := ec.decrypt(key, value)
+	if err != nil {
+		ec.log.Printf("unable to decrypt key \"%v\": %s", key, err)
+	}
+	return out
+}
+
+func (ec *EncryptedCache) Len() int {
+	return ec.cache.Len()
+}
+
+func (ec *EncryptedCache) Expunge() {
+	ec.cache.Expunge()
+}
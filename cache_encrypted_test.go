@@ -0,0 +1,125 @@
+package lruchal_test
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dcarbone/lruchal"
+)
+
+// inspectCache records the raw values handed to it by a wrapping cache
+type inspectCache struct {
+	*lruchal.MemoryCache
+	puts []interface{}
+}
+
+func (ic *inspectCache) Put(key, value interface{}, ttl time.Duration) {
+	ic.puts = append(ic.puts, value)
+	ic.MemoryCache.Put(key, value, ttl)
+}
+
+func TestEncryptedCache(t *testing.T) {
+	key1 := bytes.Repeat([]byte{1}, 32)
+	key2 := bytes.Repeat([]byte{2}, 32)
+
+	t.Run("InvalidKeyLength", func(t *testing.T) {
+		if _, err := lruchal.NewKeyRing("k1", []byte("short")); err == nil {
+			t.Log("Expected error for short key")
+			t.FailNow()
+		}
+	})
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		keys, _ := lruchal.NewKeyRing("k1", key1)
+		inner := &inspectCache{MemoryCache: lruchal.NewMemoryCache(10)}
+		cache := lruchal.NewEncryptedCache(inner, keys, nil)
+
+		value := map[string]interface{}{"secret": "hunter2", "n": int64(42)}
+		cache.Put("key1", value, time.Minute)
+
+		if v := cache.Get("key1"); !reflect.DeepEqual(v, value) {
+			t.Logf("Expected %#v, saw %#v", value, v)
+			t.FailNow()
+		}
+		if len(inner.puts) != 1 {
+			t.Logf("Expected 1 put to underlying cache, saw %d", len(inner.puts))
+			t.FailNow()
+		}
+		if s := fmt.Sprintf("%#v", inner.puts[0]); bytes.Contains([]byte(s), []byte("hunter2")) {
+			t.Logf("Underlying cache holds plaintext: %s", s)
+			t.FailNow()
+		}
+		if v := cache.Remove("key1"); !reflect.DeepEqual(v, value) {
+			t.Logf("Expected remove to return %#v, saw %#v", value, v)
+			t.FailNow()
+		}
+		if cache.Has("key1") {
+			t.Log("Expected key to be removed")
+			t.FailNow()
+		}
+	})
+
+	t.Run("KeyBinding", func(t *testing.T) {
+		keys, _ := lruchal.NewKeyRing("k1", key1)
+		inner := lruchal.NewMemoryCache(10)
+		cache := lruchal.NewEncryptedCache(inner, keys, nil)
+
+		cache.Put(1, "int value", time.Minute)
+		cache.Put("a", "a value", time.Minute)
+		// move ciphertexts to keys which print the same or differently, as someone able to write to inner could
+		inner.Put("1", inner.Get(1), time.Minute)
+		inner.Put("b", inner.Get("a"), time.Minute)
+
+		if v := cache.Get(1); v != "int value" {
+			t.Logf("Expected \"int value\", saw %#v", v)
+			t.FailNow()
+		}
+		for _, key := range []interface{}{"1", "b"} {
+			if v := cache.Get(key); v != nil {
+				t.Logf("Expected value moved to %#v to be unreadable, saw %#v", key, v)
+				t.FailNow()
+			}
+		}
+	})
+
+	t.Run("Rotation", func(t *testing.T) {
+		keys, _ := lruchal.NewKeyRing("k1", key1)
+		cache := lruchal.NewEncryptedCache(lruchal.NewMemoryCache(10), keys, nil)
+
+		cache.Put("old", "old value", time.Minute)
+		if err := keys.AddKey("k2", key2); err != nil {
+			t.Logf("Unable to add key: %s", err)
+			t.FailNow()
+		}
+		if err := keys.Rotate("k2"); err != nil {
+			t.Logf("Unable to rotate: %s", err)
+			t.FailNow()
+		}
+		cache.Put("new", "new value", time.Minute)
+
+		if v := cache.Get("old"); v != "old value" {
+			t.Logf("Expected value encrypted with previous key to be readable, saw %#v", v)
+			t.FailNow()
+		}
+		if v := cache.Get("new"); v != "new value" {
+			t.Logf("Expected \"new value\", saw %#v", v)
+			t.FailNow()
+		}
+
+		if err := keys.RemoveKey("k2"); err == nil {
+			t.Log("Expected error removing current key")
+			t.FailNow()
+		}
+		if err := keys.RemoveKey("k1"); err != nil {
+			t.Logf("Unable to remove key: %s", err)
+			t.FailNow()
+		}
+		if v := cache.Get("old"); v != nil {
+			t.Logf("Expected value encrypted with removed key to be unreadable, saw %#v", v)
+			t.FailNow()
+		}
+	})
+}
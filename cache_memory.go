@@ -2,6 +2,7 @@ package lruchal
 
 import (
 	"container/list"
+	"fmt"
 	"sync"
 	"time"
 )
@@ -9,22 +10,31 @@ import (
 type memoryCacheItem struct {
 	mu *sync.Mutex
 
-	key   interface{}
-	value interface{}
+	key       interface{}
+	value     interface{}
+	size      int
+	tags      []string
+	cas       uint64
+	expiresAt time.Time
 
-	expired bool
-	kill    chan struct{}
+	expired  bool
+	kill     chan struct{}
+	onExpire func(key interface{}, ci *memoryCacheItem)
+
+	reported bool // true once the item's expiry has been reported to the change func, guarded by the cache's lock
 }
 
-func newMemoryCachedItem(key, value interface{}, ttl time.Duration) *memoryCacheItem {
+func newMemoryCachedItem(key, value interface{}, ttl time.Duration, onExpire func(interface{}, *memoryCacheItem)) *memoryCacheItem {
 	ci := &memoryCacheItem{
-		mu:    new(sync.Mutex),
-		key:   key,
-		value: value,
-		kill:  make(chan struct{}),
+		mu:        new(sync.Mutex),
+		key:       key,
+		value:     value,
+		expiresAt: time.Now().Add(ttl),
+		kill:      make(chan struct{}),
+		onExpire:  onExpire,
 	}
 
-	go ci.expire(ttl)
+	go ci.expire(key, ttl)
 
 	return ci
 }
@@ -33,16 +43,21 @@ func (ci *memoryCacheItem) Key() interface{} {
 	return ci.key
 }
 
+// Value returns the item's value, or nil if it has expired
 func (ci *memoryCacheItem) Value() interface{} {
 	ci.mu.Lock()
 	defer ci.mu.Unlock()
+	if ci.expired || !time.Now().Before(ci.expiresAt) {
+		return nil
+	}
 	return ci.value
 }
 
+// Expired returns true once the item's ttl has elapsed, even if it has not yet been removed from the cache
 func (ci *memoryCacheItem) Expired() bool {
 	ci.mu.Lock()
 	defer ci.mu.Unlock()
-	return ci.expired
+	return ci.expired || !time.Now().Before(ci.expiresAt)
 }
 
 func (ci *memoryCacheItem) term() {
@@ -56,7 +71,7 @@ func (ci *memoryCacheItem) term() {
 	}
 }
 
-func (ci *memoryCacheItem) expire(ttl time.Duration) {
+func (ci *memoryCacheItem) expire(key interface{}, ttl time.Duration) {
 	timer := time.NewTimer(ttl)
 	defer timer.Stop()
 	select {
@@ -64,28 +79,74 @@ func (ci *memoryCacheItem) expire(ttl time.Duration) {
 		ci.mu.Lock()
 		ci.expired = true
 		ci.value = nil
-		ci.key = nil
 		ci.mu.Unlock()
+		if ci.onExpire != nil {
+			ci.onExpire(key, ci)
+		}
 	case <-ci.kill:
 	}
 }
 
+// EvictionPolicy determines which key MemoryCache removes when it is full
+type EvictionPolicy string
+
+const (
+	EvictionLRU  EvictionPolicy = "lru"  // evict the least recently used key
+	EvictionFIFO EvictionPolicy = "fifo" // evict the least recently inserted key, ignoring reads and updates
+)
+
+// ChangeType describes how a key in MemoryCache changed
+type ChangeType string
+
+const (
+	ChangePut    ChangeType = "put"
+	ChangeRemove ChangeType = "remove"
+	ChangeExpire ChangeType = "expire"
+	ChangeEvict  ChangeType = "evict"
+)
+
+// Change is passed to the func set with SetOnChange
+type Change struct {
+	Type ChangeType
+	Key  interface{}
+}
+
 type MemoryCache struct {
 	mu       *sync.Mutex
 	list     *list.List
 	elements map[interface{}]*list.Element
+	tags     map[string]map[interface{}]struct{}
 	maxSize  int
+	policy   EvictionPolicy
+
+	sizer func(value interface{}) int
+	bytes int64
+
+	cas uint64 // incremented on every put
+
+	onChange func(Change)
 }
 
 func NewMemoryCache(maxSize int) *MemoryCache {
+	return NewMemoryCacheWithPolicy(maxSize, EvictionLRU)
+}
+
+func NewMemoryCacheWithPolicy(maxSize int, policy EvictionPolicy) *MemoryCache {
 	if maxSize <= 0 {
 		panic("maxSize must be greater than 0")
 	}
+	switch policy {
+	case EvictionLRU, EvictionFIFO:
+	default:
+		panic(fmt.Sprintf("unknown eviction policy \"%s\"", policy))
+	}
 	cc := &MemoryCache{
 		mu:       new(sync.Mutex),
 		list:     list.New(),
 		elements: make(map[interface{}]*list.Element, maxSize),
+		tags:     make(map[string]map[interface{}]struct{}),
 		maxSize:  maxSize,
+		policy:   policy,
 	}
 
 	cc.list.Init()
@@ -96,8 +157,8 @@ func NewMemoryCache(maxSize int) *MemoryCache {
 func (cc *MemoryCache) Has(key interface{}) bool {
 	cc.mu.Lock()
 	defer cc.mu.Unlock()
-	_, ok := cc.elements[key]
-	return ok
+	elem, ok := cc.elements[key]
+	return ok && !elem.Value.(*memoryCacheItem).Expired()
 }
 
 // Remove will attempt to remove a key from this cache, returning it's value.  Returns nil if key not found.
@@ -105,29 +166,51 @@ func (cc *MemoryCache) Remove(key interface{}) interface{} {
 	cc.mu.Lock()
 	defer cc.mu.Unlock()
 	if elem, ok := cc.elements[key]; ok {
-		item := cc.list.Remove(elem).(*memoryCacheItem)
-		val := item.Value()
-		delete(cc.elements, item.key)
-		item.term()
+		val := elem.Value.(*memoryCacheItem).Value()
+		cc.removeElement(key, elem)
+		cc.notify(ChangeRemove, key)
 		return val
 	}
 	return nil
 }
 
-// Put will perform an upsert on a key, potentially expunging the least recently used if there is no more room.
+// Put will perform an upsert on a key, potentially evicting a key per the cache's policy if there is no more room.
 func (cc *MemoryCache) Put(key, value interface{}, ttl time.Duration) {
+	cc.PutWithTags(key, value, ttl)
+}
+
+// PutWithTags will perform an upsert on a key as with Put, associating it with the provided tags.  Any tags the key was
+// previously put with are replaced.
+func (cc *MemoryCache) PutWithTags(key, value interface{}, ttl time.Duration, tags ...string) {
 	cc.mu.Lock()
 	defer cc.mu.Unlock()
+	item := newMemoryCachedItem(key, value, ttl, cc.expired)
+	cc.cas++
+	item.cas = cc.cas
+	if cc.sizer != nil {
+		item.size = cc.sizer(value)
+		cc.bytes += int64(item.size)
+	}
+	if len(tags) > 0 {
+		item.tags = append([]string(nil), tags...)
+		cc.tag(key, item.tags)
+	}
 	if elem, ok := cc.elements[key]; ok {
-		elem.Value.(*memoryCacheItem).term()
-		elem.Value = newMemoryCachedItem(key, value, ttl)
-		cc.list.MoveToFront(elem)
+		old := elem.Value.(*memoryCacheItem)
+		cc.bytes -= int64(old.size)
+		cc.untag(key, old.tags, item.tags)
+		old.term()
+		elem.Value = item
+		if cc.policy == EvictionLRU {
+			cc.list.MoveToFront(elem)
+		}
 	} else {
 		if cc.list.Len() == cc.maxSize {
-			cc.list.Remove(cc.list.Back()).(*memoryCacheItem).term()
+			cc.evict()
 		}
-		cc.elements[key] = cc.list.PushFront(newMemoryCachedItem(key, value, ttl))
+		cc.elements[key] = cc.list.PushFront(item)
 	}
+	cc.notify(ChangePut, key)
 }
 
 // Get will attempt to return a key value for you.  Will return nil if key is expired.
@@ -136,35 +219,258 @@ func (cc *MemoryCache) Get(key interface{}) interface{} {
 	defer cc.mu.Unlock()
 	if elem, ok := cc.elements[key]; ok {
 		if !elem.Value.(*memoryCacheItem).Expired() {
-			cc.list.MoveToFront(elem)
+			if cc.policy == EvictionLRU {
+				cc.list.MoveToFront(elem)
+			}
 			return elem.Value.(*memoryCacheItem).Value()
 		}
 	}
 	return nil
 }
 
+// TTL returns the time remaining before key expires.  Returns false if the key is not found or has expired.
+func (cc *MemoryCache) TTL(key interface{}) (time.Duration, bool) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	if elem, ok := cc.elements[key]; ok {
+		item := elem.Value.(*memoryCacheItem)
+		if !item.Expired() {
+			return time.Until(item.expiresAt), true
+		}
+	}
+	return 0, false
+}
+
+// Expire will reset the ttl of key without modifying it's value.  Returns false if the key is not found or has expired.
+func (cc *MemoryCache) Expire(key interface{}, ttl time.Duration) bool {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	elem, ok := cc.elements[key]
+	if !ok {
+		return false
+	}
+	old := elem.Value.(*memoryCacheItem)
+	value := old.Value()
+	if value == nil {
+		return false
+	}
+	item := newMemoryCachedItem(key, value, ttl, cc.expired)
+	item.size = old.size
+	item.tags = old.tags
+	item.cas = old.cas
+	old.term()
+	elem.Value = item
+	return true
+}
+
+// CAS returns a number that changes every time key is put, for use in check-and-set operations.  Returns false if the
+// key is not found or has expired.
+func (cc *MemoryCache) CAS(key interface{}) (uint64, bool) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	if elem, ok := cc.elements[key]; ok {
+		item := elem.Value.(*memoryCacheItem)
+		if !item.Expired() {
+			return item.cas, true
+		}
+	}
+	return 0, false
+}
+
+// Keys returns all keys that have not expired, in no particular order
+func (cc *MemoryCache) Keys() []interface{} {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	keys := make([]interface{}, 0, len(cc.elements))
+	for key, elem := range cc.elements {
+		if !elem.Value.(*memoryCacheItem).Expired() {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// InvalidateTag will remove every key put with tag, returning the removed keys
+func (cc *MemoryCache) InvalidateTag(tag string) []interface{} {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	keys := make([]interface{}, 0, len(cc.tags[tag]))
+	for key := range cc.tags[tag] {
+		keys = append(keys, key)
+	}
+	for _, key := range keys {
+		if elem, ok := cc.elements[key]; ok {
+			cc.removeElement(key, elem)
+			cc.notify(ChangeRemove, key)
+		}
+	}
+	return keys
+}
+
+// Tags returns the number of keys associated with each tag
+func (cc *MemoryCache) Tags() map[string]int {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	out := make(map[string]int, len(cc.tags))
+	for tag, keys := range cc.tags {
+		out[tag] = len(keys)
+	}
+	return out
+}
+
 func (cc *MemoryCache) Len() int {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
 	return cc.list.Len()
 }
 
+// Evict will remove the key that would next be evicted per the cache's policy, returning false if the cache is empty
+func (cc *MemoryCache) Evict() bool {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	return cc.evict()
+}
+
+func (cc *MemoryCache) evict() bool {
+	elem := cc.list.Back()
+	if elem == nil {
+		return false
+	}
+	key := elem.Value.(*memoryCacheItem).key
+	cc.removeElement(key, elem)
+	cc.notify(ChangeEvict, key)
+	return true
+}
+
+// expired is called by an item once it's ttl elapses, removing it from the tag index and reporting it's expiry.  The
+// item itself remains until expunged.
+func (cc *MemoryCache) expired(key interface{}, ci *memoryCacheItem) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	// the key may have since been removed or replaced
+	if elem, ok := cc.elements[key]; ok && elem.Value == ci {
+		cc.untag(key, ci.tags, nil)
+		ci.tags = nil
+		cc.reportExpired(key, ci)
+	}
+}
+
+// reportExpired notifies the change func of an item's expiry, unless it already has been.  Caller must hold lock.
+func (cc *MemoryCache) reportExpired(key interface{}, ci *memoryCacheItem) {
+	if !ci.reported {
+		ci.reported = true
+		cc.notify(ChangeExpire, key)
+	}
+}
+
+// SetOnChange registers fn to be called for every key put, removed, expired or evicted, in the order the changes are
+// made.  fn is called with the cache's lock held, so must not block or call back into the cache.
+func (cc *MemoryCache) SetOnChange(fn func(Change)) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	cc.onChange = fn
+}
+
+// notify calls the change func, if set.  Caller must hold lock.
+func (cc *MemoryCache) notify(t ChangeType, key interface{}) {
+	if cc.onChange != nil {
+		cc.onChange(Change{Type: t, Key: key})
+	}
+}
+
+// removeElement removes key from the cache and all of it's tags.  Caller must hold lock.
+func (cc *MemoryCache) removeElement(key interface{}, elem *list.Element) {
+	item := cc.list.Remove(elem).(*memoryCacheItem)
+	delete(cc.elements, key)
+	cc.bytes -= int64(item.size)
+	cc.untag(key, item.tags, nil)
+	item.term()
+}
+
+// tag adds key to the index of each tag.  Caller must hold lock.
+func (cc *MemoryCache) tag(key interface{}, tags []string) {
+	for _, tag := range tags {
+		keys, ok := cc.tags[tag]
+		if !ok {
+			keys = make(map[interface{}]struct{})
+			cc.tags[tag] = keys
+		}
+		keys[key] = struct{}{}
+	}
+}
+
+// untag removes key from the index of each tag, other than those it is being kept under.  Caller must hold lock.
+func (cc *MemoryCache) untag(key interface{}, tags, keep []string) {
+	for _, tag := range tags {
+		if containsString(keep, tag) {
+			continue
+		}
+		if keys, ok := cc.tags[tag]; ok {
+			delete(keys, key)
+			if len(keys) == 0 {
+				delete(cc.tags, tag)
+			}
+		}
+	}
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// SetSizer enables tracking of the approximate number of bytes held by the cache, as reported by Bytes.  sizer is
+// called once for every value put.
+func (cc *MemoryCache) SetSizer(sizer func(value interface{}) int) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	cc.sizer = sizer
+}
+
+// Bytes returns the total size of all values in the cache, as reported by the sizer.  Returns 0 if no sizer is set.
+func (cc *MemoryCache) Bytes() int64 {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	return cc.bytes
+}
+
+// sizeOfKey returns the size of key's value as reported by the sizer, or 0 if the key is not present
+func (cc *MemoryCache) sizeOfKey(key interface{}) int {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	if elem, ok := cc.elements[key]; ok {
+		return elem.Value.(*memoryCacheItem).size
+	}
+	return 0
+}
+
 // Expunge will remove expired keys from the cache
 func (cc *MemoryCache) Expunge() {
 	cc.mu.Lock()
 	defer cc.mu.Unlock()
-	expired := make([]interface{}, cc.Len())
-	i := 0
 	for key, elem := range cc.elements {
-		if elem.Value.(*memoryCacheItem).Expired() {
-			expired[i] = key
-			cc.list.Remove(elem)
-			i++
+		if item := elem.Value.(*memoryCacheItem); item.Expired() {
+			// the item's timer may not have fired yet
+			cc.reportExpired(key, item)
+			cc.removeElement(key, elem)
 		}
 	}
+}
 
-	for _, key := range expired {
-		if key == nil {
-			return
-		}
-		delete(cc.elements, key)
+// Purge will remove all keys from the cache
+func (cc *MemoryCache) Purge() {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	for key, elem := range cc.elements {
+		elem.Value.(*memoryCacheItem).term()
+		cc.notify(ChangeRemove, key)
 	}
+	cc.list.Init()
+	cc.elements = make(map[interface{}]*list.Element, cc.maxSize)
+	cc.tags = make(map[string]map[interface{}]struct{})
+	cc.bytes = 0
 }
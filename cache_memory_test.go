@@ -103,6 +103,169 @@ func TestMemoryCache(t *testing.T) {
 			t.FailNow()
 		}
 	})
+	t.Run("EvictionPolicy", func(t *testing.T) {
+		lru := lruchal.NewMemoryCache(2)
+		fifo := lruchal.NewMemoryCacheWithPolicy(2, lruchal.EvictionFIFO)
+		for _, c := range []*lruchal.MemoryCache{lru, fifo} {
+			c.Put("key1", "value1", time.Second)
+			c.Put("key2", "value2", time.Second)
+			c.Get("key1")
+			c.Put("key3", "value3", time.Second)
+		}
+
+		if !lru.Has("key1") || lru.Has("key2") {
+			t.Log("Expected lru to evict key2")
+			t.FailNow()
+		}
+		if fifo.Has("key1") || !fifo.Has("key2") {
+			t.Log("Expected fifo to evict key1")
+			t.FailNow()
+		}
+	})
+	t.Run("Bytes", func(t *testing.T) {
+		cache := lruchal.NewMemoryCache(2)
+		cache.SetSizer(func(v interface{}) int { return len(v.(string)) })
+		cache.Put("key1", "12345", time.Second)
+		cache.Put("key2", "123", time.Second)
+		cache.Put("key1", "1", time.Second)
+		if b := cache.Bytes(); b != 4 {
+			t.Logf("Expected 4 bytes, saw %d", b)
+			t.FailNow()
+		}
+
+		cache.Put("key3", "1234567", time.Second)
+		if b := cache.Bytes(); b != 8 || cache.Has("key2") {
+			t.Logf("Expected key2 to be evicted leaving 8 bytes, saw %d", b)
+			t.FailNow()
+		}
+
+		if !cache.Evict() || !cache.Evict() || cache.Evict() {
+			t.Log("Expected exactly 2 keys to be evicted")
+			t.FailNow()
+		}
+		if b := cache.Bytes(); b != 0 || cache.Len() != 0 {
+			t.Logf("Expected empty cache, saw len %d and %d bytes", cache.Len(), b)
+			t.FailNow()
+		}
+	})
+	t.Run("Tags", func(t *testing.T) {
+		cache := lruchal.NewMemoryCache(3)
+		cache.PutWithTags("profile", "value1", time.Second, "user1")
+		cache.PutWithTags("avatar", "value2", time.Second, "user1", "images")
+		cache.PutWithTags("session", "value3", time.Millisecond, "user2")
+		cache.Put("other", "value4", time.Second)
+
+		// "profile" is evicted to make room for "other", and "session" expires
+		time.Sleep(50 * time.Millisecond)
+		if tags := cache.Tags(); !reflect.DeepEqual(tags, map[string]int{"user1": 1, "images": 1}) {
+			t.Logf("Expected evicted and expired keys to be removed from the tag index, saw %v", tags)
+			t.FailNow()
+		}
+
+		keys := cache.InvalidateTag("user1")
+		if len(keys) != 1 || keys[0] != "avatar" {
+			t.Logf("Expected InvalidateTag to remove [avatar], saw %v", keys)
+			t.FailNow()
+		}
+		if cache.Has("avatar") || !cache.Has("other") || len(cache.Tags()) != 0 {
+			t.Logf("Expected only \"other\" to remain untagged, saw tags %v", cache.Tags())
+			t.FailNow()
+		}
+	})
+	t.Run("Expire", func(t *testing.T) {
+		cache := lruchal.NewMemoryCache(10)
+		cache.Put("key1", "value1", time.Hour)
+		cache.Put("key2", "value2", time.Millisecond)
+
+		if ttl, ok := cache.TTL("key1"); !ok || ttl <= 59*time.Minute || ttl > time.Hour {
+			t.Logf("Expected key1 to have a ttl of about an hour, saw %s", ttl)
+			t.FailNow()
+		}
+		if !cache.Expire("key1", time.Millisecond) {
+			t.Log("Expected Expire to find key1")
+			t.FailNow()
+		}
+		if cache.Expire("missing", time.Second) {
+			t.Log("Expected Expire to not find missing key")
+			t.FailNow()
+		}
+
+		time.Sleep(5 * time.Millisecond)
+		if v := cache.Get("key1"); v != nil {
+			t.Logf("Expected key1 to have expired, saw %v", v)
+			t.FailNow()
+		}
+		if _, ok := cache.TTL("key2"); ok {
+			t.Log("Expected TTL to not report an expired key")
+			t.FailNow()
+		}
+		if keys := cache.Keys(); len(keys) != 0 {
+			t.Logf("Expected Keys to exclude expired keys, saw %v", keys)
+			t.FailNow()
+		}
+	})
+	t.Run("CAS", func(t *testing.T) {
+		cache := lruchal.NewMemoryCache(10)
+		cache.Put("key1", "value1", time.Second)
+		first, ok := cache.CAS("key1")
+		if !ok {
+			t.Log("Expected CAS to find key1")
+			t.FailNow()
+		}
+
+		cache.Expire("key1", time.Minute)
+		if cas, _ := cache.CAS("key1"); cas != first {
+			t.Logf("Expected Expire to keep cas %d, saw %d", first, cas)
+			t.FailNow()
+		}
+
+		cache.Put("key1", "value2", time.Second)
+		if cas, _ := cache.CAS("key1"); cas == first {
+			t.Log("Expected Put to change cas")
+			t.FailNow()
+		}
+		if _, ok := cache.CAS("missing"); ok {
+			t.Log("Expected CAS to not find missing key")
+			t.FailNow()
+		}
+	})
+	t.Run("OnChange", func(t *testing.T) {
+		cache := lruchal.NewMemoryCache(2)
+		var changes []lruchal.Change
+		cache.SetOnChange(func(c lruchal.Change) { changes = append(changes, c) })
+
+		cache.Put("key1", "value1", time.Hour)
+		cache.Put("key2", "value2", time.Hour)
+		cache.Put("key3", "value3", time.Hour)
+		cache.Remove("key2")
+
+		expected := []lruchal.Change{
+			{Type: lruchal.ChangePut, Key: "key1"},
+			{Type: lruchal.ChangePut, Key: "key2"},
+			{Type: lruchal.ChangeEvict, Key: "key1"},
+			{Type: lruchal.ChangePut, Key: "key3"},
+			{Type: lruchal.ChangeRemove, Key: "key2"},
+		}
+		if len(changes) != len(expected) {
+			t.Logf("Expected %v, saw %v", expected, changes)
+			t.FailNow()
+		}
+		for i := range expected {
+			if changes[i] != expected[i] {
+				t.Logf("Expected %v, saw %v", expected, changes)
+				t.FailNow()
+			}
+		}
+
+		changes = nil
+		cache.Put("key4", "value4", time.Millisecond)
+		time.Sleep(5 * time.Millisecond)
+		cache.Expunge()
+		if len(changes) != 2 || changes[1] != (lruchal.Change{Type: lruchal.ChangeExpire, Key: "key4"}) {
+			t.Logf("Expected key4 to be reported as expired exactly once, saw %v", changes)
+			t.FailNow()
+		}
+	})
 }
 
 func BenchmarkMemoryCache100(b *testing.B) {
@@ -0,0 +1,102 @@
+package lruchal
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// RemoteCache adapts a Client to the Cache interface, allowing a remote server to be used anywhere a local cache
+// would be.  As the Cache interface does not return errors, any errors seen while talking to the server are logged and
+// the zero value for the call is returned.
+type RemoteCache struct {
+	client *Client
+	log    Logger
+}
+
+// NewRemoteCache will construct a Cache backed by the provided client.  If logger is nil, DefaultLogger is used.
+func NewRemoteCache(client *Client, logger Logger) *RemoteCache {
+	if client == nil {
+		panic("client cannot be nil")
+	}
+	if logger == nil {
+		logger = DefaultLogger("remote-cache")
+	}
+	rc := &RemoteCache{
+		client: client,
+		log:    logger,
+	}
+	return rc
+}
+
+// remoteKey converts a key to the string representation used by the http api
+func remoteKey(key interface{}) string {
+	if s, ok := key.(string); ok {
+		return s
+	}
+	return fmt.Sprintf("%v", key)
+}
+
+func (rc *RemoteCache) Has(key interface{}) bool {
+	ok, err := rc.client.Has(remoteKey(key))
+	if err != nil {
+		rc.log.Printf("unable to check for key \"%v\": %s", key, err)
+	}
+	return ok
+}
+
+func (rc *RemoteCache) Remove(key interface{}) interface{} {
+	v, err := rc.client.Remove(remoteKey(key))
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		rc.log.Printf("unable to remove key \"%v\": %s", key, err)
+	}
+	return v
+}
+
+func (rc *RemoteCache) Put(key, value interface{}, ttl time.Duration) {
+	err := rc.client.Put(Item{Key: remoteKey(key), Value: value, TTL: ttl.String()})
+	if err != nil {
+		rc.log.Printf("unable to put key \"%v\": %s", key, err)
+	}
+}
+
+func (rc *RemoteCache) PutWithTags(key, value interface{}, ttl time.Duration, tags ...string) {
+	err := rc.client.Put(Item{Key: remoteKey(key), Value: value, TTL: ttl.String(), Tags: tags})
+	if err != nil {
+		rc.log.Printf("unable to put key \"%v\": %s", key, err)
+	}
+}
+
+func (rc *RemoteCache) InvalidateTag(tag string) []interface{} {
+	keys, err := rc.client.InvalidateTag(tag)
+	if err != nil {
+		rc.log.Printf("unable to invalidate tag \"%s\": %s", tag, err)
+	}
+	out := make([]interface{}, len(keys))
+	for i, key := range keys {
+		out[i] = key
+	}
+	return out
+}
+
+func (rc *RemoteCache) Get(key interface{}) interface{} {
+	v, err := rc.client.Get(remoteKey(key))
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		rc.log.Printf("unable to get key \"%v\": %s", key, err)
+	}
+	return v
+}
+
+func (rc *RemoteCache) Len() int {
+	l, err := rc.client.Len()
+	if err != nil {
+		rc.log.Printf("unable to get len: %s", err)
+	}
+	return l
+}
+
+func (rc *RemoteCache) Expunge() {
+	if err := rc.client.Expunge(); err != nil {
+		rc.log.Printf("unable to expunge: %s", err)
+	}
+}
@@ -0,0 +1,122 @@
+package lruchal_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/dcarbone/lruchal"
+)
+
+func TestRemoteCache(t *testing.T) {
+	srv := newTestServer(t, &lruchal.ServerConfig{Port: lruchal.RandomPort, BindAddress: "127.0.0.1"})
+	if err := srv.Listen(); err != nil {
+		t.Logf("Unable to listen: %s", err)
+		t.FailNow()
+	}
+	go srv.Serve()
+	defer srv.Close()
+
+	client, err := lruchal.NewClient(&lruchal.ClientConfig{Address: srv.Addr().String()})
+	if err != nil {
+		t.Logf("Unable to create client: %s", err)
+		t.FailNow()
+	}
+	defer client.Close()
+
+	t.Run("Cache", func(t *testing.T) {
+		var cache lruchal.Cache = lruchal.NewRemoteCache(client, log.New(ioutil.Discard, "", 0))
+
+		cache.Put("remote1", "value1", time.Minute)
+		cache.Put(2, "value2", time.Minute)
+		cache.Put("remote3", "value3", 50*time.Millisecond)
+		if l := cache.Len(); l != 3 {
+			t.Logf("Expected len 3, saw %d", l)
+			t.FailNow()
+		}
+		if !cache.Has("remote1") || !cache.Has(2) || cache.Has("missing") {
+			t.Log("Has did not match the keys put")
+			t.FailNow()
+		}
+		if v := cache.Get(2); v != "value2" {
+			t.Logf("Expected value2, saw %v", v)
+			t.FailNow()
+		}
+		if v := cache.Get("missing"); v != nil {
+			t.Logf("Expected nil for missing key, saw %v", v)
+			t.FailNow()
+		}
+		if v := cache.Remove("remote1"); v != "value1" {
+			t.Logf("Expected removed value1, saw %v", v)
+			t.FailNow()
+		}
+		if v := cache.Remove("remote1"); v != nil {
+			t.Logf("Expected nil removing a removed key, saw %v", v)
+			t.FailNow()
+		}
+
+		time.Sleep(100 * time.Millisecond)
+		cache.Expunge()
+		if l := cache.Len(); l != 1 {
+			t.Logf("Expected len 1 after expunge, saw %d", l)
+			t.FailNow()
+		}
+		cache.Remove(2)
+	})
+
+	t.Run("EscapedKeys", func(t *testing.T) {
+		keys := []string{"a/b", "a?b=c", "a#b", "100%", "%2F", "a/../b", "..", "a b", "ключ"}
+		for _, key := range keys {
+			if err := client.Put(lruchal.Item{Key: key, Value: key, TTL: "1m", Tags: []string{"tag/" + key}}); err != nil {
+				t.Logf("Unable to put %q: %s", key, err)
+				t.FailNow()
+			}
+			if v, err := client.Get(key); err != nil || v != key {
+				t.Logf("Expected %q, saw %v (err=%v)", key, v, err)
+				t.FailNow()
+			}
+			if ok, err := client.Has(key); err != nil || !ok {
+				t.Logf("Expected to have %q (err=%v)", key, err)
+				t.FailNow()
+			}
+			if err := client.PutBytes("bytes"+key, []byte(key), "text/plain", time.Minute); err != nil {
+				t.Logf("Unable to put bytes for %q: %s", key, err)
+				t.FailNow()
+			}
+			if b, _, err := client.GetBytes("bytes" + key); err != nil || string(b) != key {
+				t.Logf("Expected bytes %q, saw %q (err=%v)", key, b, err)
+				t.FailNow()
+			}
+		}
+		// keys sharing a prefix up to an escaped character must not be confused with one another
+		if _, err := client.Get("a"); !errors.Is(err, lruchal.ErrNotFound) {
+			t.Logf("Expected ErrNotFound for \"a\", saw %v", err)
+			t.FailNow()
+		}
+
+		for _, key := range keys[:4] {
+			if removed, err := client.InvalidateTag("tag/" + key); err != nil || len(removed) != 1 || removed[0] != key {
+				t.Logf("Expected tag to remove %q, saw %v (err=%v)", key, removed, err)
+				t.FailNow()
+			}
+		}
+		for _, key := range keys[4:] {
+			if v, err := client.Remove(key); err != nil || v != key {
+				t.Logf("Expected to remove %q, saw %v (err=%v)", key, v, err)
+				t.FailNow()
+			}
+		}
+		for _, key := range keys {
+			if _, err := client.Remove("bytes" + key); err != nil {
+				t.Logf("Unable to remove %q: %s", key, err)
+				t.FailNow()
+			}
+		}
+		if l, err := client.Len(); err != nil || l != 0 {
+			t.Logf("Expected every key to be removed, saw len %d (err=%v)", l, err)
+			t.FailNow()
+		}
+	})
+}
@@ -0,0 +1,201 @@
+package lruchal
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type CircuitState int
+
+const (
+	CircuitClosed CircuitState = iota
+	CircuitOpen
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+const (
+	DefaultCircuitFailureRateThreshold = 0.5
+	DefaultCircuitMinimumRequests      = 10
+	DefaultCircuitWindow               = 10 * time.Second
+	DefaultCircuitCooldown             = 5 * time.Second
+	DefaultCircuitHalfOpenRequests     = 1
+)
+
+// CircuitBreakerConfig controls when Client stops sending requests to an unhealthy server.
+//
+// While closed, requests are counted over a fixed Window.  Once at least MinimumRequests have been seen in the
+// window and the fraction that failed reaches FailureRateThreshold, the circuit opens and all calls return
+// ErrCircuitOpen without contacting the server.  After Cooldown the circuit becomes half-open and allows
+// HalfOpenRequests trial requests through.  If all of them succeed the circuit closes, if any fail it opens again.
+//
+// Transport errors and 5xx responses, other than 507 quota rejections, count as failures.
+type CircuitBreakerConfig struct {
+	FailureRateThreshold float64
+	MinimumRequests      int
+	Window               time.Duration
+	Cooldown             time.Duration
+	HalfOpenRequests     int
+	OnStateChange        func(from, to CircuitState) // optional, called outside of any lock
+}
+
+func NewDefaultCircuitBreakerConfig() *CircuitBreakerConfig {
+	c := &CircuitBreakerConfig{
+		FailureRateThreshold: DefaultCircuitFailureRateThreshold,
+		MinimumRequests:      DefaultCircuitMinimumRequests,
+		Window:               DefaultCircuitWindow,
+		Cooldown:             DefaultCircuitCooldown,
+		HalfOpenRequests:     DefaultCircuitHalfOpenRequests,
+	}
+	return c
+}
+
+type circuitBreaker struct {
+	mu     *sync.Mutex
+	config CircuitBreakerConfig
+
+	state       CircuitState
+	windowStart time.Time
+	requests    int
+	failures    int
+	openedAt    time.Time
+
+	halfOpenInFlight  int
+	halfOpenSuccesses int
+}
+
+func newCircuitBreaker(config *CircuitBreakerConfig) *circuitBreaker {
+	def := NewDefaultCircuitBreakerConfig()
+	if config.FailureRateThreshold > 0 {
+		def.FailureRateThreshold = config.FailureRateThreshold
+	}
+	if config.MinimumRequests > 0 {
+		def.MinimumRequests = config.MinimumRequests
+	}
+	if config.Window > 0 {
+		def.Window = config.Window
+	}
+	if config.Cooldown > 0 {
+		def.Cooldown = config.Cooldown
+	}
+	if config.HalfOpenRequests > 0 {
+		def.HalfOpenRequests = config.HalfOpenRequests
+	}
+	def.OnStateChange = config.OnStateChange
+
+	cb := &circuitBreaker{
+		mu:          new(sync.Mutex),
+		config:      *def,
+		windowStart: time.Now(),
+	}
+	return cb
+}
+
+func (cb *circuitBreaker) State() CircuitState {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	return cb.state
+}
+
+// allow returns ErrCircuitOpen if a request may not currently be sent.  Every nil return must be followed by a
+// call to record.
+func (cb *circuitBreaker) allow() error {
+	cb.mu.Lock()
+	from := cb.state
+	if cb.state == CircuitOpen && time.Since(cb.openedAt) >= cb.config.Cooldown {
+		cb.setState(CircuitHalfOpen)
+	}
+	var err error
+	switch cb.state {
+	case CircuitOpen:
+		err = ErrCircuitOpen
+	case CircuitHalfOpen:
+		if cb.halfOpenInFlight+cb.halfOpenSuccesses >= cb.config.HalfOpenRequests {
+			err = ErrCircuitOpen
+		} else {
+			cb.halfOpenInFlight++
+		}
+	}
+	to := cb.state
+	cb.mu.Unlock()
+
+	cb.notify(from, to)
+	return err
+}
+
+// record registers the outcome of a request permitted by allow
+func (cb *circuitBreaker) record(success bool) {
+	cb.mu.Lock()
+	from := cb.state
+	switch cb.state {
+	case CircuitClosed:
+		if time.Since(cb.windowStart) >= cb.config.Window {
+			cb.windowStart = time.Now()
+			cb.requests = 0
+			cb.failures = 0
+		}
+		cb.requests++
+		if !success {
+			cb.failures++
+		}
+		if cb.requests >= cb.config.MinimumRequests &&
+			float64(cb.failures)/float64(cb.requests) >= cb.config.FailureRateThreshold {
+			cb.setState(CircuitOpen)
+		}
+	case CircuitHalfOpen:
+		cb.halfOpenInFlight--
+		if !success {
+			cb.setState(CircuitOpen)
+		} else if cb.halfOpenSuccesses++; cb.halfOpenSuccesses >= cb.config.HalfOpenRequests {
+			cb.setState(CircuitClosed)
+		}
+	}
+	to := cb.state
+	cb.mu.Unlock()
+
+	cb.notify(from, to)
+}
+
+// setState transitions the breaker, resetting all counters.  Caller must hold lock.
+func (cb *circuitBreaker) setState(state CircuitState) {
+	cb.state = state
+	cb.windowStart = time.Now()
+	cb.requests = 0
+	cb.failures = 0
+	cb.halfOpenInFlight = 0
+	cb.halfOpenSuccesses = 0
+	if state == CircuitOpen {
+		cb.openedAt = time.Now()
+	}
+}
+
+func (cb *circuitBreaker) notify(from, to CircuitState) {
+	if from != to && cb.config.OnStateChange != nil {
+		cb.config.OnStateChange(from, to)
+	}
+}
+
+// circuitSuccess determines whether the outcome of a request counts as a success for the breaker.  Requests
+// cancelled by the caller are counted as successes as they say nothing about the server's health.
+func circuitSuccess(ctx context.Context, code int, err error) bool {
+	if err != nil {
+		return ctx.Err() != nil && errors.Is(err, ctx.Err())
+	}
+	// a full namespace says nothing about the health of the server
+	return code < 500 || code == http.StatusInsufficientStorage
+}
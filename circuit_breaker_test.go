@@ -0,0 +1,85 @@
+package lruchal_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dcarbone/lruchal"
+)
+
+func TestClientCircuitBreaker(t *testing.T) {
+	var healthy int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&healthy) == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`"value1"`))
+	}))
+	defer ts.Close()
+
+	mu := new(sync.Mutex)
+	var transitions []lruchal.CircuitState
+
+	client := newTestClient(t, ts, &lruchal.ClientConfig{
+		CircuitBreaker: &lruchal.CircuitBreakerConfig{
+			FailureRateThreshold: 0.5,
+			MinimumRequests:      4,
+			Window:               time.Minute,
+			Cooldown:             50 * time.Millisecond,
+			OnStateChange: func(from, to lruchal.CircuitState) {
+				mu.Lock()
+				transitions = append(transitions, to)
+				mu.Unlock()
+			},
+		},
+	})
+
+	for i := 0; i < 4; i++ {
+		if _, err := client.Get("key1"); err == nil || errors.Is(err, lruchal.ErrCircuitOpen) {
+			t.Logf("Expected server error on request %d, saw %v", i, err)
+			t.FailNow()
+		}
+	}
+
+	if s := client.CircuitState(); s != lruchal.CircuitOpen {
+		t.Logf("Expected circuit to be open, saw %s", s)
+		t.FailNow()
+	}
+	if _, err := client.Get("key1"); !errors.Is(err, lruchal.ErrCircuitOpen) {
+		t.Logf("Expected ErrCircuitOpen, saw %v", err)
+		t.FailNow()
+	}
+
+	atomic.StoreInt32(&healthy, 1)
+	time.Sleep(75 * time.Millisecond)
+
+	if _, err := client.Get("key1"); err != nil {
+		t.Logf("Expected half-open trial request to succeed, saw %s", err)
+		t.FailNow()
+	}
+	if s := client.CircuitState(); s != lruchal.CircuitClosed {
+		t.Logf("Expected circuit to be closed, saw %s", s)
+		t.FailNow()
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	expected := []lruchal.CircuitState{lruchal.CircuitOpen, lruchal.CircuitHalfOpen, lruchal.CircuitClosed}
+	if len(transitions) != len(expected) {
+		t.Logf("Expected transitions %v, saw %v", expected, transitions)
+		t.FailNow()
+	}
+	for i := range expected {
+		if transitions[i] != expected[i] {
+			t.Logf("Expected transitions %v, saw %v", expected, transitions)
+			t.FailNow()
+		}
+	}
+}
@@ -2,23 +2,49 @@ package lruchal
 
 import (
 	"bytes"
-	"encoding/json"
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net"
 	"net/http"
+	"net/url"
 	"runtime"
+	"strings"
 	"time"
 )
 
+const (
+	DefaultClientReadTimeout  = 5 * time.Second
+	DefaultClientWriteTimeout = 10 * time.Second
+)
+
 type ClientConfig struct {
-	Address    string
-	HttpClient *http.Client
+	Address        string // host:port of the server, optionally prefixed with tcp://, or unix:///path/to/sock to dial a unix socket
+	HttpClient     *http.Client
+	ReadTimeout    time.Duration         // default timeout for calls that do not modify the cache, applied per attempt
+	WriteTimeout   time.Duration         // default timeout for calls that modify the cache, applied per attempt
+	Retry          *RetryPolicy          // optional, if nil failed requests are not retried
+	CircuitBreaker *CircuitBreakerConfig // optional, if nil requests are always sent
+	NearCache      *NearCacheConfig      // optional, if nil every call is sent to the server
+	Codec          Codec                 // encoding used for values on the wire, defaults to json
+	UseNumber      bool                  // if true and using the default json codec, numbers are decoded as json.Number
+	Compression    int                   // if > 0, request bodies of at least this many bytes are sent gzipped
+	TLS            *ClientTLSConfig      // optional, if set the server is connected to with https
+	Token          string                // optional, sent as a bearer token with every request
+	Namespace      string                // optional, if set all calls operate on this namespace rather than the default
+	Logger         Logger
 }
 
 func NewDefaultClientConfig() *ClientConfig {
 	c := &ClientConfig{
-		Address: fmt.Sprintf("127.0.0.1:%d", DefaultPort),
+		Address:      fmt.Sprintf("127.0.0.1:%d", DefaultPort),
+		ReadTimeout:  DefaultClientReadTimeout,
+		WriteTimeout: DefaultClientWriteTimeout,
+		Logger:       DefaultLogger("client"),
 	}
 
 	c.HttpClient = &http.Client{
@@ -41,8 +67,23 @@ func NewDefaultClientConfig() *ClientConfig {
 }
 
 type Client struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	log    Logger
+
 	addr   string
+	scheme string // http, or https if tls is enabled
+	token  string
+	prefix string // path prefix selecting the namespace, if any
 	client *http.Client
+
+	readTimeout  time.Duration
+	writeTimeout time.Duration
+	retry        *RetryPolicy
+	breaker      *circuitBreaker
+	near         *nearCache
+	codec        Codec
+	compression  int
 }
 
 func NewDefaultClient() (*Client, error) {
@@ -62,63 +103,477 @@ func newClient(config *ClientConfig) (*Client, error) {
 		// TODO: replicate
 		def.HttpClient = config.HttpClient
 	}
+	if config.ReadTimeout > 0 {
+		def.ReadTimeout = config.ReadTimeout
+	}
+	if config.WriteTimeout > 0 {
+		def.WriteTimeout = config.WriteTimeout
+	}
+	if config.Retry != nil {
+		rp := *config.Retry
+		def.Retry = &rp
+	}
+	if config.CircuitBreaker != nil {
+		def.CircuitBreaker = config.CircuitBreaker
+	}
+	if config.NearCache != nil {
+		def.NearCache = config.NearCache
+	}
+	var unixPath string
+	if strings.HasPrefix(def.Address, "unix://") {
+		if unixPath = strings.TrimPrefix(def.Address, "unix://"); unixPath == "" {
+			return nil, errors.New("unix address has no path")
+		}
+		// the host is only used to build request urls, all connections go to the socket
+		def.Address = "unix"
+	}
+	def.Address = strings.TrimPrefix(def.Address, "tcp://")
+	scheme := "http"
+	var tc *tls.Config
+	if config.TLS != nil {
+		// a socket path names no host to verify the server's certificate against
+		if unixPath != "" && config.TLS.ServerName == "" {
+			return nil, errors.New("tls over a unix socket requires a ServerName")
+		}
+		var err error
+		if tc, err = config.TLS.tlsConfig(def.Address); err != nil {
+			return nil, fmt.Errorf("unable to configure tls: %s", err)
+		}
+		scheme = "https"
+	}
+	if unixPath != "" || tc != nil {
+		hc, err := transportHTTPClient(def.HttpClient, unixPath, tc)
+		if err != nil {
+			return nil, err
+		}
+		def.HttpClient = hc
+	}
+	if config.Logger != nil {
+		def.Logger = config.Logger
+	}
+	if config.Codec != nil {
+		def.Codec = config.Codec
+	} else {
+		def.Codec = &JSONCodec{UseNumber: config.UseNumber}
+	}
 
 	c := &Client{
-		addr:   def.Address,
-		client: def.HttpClient,
+		log:          def.Logger,
+		addr:         def.Address,
+		scheme:       scheme,
+		token:        config.Token,
+		client:       def.HttpClient,
+		readTimeout:  def.ReadTimeout,
+		writeTimeout: def.WriteTimeout,
+		retry:        def.Retry,
+		codec:        def.Codec,
+		compression:  config.Compression,
+	}
+
+	if config.Namespace != "" && config.Namespace != DefaultNamespace {
+		c.prefix = "/ns/" + url.PathEscape(config.Namespace)
+	}
+
+	if def.CircuitBreaker != nil {
+		c.breaker = newCircuitBreaker(def.CircuitBreaker)
+	}
+
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+
+	if def.NearCache != nil {
+		c.near = newNearCache(def.NearCache)
+		if def.NearCache.Invalidations {
+			go c.watchInvalidations(c.ctx)
+		}
 	}
 
 	return c, nil
 }
 
+// transportHTTPClient returns a copy of hc with it's transport dialing the unix socket at path if set, and using tls
+// if tc is set
+func transportHTTPClient(hc *http.Client, path string, tc *tls.Config) (*http.Client, error) {
+	var transport *http.Transport
+	switch t := hc.Transport.(type) {
+	case nil:
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	case *http.Transport:
+		transport = t.Clone()
+	default:
+		return nil, fmt.Errorf("unable to configure transport %T", hc.Transport)
+	}
+	if path != "" {
+		dialer := &net.Dialer{Timeout: 30 * time.Second}
+		transport.Proxy = nil
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "unix", path)
+		}
+	}
+	if tc != nil {
+		transport.TLSClientConfig = tc
+	}
+
+	out := *hc
+	out.Transport = transport
+	return &out, nil
+}
+
+// newRequest creates a request for path within the client's namespace
+func (c *Client) newRequest(method, path string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequest(method, fmt.Sprintf("%s://%s%s%s", c.scheme, c.addr, c.prefix, path), body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create request: %s", err)
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	return req, nil
+}
+
+// Close will stop any background routines started by this client.  The client must not be used afterwards.
+func (c *Client) Close() error {
+	c.cancel()
+	return nil
+}
+
+// CircuitState returns the current state of this client's circuit breaker.  If no breaker is configured, the circuit
+// is always closed.
+func (c *Client) CircuitState() CircuitState {
+	if c.breaker == nil {
+		return CircuitClosed
+	}
+	return c.breaker.State()
+}
+
+// request describes a single call to the server
+type request struct {
+	method     string
+	path       string
+	header     http.Header
+	body       []byte
+	timeout    time.Duration // applied per attempt, on top of any deadline already present on the caller's context
+	idempotent bool
+}
+
+type response struct {
+	code   int
+	header http.Header
+	body   []byte
+}
+
+// do will execute a request against the server, retrying per the configured RetryPolicy, returning the final
+// response.  If the circuit breaker is open, ErrCircuitOpen is returned without retrying.
+func (c *Client) do(ctx context.Context, req *request) (*response, error) {
+	req = c.compress(req)
+	for attempt := 1; ; attempt++ {
+		if c.breaker != nil {
+			if err := c.breaker.allow(); err != nil {
+				return nil, err
+			}
+		}
+		resp, err := c.doOnce(ctx, req)
+		code := 0
+		if resp != nil {
+			code = resp.code
+		}
+		if c.breaker != nil {
+			c.breaker.record(circuitSuccess(ctx, code, err))
+		}
+		if ctx.Err() != nil || !c.retry.shouldRetry(attempt, req.idempotent, code, err) {
+			return resp, err
+		}
+		if werr := c.retry.wait(ctx, attempt); werr != nil {
+			if err != nil {
+				return resp, err
+			}
+			return nil, werr
+		}
+	}
+}
+
+// compress returns a copy of req with it's body gzipped, if compression is enabled and the body is large enough to
+// benefit from it
+func (c *Client) compress(req *request) *request {
+	if c.compression <= 0 || len(req.body) < c.compression || req.header.Get("Content-Encoding") != "" {
+		return req
+	}
+	gz, err := gzipBytes(req.body, gzip.DefaultCompression)
+	if err != nil || len(gz) >= len(req.body) {
+		return req
+	}
+	out := *req
+	out.header = req.header.Clone()
+	if out.header == nil {
+		out.header = make(http.Header)
+	}
+	out.header.Set("Content-Encoding", "gzip")
+	out.body = gz
+	return &out
+}
+
+// doOnce will execute a single attempt of a request against the server
+func (c *Client) doOnce(ctx context.Context, req *request) (*response, error) {
+	if req.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, req.timeout)
+		defer cancel()
+	}
+
+	var reqBody io.Reader
+	if req.body != nil {
+		reqBody = bytes.NewReader(req.body)
+	}
+
+	httpReq, err := c.newRequest(req.method, req.path, reqBody)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range req.header {
+		httpReq.Header[k] = v
+	}
+
+	httpResp, err := c.client.Do(httpReq.WithContext(ctx))
+	if httpResp != nil {
+		defer httpResp.Body.Close()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &response{
+		code:   httpResp.StatusCode,
+		header: httpResp.Header,
+	}
+
+	resp.body, err = ioutil.ReadAll(httpResp.Body)
+	if err != nil {
+		return resp, fmt.Errorf("unable to read response: %s", err)
+	}
+
+	return resp, nil
+}
+
 func (c *Client) Get(key string) (interface{}, error) {
-	resp, err := c.client.Get(fmt.Sprintf("http://%s/get/%s", c.addr, key))
-	if resp != nil {
-		defer resp.Body.Close()
+	return c.GetContext(context.Background(), key)
+}
+
+func (c *Client) GetContext(ctx context.Context, key string) (interface{}, error) {
+	var data interface{}
+	if err := c.GetIntoContext(ctx, key, &data); err != nil {
+		return nil, err
 	}
+	return data, nil
+}
+
+// GetInto will decode the value of key directly into v, which must be a pointer
+func (c *Client) GetInto(key string, v interface{}) error {
+	return c.GetIntoContext(context.Background(), key, v)
+}
+
+func (c *Client) GetIntoContext(ctx context.Context, key string, v interface{}) error {
+	b, err := c.getRaw(ctx, key)
+	if err != nil {
+		return err
+	}
+	return c.decode(b, v)
+}
+
+// GetAs will decode the value of key into a new T
+func GetAs[T any](ctx context.Context, c *Client, key string) (T, error) {
+	var v T
+	err := c.GetIntoContext(ctx, key, &v)
+	return v, err
+}
+
+// getRaw returns the encoded value of key, from the near cache if possible
+func (c *Client) getRaw(ctx context.Context, key string) ([]byte, error) {
+	if c.near != nil {
+		if b, ok := c.near.get(key); ok {
+			return b, nil
+		}
+	}
+
+	resp, err := c.do(ctx, &request{method: "GET", path: "/get/" + url.PathEscape(key), header: c.header(false), timeout: c.readTimeout, idempotent: true})
 	if err != nil {
 		return nil, err
 	}
 
-	if resp.StatusCode == 200 {
-		b, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			return nil, fmt.Errorf("unable to read response: %s", err)
+	if resp.code == 200 {
+		if c.near != nil {
+			c.near.put(key, resp.body, 0)
+		}
+		return resp.body, nil
+	}
+
+	return nil, newServerError(resp.code, resp.body)
+}
+
+// decode will unmarshal an encoded value into v using this client's codec
+func (c *Client) decode(b []byte, v interface{}) error {
+	if err := c.codec.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("unable to unmarshal data: %s", err)
+	}
+	return nil
+}
+
+// header returns the headers sent with every request, optionally with a Content-Type for the request body
+func (c *Client) header(withBody bool) http.Header {
+	header := make(http.Header)
+	header.Set("Accept", c.codec.ContentType())
+	if withBody {
+		header.Set("Content-Type", c.codec.ContentType())
+	}
+	return header
+}
+
+func (c *Client) Put(item Item) error {
+	return c.PutContext(context.Background(), item)
+}
+
+func (c *Client) PutContext(ctx context.Context, item Item) error {
+	// an empty ttl is left to the server, which will apply the namespace's default ttl if it has one
+	var ttl time.Duration
+	if item.TTL != "" {
+		var err error
+		if ttl, err = time.ParseDuration(item.TTL); err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidTTL, err)
 		}
+	}
+
+	b, err := c.codec.Marshal(item)
+	if err != nil {
+		return fmt.Errorf("unable to serialize: %s", err)
+	}
+
+	resp, err := c.do(ctx, &request{method: "PUT", path: "/put", header: c.header(true), body: b, timeout: c.writeTimeout, idempotent: true})
+	if err != nil {
+		return err
+	}
+
+	if resp.code == 204 {
+		if c.near != nil {
+			if vb, err := c.codec.Marshal(item.Value); err == nil && ttl > 0 {
+				c.near.put(item.Key, vb, ttl)
+			} else {
+				c.near.remove(item.Key)
+			}
+		}
+		return nil
+	}
+
+	return newServerError(resp.code, resp.body)
+}
+
+// Has will return true if the server has the specified key
+func (c *Client) Has(key string) (bool, error) {
+	return c.HasContext(context.Background(), key)
+}
+
+func (c *Client) HasContext(ctx context.Context, key string) (bool, error) {
+	resp, err := c.do(ctx, &request{method: "GET", path: "/has/" + url.PathEscape(key), timeout: c.readTimeout, idempotent: true})
+	if err != nil {
+		return false, err
+	}
+
+	switch resp.code {
+	case 204:
+		return true, nil
+	case 404:
+		return false, nil
+	}
+
+	return false, newServerError(resp.code, resp.body)
+}
+
+// Remove will attempt to remove a key from the server, returning it's value.  Returns ErrNotFound if key not found.
+func (c *Client) Remove(key string) (interface{}, error) {
+	return c.RemoveContext(context.Background(), key)
+}
+
+func (c *Client) RemoveContext(ctx context.Context, key string) (interface{}, error) {
+	if c.near != nil {
+		defer c.near.remove(key)
+	}
+
+	resp, err := c.do(ctx, &request{method: "DELETE", path: "/remove/" + url.PathEscape(key), header: c.header(false), timeout: c.writeTimeout})
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.code == 200 {
 		var data interface{}
-		err = json.Unmarshal(b, &data)
-		if err != nil {
-			return nil, fmt.Errorf("unable to unmarshal data: %s", err)
+		if err := c.decode(resp.body, &data); err != nil {
+			return nil, err
 		}
 		return data, nil
 	}
 
-	return nil, fmt.Errorf("%d: %s", resp.StatusCode, resp.Status)
+	return nil, newServerError(resp.code, resp.body)
 }
 
-func (c *Client) Put(item Item) error {
-	b, err := json.Marshal(item)
+// InvalidateTag will remove every key put with tag, returning the removed keys
+func (c *Client) InvalidateTag(tag string) ([]string, error) {
+	return c.InvalidateTagContext(context.Background(), tag)
+}
+
+func (c *Client) InvalidateTagContext(ctx context.Context, tag string) ([]string, error) {
+	resp, err := c.do(ctx, &request{method: "DELETE", path: "/tag/" + url.PathEscape(tag), header: c.header(false), timeout: c.writeTimeout, idempotent: true})
 	if err != nil {
-		return fmt.Errorf("unable to serialize: %s", err)
+		return nil, err
+	}
+
+	if resp.code == 200 {
+		var keys []string
+		if err := c.decode(resp.body, &keys); err != nil {
+			return nil, err
+		}
+		if c.near != nil {
+			for _, key := range keys {
+				c.near.remove(key)
+			}
+		}
+		return keys, nil
 	}
 
-	req, err := http.NewRequest("PUT", fmt.Sprintf("http://%s/put", c.addr), bytes.NewBuffer(b))
+	return nil, newServerError(resp.code, resp.body)
+}
+
+// Len will return the number of keys currently held by the server, including expired keys not yet expunged
+func (c *Client) Len() (int, error) {
+	return c.LenContext(context.Background())
+}
+
+func (c *Client) LenContext(ctx context.Context) (int, error) {
+	resp, err := c.do(ctx, &request{method: "GET", path: "/len", header: c.header(false), timeout: c.readTimeout, idempotent: true})
 	if err != nil {
-		return fmt.Errorf("unable to create request: %s", err)
+		return 0, err
 	}
 
-	resp, err := c.client.Do(req)
-	if resp != nil {
-		defer resp.Body.Close()
+	if resp.code == 200 {
+		var l int
+		if err := c.decode(resp.body, &l); err != nil {
+			return 0, err
+		}
+		return l, nil
 	}
+
+	return 0, newServerError(resp.code, resp.body)
+}
+
+// Expunge will ask the server to remove all expired keys
+func (c *Client) Expunge() error {
+	return c.ExpungeContext(context.Background())
+}
+
+func (c *Client) ExpungeContext(ctx context.Context) error {
+	resp, err := c.do(ctx, &request{method: "POST", path: "/expunge", timeout: c.writeTimeout, idempotent: true})
 	if err != nil {
 		return err
 	}
 
-	if resp.StatusCode == 204 {
+	if resp.code == 204 {
 		return nil
 	}
 
-	b, _ = ioutil.ReadAll(resp.Body)
-	return fmt.Errorf("%d: %s", resp.StatusCode, string(b))
+	return newServerError(resp.code, resp.body)
 }
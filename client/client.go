@@ -45,7 +45,7 @@ func seed() error {
 	}
 
 	for i := 0; i < int(flagCount); i++ {
-		err := client.Put(lruchal.Item{fmt.Sprintf("key%d", i), fmt.Sprintf("value%d", i), flagTTL})
+		err := client.Put(lruchal.Item{Key: fmt.Sprintf("key%d", i), Value: fmt.Sprintf("value%d", i), TTL: flagTTL})
 		if err != nil {
 			return err
 		}
@@ -114,7 +114,7 @@ func repl() error {
 					} else if _, err := time.ParseDuration(*ttlPtr); err != nil {
 						fmt.Fprintf(os.Stdout, "invalid ttl format specified: %s\n", err)
 					} else {
-						err := client.Put(lruchal.Item{*keyPtr, *valuePtr, *ttlPtr})
+						err := client.Put(lruchal.Item{Key: *keyPtr, Value: *valuePtr, TTL: *ttlPtr})
 						if err != nil {
 							fmt.Fprintf(os.Stdout, "Error: %s\n", err)
 						} else {
@@ -0,0 +1,313 @@
+package lruchal_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dcarbone/lruchal"
+)
+
+func newTestClient(t *testing.T, ts *httptest.Server, config *lruchal.ClientConfig) *lruchal.Client {
+	if config == nil {
+		config = new(lruchal.ClientConfig)
+	}
+	config.Address = strings.TrimPrefix(ts.URL, "http://")
+	client, err := lruchal.NewClient(config)
+	if err != nil {
+		t.Logf("Unable to create client: %s", err)
+		t.FailNow()
+	}
+	return client
+}
+
+func TestClient(t *testing.T) {
+	t.Run("ContextCancellation", func(t *testing.T) {
+		done := make(chan struct{})
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-done
+		}))
+		defer ts.Close()
+		defer close(done)
+
+		client := newTestClient(t, ts, nil)
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		if _, err := client.GetContext(ctx, "key1"); err == nil {
+			t.Log("Expected error from cancelled context")
+			t.FailNow()
+		}
+		if d := time.Since(start); d > time.Second {
+			t.Logf("Expected request to be cancelled promptly, took %s", d)
+			t.FailNow()
+		}
+	})
+
+	t.Run("ReadTimeout", func(t *testing.T) {
+		done := make(chan struct{})
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-done
+		}))
+		defer ts.Close()
+		defer close(done)
+
+		client := newTestClient(t, ts, &lruchal.ClientConfig{ReadTimeout: 50 * time.Millisecond})
+
+		start := time.Now()
+		if _, err := client.Has("key1"); err == nil {
+			t.Log("Expected error from timed out request")
+			t.FailNow()
+		}
+		if d := time.Since(start); d > time.Second {
+			t.Logf("Expected request to time out promptly, took %s", d)
+			t.FailNow()
+		}
+	})
+
+	t.Run("TypedErrors", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/get/missing":
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`{"code":"not_found","message":"Key \"missing\" not found"}`))
+			case "/get/conflict":
+				w.WriteHeader(http.StatusConflict)
+				w.Write([]byte("nope"))
+			default:
+				w.WriteHeader(http.StatusInternalServerError)
+				w.Write([]byte(`{"code":"internal","message":"boom"}`))
+			}
+		}))
+		defer ts.Close()
+
+		client := newTestClient(t, ts, nil)
+
+		if _, err := client.Get("missing"); !errors.Is(err, lruchal.ErrNotFound) {
+			t.Logf("Expected ErrNotFound, saw %v", err)
+			t.FailNow()
+		}
+		if _, err := client.Get("conflict"); !errors.Is(err, lruchal.ErrConflict) {
+			t.Logf("Expected ErrConflict, saw %v", err)
+			t.FailNow()
+		}
+
+		_, err := client.Get("other")
+		se := new(lruchal.ServerError)
+		if !errors.As(err, &se) {
+			t.Logf("Expected *ServerError, saw %T", err)
+			t.FailNow()
+		}
+		if se.StatusCode != http.StatusInternalServerError || se.Code != "internal" || se.Message != "boom" {
+			t.Logf("Unexpected ServerError contents: %#v", se)
+			t.FailNow()
+		}
+
+		if err := client.Put(lruchal.Item{Key: "key1", Value: "value1", TTL: "forever"}); !errors.Is(err, lruchal.ErrInvalidTTL) {
+			t.Logf("Expected ErrInvalidTTL, saw %v", err)
+			t.FailNow()
+		}
+	})
+	t.Run("Namespace", func(t *testing.T) {
+		var paths []string
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			paths = append(paths, r.URL.Path)
+			w.Write([]byte("1"))
+		}))
+		defer ts.Close()
+
+		client := newTestClient(t, ts, &lruchal.ClientConfig{Namespace: "sessions"})
+		if _, err := client.Len(); err != nil {
+			t.Logf("Unexpected error: %s", err)
+			t.FailNow()
+		}
+		client = newTestClient(t, ts, &lruchal.ClientConfig{Namespace: lruchal.DefaultNamespace})
+		if _, err := client.Len(); err != nil {
+			t.Logf("Unexpected error: %s", err)
+			t.FailNow()
+		}
+
+		if len(paths) != 2 || paths[0] != "/ns/sessions/len" || paths[1] != "/len" {
+			t.Logf("Unexpected request paths: %v", paths)
+			t.FailNow()
+		}
+	})
+}
+
+func TestClientTypedDecoding(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"name":"widget","count":9007199254740993}`))
+	}))
+	defer ts.Close()
+
+	type widget struct {
+		Name  string `json:"name"`
+		Count int64  `json:"count"`
+	}
+
+	t.Run("GetInto", func(t *testing.T) {
+		client := newTestClient(t, ts, nil)
+		w := new(widget)
+		if err := client.GetInto("key1", w); err != nil {
+			t.Logf("Unexpected error: %s", err)
+			t.FailNow()
+		}
+		if w.Name != "widget" || w.Count != 9007199254740993 {
+			t.Logf("Unexpected value: %#v", w)
+			t.FailNow()
+		}
+	})
+
+	t.Run("GetAs", func(t *testing.T) {
+		client := newTestClient(t, ts, nil)
+		w, err := lruchal.GetAs[widget](context.Background(), client, "key1")
+		if err != nil {
+			t.Logf("Unexpected error: %s", err)
+			t.FailNow()
+		}
+		if w.Name != "widget" || w.Count != 9007199254740993 {
+			t.Logf("Unexpected value: %#v", w)
+			t.FailNow()
+		}
+	})
+
+	t.Run("UseNumber", func(t *testing.T) {
+		client := newTestClient(t, ts, &lruchal.ClientConfig{UseNumber: true})
+		v, err := client.Get("key1")
+		if err != nil {
+			t.Logf("Unexpected error: %s", err)
+			t.FailNow()
+		}
+		n, ok := v.(map[string]interface{})["count"].(json.Number)
+		if !ok || n.String() != "9007199254740993" {
+			t.Logf("Expected json.Number 9007199254740993, saw %#v", v)
+			t.FailNow()
+		}
+	})
+}
+
+func TestClientCompression(t *testing.T) {
+	var sawEncoding string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sawEncoding = r.Header.Get("Content-Encoding")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	client := newTestClient(t, ts, &lruchal.ClientConfig{Compression: 512})
+
+	if err := client.Put(lruchal.Item{Key: "small", Value: "value", TTL: "1m"}); err != nil {
+		t.Logf("Unexpected error: %s", err)
+		t.FailNow()
+	}
+	if sawEncoding != "" {
+		t.Logf("Expected small body to be sent uncompressed, saw encoding \"%s\"", sawEncoding)
+		t.FailNow()
+	}
+
+	if err := client.Put(lruchal.Item{Key: "large", Value: strings.Repeat("value", 1000), TTL: "1m"}); err != nil {
+		t.Logf("Unexpected error: %s", err)
+		t.FailNow()
+	}
+	if sawEncoding != "gzip" {
+		t.Logf("Expected large body to be sent gzipped, saw encoding \"%s\"", sawEncoding)
+		t.FailNow()
+	}
+}
+
+func TestServerMaxBodySize(t *testing.T) {
+	srv := newTestServer(t, &lruchal.ServerConfig{Port: lruchal.RandomPort, BindAddress: "127.0.0.1", MaxBodySize: 4096})
+	if err := srv.Listen(); err != nil {
+		t.Logf("Unable to listen: %s", err)
+		t.FailNow()
+	}
+	go srv.Serve()
+	defer srv.Close()
+
+	for _, compression := range []int{0, 512} {
+		client, err := lruchal.NewClient(&lruchal.ClientConfig{Address: srv.Addr().String(), Compression: compression})
+		if err != nil {
+			t.Logf("Unable to create client: %s", err)
+			t.FailNow()
+		}
+		defer client.Close()
+
+		if err := client.Put(lruchal.Item{Key: "small", Value: "value", TTL: "1m"}); err != nil {
+			t.Logf("Unable to put small value: %s", err)
+			t.FailNow()
+		}
+		// when compressed this is well under the limit on the wire, but not once decompressed
+		err = client.Put(lruchal.Item{Key: "large", Value: strings.Repeat("a", 1<<20), TTL: "1m"})
+		if !errors.Is(err, lruchal.ErrBodyTooLarge) {
+			t.Logf("Expected ErrBodyTooLarge with compression %d, saw %v", compression, err)
+			t.FailNow()
+		}
+	}
+}
+
+// unavailableKeys is a KeyProvider which is never able to return a key
+type unavailableKeys struct{}
+
+func (unavailableKeys) CurrentKey() (string, []byte, error) {
+	return "", nil, errors.New("key store unavailable")
+}
+
+func (unavailableKeys) Key(string) ([]byte, error) {
+	return nil, errors.New("key store unavailable")
+}
+
+func TestServerStoreError(t *testing.T) {
+	srv := newTestServer(t, &lruchal.ServerConfig{
+		Port:          lruchal.RandomPort,
+		RESPPort:      lruchal.RandomPort,
+		MemcachedPort: lruchal.RandomPort,
+		BindAddress:   "127.0.0.1",
+		Encryption:    unavailableKeys{},
+	})
+	if err := srv.Listen(); err != nil {
+		t.Logf("Unable to listen: %s", err)
+		t.FailNow()
+	}
+	go srv.Serve()
+	defer srv.Close()
+
+	client, err := lruchal.NewClient(&lruchal.ClientConfig{Address: srv.Addr().String()})
+	if err != nil {
+		t.Logf("Unable to create client: %s", err)
+		t.FailNow()
+	}
+	defer client.Close()
+
+	// a put which cannot be encrypted must be refused, rather than reported as stored while being dropped
+	err = client.Put(lruchal.Item{Key: "key1", Value: "value1", TTL: "1m"})
+	var se *lruchal.ServerError
+	if !errors.As(err, &se) || se.StatusCode != http.StatusInternalServerError || se.Code != lruchal.ErrorCodeStoreFailed {
+		t.Logf("Expected 500 %s, saw %v", lruchal.ErrorCodeStoreFailed, err)
+		t.FailNow()
+	}
+	if errors.Is(err, lruchal.ErrQuotaExceeded) {
+		t.Logf("Expected an error other than ErrQuotaExceeded, saw %v", err)
+		t.FailNow()
+	}
+	if err := client.PutBytes("key2", []byte("value2"), "", time.Minute); !errors.As(err, &se) || se.StatusCode != http.StatusInternalServerError {
+		t.Logf("Expected 500 putting bytes, saw %v", err)
+		t.FailNow()
+	}
+
+	rc := dialRESP(t, srv)
+	rc.send("SET", "key1", "value1")
+	if reply, ok := rc.read().(respError); !ok || !strings.HasPrefix(string(reply), "ERR ") {
+		t.Logf("Expected an ERR reply from resp, saw %#v", reply)
+		t.FailNow()
+	}
+
+	mc := dialMemcached(t, srv)
+	mc.expect("set key1 0 0 6\r\nvalue1\r\n", "SERVER_ERROR unable to store object")
+}
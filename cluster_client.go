@@ -0,0 +1,303 @@
+package lruchal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultClusterVirtualNodes    = 160
+	DefaultClusterFailureCooldown = 10 * time.Second
+)
+
+type ClusterClientConfig struct {
+	Addresses       []string      // initial list of node addresses
+	VirtualNodes    int           // number of points each node occupies on the hash ring
+	FailureCooldown time.Duration // length of time a failed node is skipped before being tried again
+	ClientConfig    *ClientConfig // optional template used to construct each node's Client, Address is ignored
+}
+
+func NewDefaultClusterClientConfig() *ClusterClientConfig {
+	c := &ClusterClientConfig{
+		VirtualNodes:    DefaultClusterVirtualNodes,
+		FailureCooldown: DefaultClusterFailureCooldown,
+		ClientConfig:    new(ClientConfig),
+	}
+	return c
+}
+
+type clusterNode struct {
+	client    *Client
+	downUntil time.Time
+}
+
+// ClusterClient distributes keys across several servers using consistent hashing, so that adding or removing a node
+// only moves the keys owned by that node.  Nodes that fail with a transport error are skipped for FailureCooldown,
+// with their keys served by the next node on the ring in the meantime.
+type ClusterClient struct {
+	mu       *sync.RWMutex
+	ring     *hashRing
+	nodes    map[string]*clusterNode
+	cooldown time.Duration
+	template ClientConfig
+}
+
+func NewClusterClient(config *ClusterClientConfig) (*ClusterClient, error) {
+	def := NewDefaultClusterClientConfig()
+	if config.VirtualNodes > 0 {
+		def.VirtualNodes = config.VirtualNodes
+	}
+	if config.FailureCooldown > 0 {
+		def.FailureCooldown = config.FailureCooldown
+	}
+	if config.ClientConfig != nil {
+		def.ClientConfig = config.ClientConfig
+	}
+
+	cc := &ClusterClient{
+		mu:       new(sync.RWMutex),
+		ring:     newHashRing(def.VirtualNodes),
+		nodes:    make(map[string]*clusterNode),
+		cooldown: def.FailureCooldown,
+		template: *def.ClientConfig,
+	}
+
+	for _, addr := range config.Addresses {
+		if err := cc.AddNode(addr); err != nil {
+			cc.Close()
+			return nil, err
+		}
+	}
+
+	return cc, nil
+}
+
+// AddNode will add a server to the cluster.  Adding an existing node is a no-op.
+func (cc *ClusterClient) AddNode(addr string) error {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	if _, ok := cc.nodes[addr]; ok {
+		return nil
+	}
+	config := cc.template
+	config.Address = addr
+	client, err := NewClient(&config)
+	if err != nil {
+		return fmt.Errorf("unable to create client for node \"%s\": %s", addr, err)
+	}
+	cc.nodes[addr] = &clusterNode{client: client}
+	cc.ring.add(addr)
+	return nil
+}
+
+// RemoveNode will remove a server from the cluster
+func (cc *ClusterClient) RemoveNode(addr string) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	if node, ok := cc.nodes[addr]; ok {
+		node.client.Close()
+		delete(cc.nodes, addr)
+		cc.ring.remove(addr)
+	}
+}
+
+// Nodes returns the addresses of all nodes in the cluster, including those currently marked as failed
+func (cc *ClusterClient) Nodes() []string {
+	cc.mu.RLock()
+	defer cc.mu.RUnlock()
+	nodes := make([]string, 0, len(cc.nodes))
+	for addr := range cc.nodes {
+		nodes = append(nodes, addr)
+	}
+	sort.Strings(nodes)
+	return nodes
+}
+
+// NodeFor returns the address of the node currently responsible for key, or "" if no node is available
+func (cc *ClusterClient) NodeFor(key string) string {
+	addr, _ := cc.nodeFor(key)
+	return addr
+}
+
+func (cc *ClusterClient) nodeFor(key string) (string, *Client) {
+	cc.mu.RLock()
+	defer cc.mu.RUnlock()
+	now := time.Now()
+	addr := cc.ring.get(key, func(addr string) bool {
+		return now.Before(cc.nodes[addr].downUntil)
+	})
+	if addr == "" {
+		return "", nil
+	}
+	return addr, cc.nodes[addr].client
+}
+
+// observe marks a node as failed if err indicates the node could not be reached
+func (cc *ClusterClient) observe(ctx context.Context, addr string, err error) {
+	if err == nil || ctx.Err() != nil {
+		return
+	}
+	var netErr net.Error
+	if !errors.As(err, &netErr) && !errors.Is(err, ErrCircuitOpen) {
+		return
+	}
+	cc.mu.Lock()
+	if node, ok := cc.nodes[addr]; ok {
+		node.downUntil = time.Now().Add(cc.cooldown)
+	}
+	cc.mu.Unlock()
+}
+
+// Close will close the clients of all nodes
+func (cc *ClusterClient) Close() error {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	for _, node := range cc.nodes {
+		node.client.Close()
+	}
+	return nil
+}
+
+func (cc *ClusterClient) Get(key string) (interface{}, error) {
+	return cc.GetContext(context.Background(), key)
+}
+
+func (cc *ClusterClient) GetContext(ctx context.Context, key string) (interface{}, error) {
+	addr, client := cc.nodeFor(key)
+	if client == nil {
+		return nil, ErrNoAvailableNodes
+	}
+	v, err := client.GetContext(ctx, key)
+	cc.observe(ctx, addr, err)
+	return v, err
+}
+
+// GetInto will decode the value of key directly into v, which must be a pointer
+func (cc *ClusterClient) GetInto(key string, v interface{}) error {
+	return cc.GetIntoContext(context.Background(), key, v)
+}
+
+func (cc *ClusterClient) GetIntoContext(ctx context.Context, key string, v interface{}) error {
+	addr, client := cc.nodeFor(key)
+	if client == nil {
+		return ErrNoAvailableNodes
+	}
+	err := client.GetIntoContext(ctx, key, v)
+	cc.observe(ctx, addr, err)
+	return err
+}
+
+func (cc *ClusterClient) Put(item Item) error {
+	return cc.PutContext(context.Background(), item)
+}
+
+func (cc *ClusterClient) PutContext(ctx context.Context, item Item) error {
+	addr, client := cc.nodeFor(item.Key)
+	if client == nil {
+		return ErrNoAvailableNodes
+	}
+	err := client.PutContext(ctx, item)
+	cc.observe(ctx, addr, err)
+	return err
+}
+
+func (cc *ClusterClient) Has(key string) (bool, error) {
+	return cc.HasContext(context.Background(), key)
+}
+
+func (cc *ClusterClient) HasContext(ctx context.Context, key string) (bool, error) {
+	addr, client := cc.nodeFor(key)
+	if client == nil {
+		return false, ErrNoAvailableNodes
+	}
+	ok, err := client.HasContext(ctx, key)
+	cc.observe(ctx, addr, err)
+	return ok, err
+}
+
+func (cc *ClusterClient) Remove(key string) (interface{}, error) {
+	return cc.RemoveContext(context.Background(), key)
+}
+
+func (cc *ClusterClient) RemoveContext(ctx context.Context, key string) (interface{}, error) {
+	addr, client := cc.nodeFor(key)
+	if client == nil {
+		return nil, ErrNoAvailableNodes
+	}
+	v, err := client.RemoveContext(ctx, key)
+	cc.observe(ctx, addr, err)
+	return v, err
+}
+
+// each calls fn for every available node, returning the first error seen
+func (cc *ClusterClient) each(ctx context.Context, fn func(*Client) error) error {
+	cc.mu.RLock()
+	now := time.Now()
+	clients := make(map[string]*Client, len(cc.nodes))
+	for addr, node := range cc.nodes {
+		if !now.Before(node.downUntil) {
+			clients[addr] = node.client
+		}
+	}
+	cc.mu.RUnlock()
+
+	if len(clients) == 0 {
+		return ErrNoAvailableNodes
+	}
+
+	var first error
+	for addr, client := range clients {
+		err := fn(client)
+		cc.observe(ctx, addr, err)
+		if err != nil && first == nil {
+			first = fmt.Errorf("node \"%s\": %w", addr, err)
+		}
+	}
+	return first
+}
+
+// Len returns the sum of the number of keys held by all available nodes
+func (cc *ClusterClient) Len() (int, error) {
+	return cc.LenContext(context.Background())
+}
+
+func (cc *ClusterClient) LenContext(ctx context.Context) (int, error) {
+	total := 0
+	err := cc.each(ctx, func(client *Client) error {
+		l, err := client.LenContext(ctx)
+		total += l
+		return err
+	})
+	return total, err
+}
+
+// InvalidateTag asks all available nodes to remove every key put with tag, returning the removed keys
+func (cc *ClusterClient) InvalidateTag(tag string) ([]string, error) {
+	return cc.InvalidateTagContext(context.Background(), tag)
+}
+
+func (cc *ClusterClient) InvalidateTagContext(ctx context.Context, tag string) ([]string, error) {
+	var keys []string
+	err := cc.each(ctx, func(client *Client) error {
+		k, err := client.InvalidateTagContext(ctx, tag)
+		keys = append(keys, k...)
+		return err
+	})
+	return keys, err
+}
+
+// Expunge asks all available nodes to remove their expired keys
+func (cc *ClusterClient) Expunge() error {
+	return cc.ExpungeContext(context.Background())
+}
+
+func (cc *ClusterClient) ExpungeContext(ctx context.Context) error {
+	return cc.each(ctx, func(client *Client) error {
+		return client.ExpungeContext(ctx)
+	})
+}
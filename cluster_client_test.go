@@ -0,0 +1,130 @@
+package lruchal_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dcarbone/lruchal"
+)
+
+func newClusterTestServers(n int) ([]*httptest.Server, []string) {
+	servers := make([]*httptest.Server, n)
+	addrs := make([]string, n)
+	for i := range servers {
+		servers[i] = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		addrs[i] = strings.TrimPrefix(servers[i].URL, "http://")
+	}
+	return servers, addrs
+}
+
+func TestClusterClient(t *testing.T) {
+	t.Run("MinimalKeyMovement", func(t *testing.T) {
+		servers, addrs := newClusterTestServers(5)
+		for _, ts := range servers {
+			defer ts.Close()
+		}
+
+		cc, err := lruchal.NewClusterClient(&lruchal.ClusterClientConfig{Addresses: addrs[:4]})
+		if err != nil {
+			t.Logf("Unable to create cluster client: %s", err)
+			t.FailNow()
+		}
+		defer cc.Close()
+
+		const keys = 10000
+		before := make(map[string]string, keys)
+		counts := make(map[string]int)
+		for i := 0; i < keys; i++ {
+			key := fmt.Sprintf("key%d", i)
+			before[key] = cc.NodeFor(key)
+			counts[before[key]]++
+		}
+		for _, addr := range addrs[:4] {
+			if c := counts[addr]; c < keys/8 || c > keys/2 {
+				t.Logf("Poor key distribution: %v", counts)
+				t.FailNow()
+			}
+		}
+
+		cc.AddNode(addrs[4])
+		moved := 0
+		for key, addr := range before {
+			if now := cc.NodeFor(key); now != addr {
+				if now != addrs[4] {
+					t.Logf("Key \"%s\" moved between existing nodes %s -> %s", key, addr, now)
+					t.FailNow()
+				}
+				moved++
+			}
+		}
+		if moved == 0 || moved > keys/3 {
+			t.Logf("Expected roughly 1/5 of keys to move to the new node, saw %d of %d", moved, keys)
+			t.FailNow()
+		}
+
+		cc.RemoveNode(addrs[4])
+		for key, addr := range before {
+			if now := cc.NodeFor(key); now != addr {
+				t.Logf("Expected key \"%s\" to return to %s after removal, saw %s", key, addr, now)
+				t.FailNow()
+			}
+		}
+	})
+
+	t.Run("FailedNodeMarkedOut", func(t *testing.T) {
+		servers, addrs := newClusterTestServers(3)
+		for _, ts := range servers[1:] {
+			defer ts.Close()
+		}
+
+		cc, _ := lruchal.NewClusterClient(&lruchal.ClusterClientConfig{
+			Addresses:       addrs,
+			FailureCooldown: 50 * time.Millisecond,
+		})
+		defer cc.Close()
+
+		var key string
+		for i := 0; ; i++ {
+			key = fmt.Sprintf("key%d", i)
+			if cc.NodeFor(key) == addrs[0] {
+				break
+			}
+		}
+
+		servers[0].Close()
+
+		if err := cc.Put(lruchal.Item{Key: key, Value: "value", TTL: "1m"}); err == nil {
+			t.Log("Expected put to closed node to fail")
+			t.FailNow()
+		}
+		if n := cc.NodeFor(key); n == addrs[0] || n == "" {
+			t.Logf("Expected failed node to be skipped, saw \"%s\"", n)
+			t.FailNow()
+		}
+		if err := cc.Put(lruchal.Item{Key: key, Value: "value", TTL: "1m"}); err != nil {
+			t.Logf("Expected put to succeed against next node, saw %s", err)
+			t.FailNow()
+		}
+
+		time.Sleep(75 * time.Millisecond)
+		if n := cc.NodeFor(key); n != addrs[0] {
+			t.Logf("Expected failed node to return after cooldown, saw \"%s\"", n)
+			t.FailNow()
+		}
+	})
+
+	t.Run("NoNodes", func(t *testing.T) {
+		cc, _ := lruchal.NewClusterClient(new(lruchal.ClusterClientConfig))
+		if _, err := cc.Get("key1"); !errors.Is(err, lruchal.ErrNoAvailableNodes) {
+			t.Logf("Expected ErrNoAvailableNodes, saw %v", err)
+			t.FailNow()
+		}
+	})
+}
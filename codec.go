@@ -0,0 +1,330 @@
+package lruchal
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+const (
+	ContentTypeJSON    = "application/json"
+	ContentTypeGob     = "application/x-gob"
+	ContentTypeMsgPack = "application/msgpack"
+)
+
+// Codec is used by Server and Client to encode and decode values on the wire.  The codec used for a request body is
+// selected by it's Content-Type header, and the codec used for a response by the request's Accept header.
+type Codec interface {
+	ContentType() string
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(b []byte, v interface{}) error
+}
+
+var (
+	codecsMu = new(sync.RWMutex)
+	codecs   = map[string]Codec{
+		// the server decodes json numbers as json.Number so that they are returned exactly as they were sent
+		ContentTypeJSON:         &JSONCodec{UseNumber: true},
+		ContentTypeGob:          new(GobCodec),
+		ContentTypeMsgPack:      new(MsgPackCodec),
+		"application/x-msgpack": new(MsgPackCodec),
+	}
+)
+
+// RegisterCodec makes a codec available to Server under it's content type, replacing any existing codec
+func RegisterCodec(codec Codec) {
+	codecsMu.Lock()
+	codecs[codec.ContentType()] = codec
+	codecsMu.Unlock()
+}
+
+// codecForContentType returns the codec for a Content-Type header value.  An empty value selects json.
+func codecForContentType(contentType string) (Codec, bool) {
+	if contentType == "" {
+		contentType = ContentTypeJSON
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, false
+	}
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+	codec, ok := codecs[mediaType]
+	return codec, ok
+}
+
+// negotiateCodec returns the first codec acceptable to an Accept header value, falling back to json
+func negotiateCodec(accept string) Codec {
+	for _, part := range strings.Split(accept, ",") {
+		mediaType := strings.TrimSpace(strings.SplitN(part, ";", 2)[0])
+		if mediaType == "" || mediaType == "*/*" || mediaType == "application/*" {
+			break
+		}
+		if codec, ok := codecForContentType(mediaType); ok {
+			return codec
+		}
+	}
+	codec, _ := codecForContentType(ContentTypeJSON)
+	return codec
+}
+
+type JSONCodec struct {
+	UseNumber bool // if true, numbers decoded into interface{} are json.Number rather than float64
+}
+
+func (*JSONCodec) ContentType() string {
+	return ContentTypeJSON
+}
+
+func (*JSONCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (c *JSONCodec) Unmarshal(b []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	if c.UseNumber {
+		dec.UseNumber()
+	}
+	return dec.Decode(v)
+}
+
+func init() {
+	gob.Register(Item{})
+	gob.Register(new(BinaryValue))
+	gob.Register(new(compressedValue))
+	gob.Register(json.Number(""))
+	gob.Register(map[string]interface{}{})
+	gob.Register([]interface{}{})
+}
+
+// gobEnvelope allows any value, including nil and bare interfaces, to be sent with gob
+type gobEnvelope struct {
+	V interface{}
+}
+
+// GobCodec encodes values with encoding/gob.  Any type sent as a value, other than the basic types and those used by
+// this package, must be registered on both the client and server with gob.Register.
+type GobCodec struct{}
+
+func (*GobCodec) ContentType() string {
+	return ContentTypeGob
+}
+
+func (*GobCodec) Marshal(v interface{}) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := gob.NewEncoder(buf).Encode(&gobEnvelope{V: v}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (*GobCodec) Unmarshal(b []byte, v interface{}) error {
+	env := new(gobEnvelope)
+	if err := gob.NewDecoder(bytes.NewReader(b)).Decode(env); err != nil {
+		return err
+	}
+	return assignValue(v, env.V)
+}
+
+// assignValue stores a decoded generic value into v, which must be a non-nil pointer
+func assignValue(v interface{}, src interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("cannot decode into non-pointer %T", v)
+	}
+	return assign(rv.Elem(), src)
+}
+
+// assign stores src into dst, converting between compatible kinds.  Maps are assigned to structs by matching keys
+// against json field names, so that values decode the same regardless of codec.
+func assign(dst reflect.Value, src interface{}) error {
+	if src == nil {
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	}
+
+	sv := reflect.ValueOf(src)
+	if sv.Type().AssignableTo(dst.Type()) {
+		dst.Set(sv)
+		return nil
+	}
+
+	switch dst.Kind() {
+	case reflect.Ptr:
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		return assign(dst.Elem(), src)
+
+	case reflect.Bool:
+		if b, ok := src.(bool); ok {
+			dst.SetBool(b)
+			return nil
+		}
+
+	case reflect.String:
+		switch s := src.(type) {
+		case string:
+			dst.SetString(s)
+			return nil
+		case []byte:
+			dst.SetString(string(s))
+			return nil
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		switch sv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if !dst.OverflowInt(sv.Int()) {
+				dst.SetInt(sv.Int())
+				return nil
+			}
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if u := sv.Uint(); u <= 1<<63-1 && !dst.OverflowInt(int64(u)) {
+				dst.SetInt(int64(u))
+				return nil
+			}
+		case reflect.Float32, reflect.Float64:
+			if f := sv.Float(); f == float64(int64(f)) && !dst.OverflowInt(int64(f)) {
+				dst.SetInt(int64(f))
+				return nil
+			}
+		}
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		switch sv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if i := sv.Int(); i >= 0 && !dst.OverflowUint(uint64(i)) {
+				dst.SetUint(uint64(i))
+				return nil
+			}
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if !dst.OverflowUint(sv.Uint()) {
+				dst.SetUint(sv.Uint())
+				return nil
+			}
+		case reflect.Float32, reflect.Float64:
+			if f := sv.Float(); f >= 0 && f == float64(uint64(f)) && !dst.OverflowUint(uint64(f)) {
+				dst.SetUint(uint64(f))
+				return nil
+			}
+		}
+
+	case reflect.Float32, reflect.Float64:
+		switch sv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			dst.SetFloat(float64(sv.Int()))
+			return nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			dst.SetFloat(float64(sv.Uint()))
+			return nil
+		case reflect.Float32, reflect.Float64:
+			dst.SetFloat(sv.Float())
+			return nil
+		}
+
+	case reflect.Slice:
+		if dst.Type().Elem().Kind() == reflect.Uint8 {
+			if s, ok := src.(string); ok {
+				dst.SetBytes([]byte(s))
+				return nil
+			}
+		}
+		if sv.Kind() == reflect.Slice || sv.Kind() == reflect.Array {
+			out := reflect.MakeSlice(dst.Type(), sv.Len(), sv.Len())
+			for i := 0; i < sv.Len(); i++ {
+				if err := assign(out.Index(i), sv.Index(i).Interface()); err != nil {
+					return err
+				}
+			}
+			dst.Set(out)
+			return nil
+		}
+
+	case reflect.Array:
+		if sv.Kind() == reflect.Slice || sv.Kind() == reflect.Array {
+			if sv.Len() != dst.Len() {
+				return fmt.Errorf("cannot decode %d elements into %s", sv.Len(), dst.Type())
+			}
+			for i := 0; i < sv.Len(); i++ {
+				if err := assign(dst.Index(i), sv.Index(i).Interface()); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+	case reflect.Map:
+		if sv.Kind() == reflect.Map && dst.Type().Key().Kind() == reflect.String {
+			out := reflect.MakeMapWithSize(dst.Type(), sv.Len())
+			iter := sv.MapRange()
+			for iter.Next() {
+				elem := reflect.New(dst.Type().Elem()).Elem()
+				if err := assign(elem, iter.Value().Interface()); err != nil {
+					return err
+				}
+				out.SetMapIndex(reflect.ValueOf(fmt.Sprint(iter.Key().Interface())).Convert(dst.Type().Key()), elem)
+			}
+			dst.Set(out)
+			return nil
+		}
+
+	case reflect.Struct:
+		if m, ok := src.(map[string]interface{}); ok {
+			return assignStruct(dst, m)
+		}
+
+	case reflect.Interface:
+		if sv.Type().Implements(dst.Type()) {
+			dst.Set(sv)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("cannot decode %T into %s", src, dst.Type())
+}
+
+func assignStruct(dst reflect.Value, m map[string]interface{}) error {
+	t := dst.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name := fieldName(field)
+		if name == "-" {
+			continue
+		}
+		src, ok := m[name]
+		if !ok {
+			for k, v := range m {
+				if strings.EqualFold(k, name) {
+					src, ok = v, true
+					break
+				}
+			}
+		}
+		if !ok {
+			continue
+		}
+		if err := assign(dst.Field(i), src); err != nil {
+			return fmt.Errorf("field %s: %s", field.Name, err)
+		}
+	}
+	return nil
+}
+
+// fieldName returns the name a struct field is encoded under, honoring json tags
+func fieldName(field reflect.StructField) string {
+	if tag, ok := field.Tag.Lookup("json"); ok {
+		if name := strings.Split(tag, ",")[0]; name != "" {
+			return name
+		}
+	}
+	return field.Name
+}
@@ -0,0 +1,456 @@
+package lruchal
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// MsgPackCodec encodes values as MessagePack (https://msgpack.org).  Unlike json, integers are decoded as int64 (or
+// uint64 if too large) rather than float64.  Structs are encoded as maps keyed by their json field names.
+type MsgPackCodec struct{}
+
+func (*MsgPackCodec) ContentType() string {
+	return ContentTypeMsgPack
+}
+
+func (*MsgPackCodec) Marshal(v interface{}) ([]byte, error) {
+	enc := &msgpackEncoder{buf: make([]byte, 0, 64)}
+	if err := enc.encode(reflect.ValueOf(v)); err != nil {
+		return nil, err
+	}
+	return enc.buf, nil
+}
+
+func (*MsgPackCodec) Unmarshal(b []byte, v interface{}) error {
+	dec := &msgpackDecoder{buf: b}
+	src, err := dec.decode()
+	if err != nil {
+		return err
+	}
+	if dec.pos != len(dec.buf) {
+		return fmt.Errorf("msgpack: %d trailing bytes", len(dec.buf)-dec.pos)
+	}
+	return assignValue(v, src)
+}
+
+type msgpackEncoder struct {
+	buf []byte
+}
+
+func (e *msgpackEncoder) write(b ...byte) {
+	e.buf = append(e.buf, b...)
+}
+
+func (e *msgpackEncoder) writeUint16(code byte, n uint16) {
+	e.write(code)
+	e.buf = append(e.buf, byte(n>>8), byte(n))
+}
+
+func (e *msgpackEncoder) writeUint32(code byte, n uint32) {
+	e.write(code)
+	e.buf = append(e.buf, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+}
+
+func (e *msgpackEncoder) writeUint64(code byte, n uint64) {
+	e.write(code)
+	e.buf = append(e.buf, byte(n>>56), byte(n>>48), byte(n>>40), byte(n>>32), byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+}
+
+func (e *msgpackEncoder) encodeInt(i int64) {
+	switch {
+	case i >= 0:
+		e.encodeUint(uint64(i))
+	case i >= -32:
+		e.write(byte(i))
+	case i >= math.MinInt8:
+		e.write(0xd0, byte(i))
+	case i >= math.MinInt16:
+		e.writeUint16(0xd1, uint16(i))
+	case i >= math.MinInt32:
+		e.writeUint32(0xd2, uint32(i))
+	default:
+		e.writeUint64(0xd3, uint64(i))
+	}
+}
+
+func (e *msgpackEncoder) encodeUint(u uint64) {
+	switch {
+	case u <= 0x7f:
+		e.write(byte(u))
+	case u <= math.MaxUint8:
+		e.write(0xcc, byte(u))
+	case u <= math.MaxUint16:
+		e.writeUint16(0xcd, uint16(u))
+	case u <= math.MaxUint32:
+		e.writeUint32(0xce, uint32(u))
+	default:
+		e.writeUint64(0xcf, u)
+	}
+}
+
+func (e *msgpackEncoder) encodeString(s string) {
+	switch l := len(s); {
+	case l <= 31:
+		e.write(0xa0 | byte(l))
+	case l <= math.MaxUint8:
+		e.write(0xd9, byte(l))
+	case l <= math.MaxUint16:
+		e.writeUint16(0xda, uint16(l))
+	default:
+		e.writeUint32(0xdb, uint32(l))
+	}
+	e.buf = append(e.buf, s...)
+}
+
+func (e *msgpackEncoder) encodeBytes(b []byte) {
+	switch l := len(b); {
+	case l <= math.MaxUint8:
+		e.write(0xc4, byte(l))
+	case l <= math.MaxUint16:
+		e.writeUint16(0xc5, uint16(l))
+	default:
+		e.writeUint32(0xc6, uint32(l))
+	}
+	e.buf = append(e.buf, b...)
+}
+
+func (e *msgpackEncoder) encodeArrayLen(l int) {
+	switch {
+	case l <= 15:
+		e.write(0x90 | byte(l))
+	case l <= math.MaxUint16:
+		e.writeUint16(0xdc, uint16(l))
+	default:
+		e.writeUint32(0xdd, uint32(l))
+	}
+}
+
+func (e *msgpackEncoder) encodeMapLen(l int) {
+	switch {
+	case l <= 15:
+		e.write(0x80 | byte(l))
+	case l <= math.MaxUint16:
+		e.writeUint16(0xde, uint16(l))
+	default:
+		e.writeUint32(0xdf, uint32(l))
+	}
+}
+
+var (
+	jsonNumberType = reflect.TypeOf(json.Number(""))
+	timeType       = reflect.TypeOf(time.Time{})
+)
+
+func (e *msgpackEncoder) encode(v reflect.Value) error {
+	if !v.IsValid() {
+		e.write(0xc0)
+		return nil
+	}
+
+	switch v.Type() {
+	case jsonNumberType:
+		n := json.Number(v.String())
+		if i, err := n.Int64(); err == nil {
+			e.encodeInt(i)
+		} else if u, err := strconv.ParseUint(string(n), 10, 64); err == nil {
+			e.encodeUint(u)
+		} else if f, err := n.Float64(); err == nil {
+			e.writeUint64(0xcb, math.Float64bits(f))
+		} else {
+			e.encodeString(string(n))
+		}
+		return nil
+	case timeType:
+		e.encodeString(v.Interface().(time.Time).Format(time.RFC3339Nano))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			e.write(0xc3)
+		} else {
+			e.write(0xc2)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		e.encodeInt(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		e.encodeUint(v.Uint())
+	case reflect.Float32:
+		e.writeUint32(0xca, math.Float32bits(float32(v.Float())))
+	case reflect.Float64:
+		e.writeUint64(0xcb, math.Float64bits(v.Float()))
+	case reflect.String:
+		e.encodeString(v.String())
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			e.write(0xc0)
+			return nil
+		}
+		return e.encode(v.Elem())
+	case reflect.Slice:
+		if v.IsNil() {
+			e.write(0xc0)
+			return nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			e.encodeBytes(v.Bytes())
+			return nil
+		}
+		fallthrough
+	case reflect.Array:
+		e.encodeArrayLen(v.Len())
+		for i := 0; i < v.Len(); i++ {
+			if err := e.encode(v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if v.IsNil() {
+			e.write(0xc0)
+			return nil
+		}
+		keys := v.MapKeys()
+		// sort keys so that equal maps always encode identically
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+		e.encodeMapLen(len(keys))
+		for _, k := range keys {
+			if err := e.encode(k); err != nil {
+				return err
+			}
+			if err := e.encode(v.MapIndex(k)); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		t := v.Type()
+		fields := make([]int, 0, t.NumField())
+		for i := 0; i < t.NumField(); i++ {
+			if t.Field(i).PkgPath == "" && fieldName(t.Field(i)) != "-" {
+				fields = append(fields, i)
+			}
+		}
+		e.encodeMapLen(len(fields))
+		for _, i := range fields {
+			e.encodeString(fieldName(t.Field(i)))
+			if err := e.encode(v.Field(i)); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("msgpack: unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// msgpackMaxDepth is how deeply arrays and maps may be nested, so a hostile payload cannot exhaust the stack
+const msgpackMaxDepth = 100
+
+var (
+	errMsgPackShort = errors.New("msgpack: unexpected end of data")
+	errMsgPackDepth = fmt.Errorf("msgpack: exceeded maximum nesting depth of %d", msgpackMaxDepth)
+)
+
+type msgpackDecoder struct {
+	buf   []byte
+	pos   int
+	depth int
+}
+
+// nest is called on entering an array or map, returning a func to call on leaving it
+func (d *msgpackDecoder) nest() (func(), error) {
+	if d.depth >= msgpackMaxDepth {
+		return nil, errMsgPackDepth
+	}
+	d.depth++
+	return func() { d.depth-- }, nil
+}
+
+func (d *msgpackDecoder) next(n int) ([]byte, error) {
+	if n < 0 || len(d.buf)-d.pos < n {
+		return nil, errMsgPackShort
+	}
+	b := d.buf[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+func (d *msgpackDecoder) uint(n int) (uint64, error) {
+	b, err := d.next(n)
+	if err != nil {
+		return 0, err
+	}
+	switch n {
+	case 1:
+		return uint64(b[0]), nil
+	case 2:
+		return uint64(binary.BigEndian.Uint16(b)), nil
+	case 4:
+		return uint64(binary.BigEndian.Uint32(b)), nil
+	default:
+		return binary.BigEndian.Uint64(b), nil
+	}
+}
+
+func (d *msgpackDecoder) decode() (interface{}, error) {
+	b, err := d.next(1)
+	if err != nil {
+		return nil, err
+	}
+	code := b[0]
+
+	switch {
+	case code <= 0x7f:
+		return int64(code), nil
+	case code >= 0xe0:
+		return int64(int8(code)), nil
+	case code&0xe0 == 0xa0:
+		return d.decodeString(int(code & 0x1f))
+	case code&0xf0 == 0x90:
+		return d.decodeArray(int(code & 0x0f))
+	case code&0xf0 == 0x80:
+		return d.decodeMap(int(code & 0x0f))
+	}
+
+	switch code {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		u, err := d.uint(1 << (code - 0xcc))
+		if err != nil {
+			return nil, err
+		}
+		if u <= math.MaxInt64 {
+			return int64(u), nil
+		}
+		return u, nil
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		n := 1 << (code - 0xd0)
+		u, err := d.uint(n)
+		if err != nil {
+			return nil, err
+		}
+		switch n {
+		case 1:
+			return int64(int8(u)), nil
+		case 2:
+			return int64(int16(u)), nil
+		case 4:
+			return int64(int32(u)), nil
+		default:
+			return int64(u), nil
+		}
+	case 0xca:
+		u, err := d.uint(4)
+		if err != nil {
+			return nil, err
+		}
+		return float64(math.Float32frombits(uint32(u))), nil
+	case 0xcb:
+		u, err := d.uint(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(u), nil
+	case 0xd9, 0xda, 0xdb:
+		l, err := d.uint(1 << (code - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeString(int(l))
+	case 0xc4, 0xc5, 0xc6:
+		l, err := d.uint(1 << (code - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+		b, err := d.next(int(l))
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte(nil), b...), nil
+	case 0xdc, 0xdd:
+		l, err := d.uint(2 << (code - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeArray(int(l))
+	case 0xde, 0xdf:
+		l, err := d.uint(2 << (code - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeMap(int(l))
+	}
+
+	return nil, fmt.Errorf("msgpack: unsupported type code 0x%x", code)
+}
+
+func (d *msgpackDecoder) decodeString(l int) (interface{}, error) {
+	b, err := d.next(l)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (d *msgpackDecoder) decodeArray(l int) (interface{}, error) {
+	// every element takes at least one byte, guard against allocating based on a corrupt length
+	if l > len(d.buf)-d.pos {
+		return nil, errMsgPackShort
+	}
+	leave, err := d.nest()
+	if err != nil {
+		return nil, err
+	}
+	defer leave()
+	out := make([]interface{}, l)
+	for i := range out {
+		v, err := d.decode()
+		if err != nil {
+			return nil, err
+		}
+		out[i] = v
+	}
+	return out, nil
+}
+
+func (d *msgpackDecoder) decodeMap(l int) (interface{}, error) {
+	if l > len(d.buf)-d.pos {
+		return nil, errMsgPackShort
+	}
+	leave, err := d.nest()
+	if err != nil {
+		return nil, err
+	}
+	defer leave()
+	out := make(map[string]interface{}, l)
+	for i := 0; i < l; i++ {
+		k, err := d.decode()
+		if err != nil {
+			return nil, err
+		}
+		v, err := d.decode()
+		if err != nil {
+			return nil, err
+		}
+		if s, ok := k.(string); ok {
+			out[s] = v
+		} else {
+			out[fmt.Sprint(k)] = v
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,119 @@
+package lruchal_test
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/dcarbone/lruchal"
+)
+
+type codecTestWidget struct {
+	Name  string            `json:"name"`
+	Count int64             `json:"count"`
+	Tags  []string          `json:"tags"`
+	Attrs map[string]uint16 `json:"attrs"`
+	Blob  []byte            `json:"blob"`
+	Ratio float64           `json:"ratio"`
+	Next  *codecTestWidget  `json:"next,omitempty"`
+}
+
+func TestCodecs(t *testing.T) {
+	widget := codecTestWidget{
+		Name:  "widget",
+		Count: 9007199254740993,
+		Tags:  []string{"a", "b"},
+		Attrs: map[string]uint16{"x": 1, "y": 65535},
+		Blob:  []byte{0, 1, 255},
+		Ratio: 0.25,
+		Next:  &codecTestWidget{Name: "next", Count: -129},
+	}
+
+	for _, codec := range []lruchal.Codec{new(lruchal.JSONCodec), new(lruchal.GobCodec), new(lruchal.MsgPackCodec)} {
+		t.Run(codec.ContentType(), func(t *testing.T) {
+			t.Run("Typed", func(t *testing.T) {
+				if _, ok := codec.(*lruchal.GobCodec); ok {
+					t.Skip("gob requires registered types")
+				}
+				b, err := codec.Marshal(widget)
+				if err != nil {
+					t.Logf("Unable to marshal: %s", err)
+					t.FailNow()
+				}
+				out := new(codecTestWidget)
+				if err := codec.Unmarshal(b, out); err != nil {
+					t.Logf("Unable to unmarshal: %s", err)
+					t.FailNow()
+				}
+				if !reflect.DeepEqual(widget, *out) {
+					t.Logf("Expected %#v, saw %#v", widget, *out)
+					t.FailNow()
+				}
+			})
+
+			t.Run("Item", func(t *testing.T) {
+				item := lruchal.Item{Key: "key1", Value: "value1", TTL: "5m", Tags: []string{"tag1", "tag2"}}
+				b, err := codec.Marshal(item)
+				if err != nil {
+					t.Logf("Unable to marshal: %s", err)
+					t.FailNow()
+				}
+				out := new(lruchal.Item)
+				if err := codec.Unmarshal(b, out); err != nil {
+					t.Logf("Unable to unmarshal: %s", err)
+					t.FailNow()
+				}
+				if !reflect.DeepEqual(*out, item) {
+					t.Logf("Expected %#v, saw %#v", item, *out)
+					t.FailNow()
+				}
+			})
+		})
+	}
+
+	t.Run("MsgPackPreservesIntegers", func(t *testing.T) {
+		codec := new(lruchal.MsgPackCodec)
+		b, err := codec.Marshal(map[string]interface{}{"big": int64(9007199254740993), "neg": -1, "max": uint64(1<<64 - 1)})
+		if err != nil {
+			t.Logf("Unable to marshal: %s", err)
+			t.FailNow()
+		}
+		var v interface{}
+		if err := codec.Unmarshal(b, &v); err != nil {
+			t.Logf("Unable to unmarshal: %s", err)
+			t.FailNow()
+		}
+		expected := map[string]interface{}{"big": int64(9007199254740993), "neg": int64(-1), "max": uint64(1<<64 - 1)}
+		if !reflect.DeepEqual(v, expected) {
+			t.Logf("Expected %#v, saw %#v", expected, v)
+			t.FailNow()
+		}
+	})
+
+	t.Run("MsgPackTruncated", func(t *testing.T) {
+		codec := new(lruchal.MsgPackCodec)
+		b, _ := codec.Marshal(widget)
+		var v interface{}
+		if err := codec.Unmarshal(b[:len(b)-1], &v); err == nil {
+			t.Log("Expected error decoding truncated data")
+			t.FailNow()
+		}
+	})
+
+	t.Run("MsgPackNested", func(t *testing.T) {
+		codec := new(lruchal.MsgPackCodec)
+		// a fixarray of one element, nested far deeper than any legitimate value
+		b := append(bytes.Repeat([]byte{0x91}, 1<<20), 0xc0)
+		var v interface{}
+		if err := codec.Unmarshal(b, &v); err == nil {
+			t.Log("Expected error decoding deeply nested data")
+			t.FailNow()
+		}
+
+		b = append(bytes.Repeat([]byte{0x81, 0xa1, 'k'}, 50), 0xc0)
+		if err := codec.Unmarshal(b, &v); err != nil {
+			t.Logf("Unable to decode moderately nested data: %s", err)
+			t.FailNow()
+		}
+	})
+}
@@ -0,0 +1,185 @@
+package lruchal
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+const DefaultCompressionThreshold = 1024
+
+// CompressionConfig enables compression of large values held by Server, and of large responses to clients that send
+// "Accept-Encoding: gzip".  Request bodies sent with "Content-Encoding: gzip" are always accepted.
+type CompressionConfig struct {
+	Threshold int // values and responses of at least this many bytes are compressed
+	Level     int // gzip compression level, defaults to gzip.DefaultCompression
+}
+
+// CompressionStats are cumulative counts of values the server has stored
+type CompressionStats struct {
+	Values          uint64 `json:"values"`           // number of values stored compressed
+	RawBytes        uint64 `json:"raw_bytes"`        // encoded size of those values before compression
+	CompressedBytes uint64 `json:"compressed_bytes"` // size of those values after compression
+	Skipped         uint64 `json:"skipped"`          // values over the threshold stored raw as they did not shrink
+}
+
+// compressedValue is held in the cache in place of a value that has been compressed.  Data is exported so that it
+// may be serialized by wrapping caches such as EncryptedCache.
+type compressedValue struct {
+	Data []byte
+}
+
+type compressor struct {
+	threshold int
+	level     int
+
+	values          uint64
+	rawBytes        uint64
+	compressedBytes uint64
+	skipped         uint64
+}
+
+func newCompressor(config *CompressionConfig) *compressor {
+	c := &compressor{
+		threshold: DefaultCompressionThreshold,
+		level:     gzip.DefaultCompression,
+	}
+	if config.Threshold > 0 {
+		c.threshold = config.Threshold
+	}
+	if config.Level != 0 {
+		c.level = config.Level
+	}
+	return c
+}
+
+func (c *compressor) Stats() CompressionStats {
+	return CompressionStats{
+		Values:          atomic.LoadUint64(&c.values),
+		RawBytes:        atomic.LoadUint64(&c.rawBytes),
+		CompressedBytes: atomic.LoadUint64(&c.compressedBytes),
+		Skipped:         atomic.LoadUint64(&c.skipped),
+	}
+}
+
+// compress returns a compressedValue in place of v if it's encoded size is over the threshold and compression
+// actually makes it smaller, otherwise v is returned as-is.
+func (c *compressor) compress(v interface{}) interface{} {
+	var raw []byte
+	if bv, ok := v.(*BinaryValue); ok && len(bv.Data) < c.threshold {
+		// binary values are at least as large encoded as their data, so this avoids encoding small ones
+		return v
+	}
+	raw, err := new(GobCodec).Marshal(v)
+	if err != nil || len(raw) < c.threshold {
+		return v
+	}
+	compressed, err := gzipBytes(raw, c.level)
+	if err != nil || len(compressed) >= len(raw) {
+		atomic.AddUint64(&c.skipped, 1)
+		return v
+	}
+	atomic.AddUint64(&c.values, 1)
+	atomic.AddUint64(&c.rawBytes, uint64(len(raw)))
+	atomic.AddUint64(&c.compressedBytes, uint64(len(compressed)))
+	return &compressedValue{Data: compressed}
+}
+
+// decompress reverses compress, returning v as-is if it was not compressed
+func (c *compressor) decompress(v interface{}) (interface{}, error) {
+	cv, ok := v.(*compressedValue)
+	if !ok {
+		return v, nil
+	}
+	raw, err := gunzipBytes(cv.Data, 0)
+	if err != nil {
+		return nil, err
+	}
+	var out interface{}
+	if err := new(GobCodec).Unmarshal(raw, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func gzipBytes(b []byte, level int) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	gw, err := gzip.NewWriterLevel(buf, level)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := gw.Write(b); err != nil {
+		return nil, err
+	}
+	if err := gw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// gunzipBytes decompresses b, returning ErrBodyTooLarge if it decompresses to more than limit bytes.  A limit <= 0
+// is unlimited.
+func gunzipBytes(b []byte, limit int64) ([]byte, error) {
+	gr, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	defer gr.Close()
+	if limit <= 0 {
+		return ioutil.ReadAll(gr)
+	}
+	out, err := ioutil.ReadAll(io.LimitReader(gr, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(out)) > limit {
+		return nil, fmt.Errorf("%w: decompressed body exceeds %d bytes", ErrBodyTooLarge, limit)
+	}
+	return out, nil
+}
+
+// readBody reads the request body, decompressing it according to it's Content-Encoding.  ErrBodyTooLarge is
+// returned if either the body or it's decompressed form is larger than limit bytes.
+func readBody(w http.ResponseWriter, r *http.Request, limit int64) ([]byte, error) {
+	b, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, limit))
+	if err != nil {
+		if int64(len(b)) >= limit {
+			return nil, fmt.Errorf("%w: body exceeds %d bytes", ErrBodyTooLarge, limit)
+		}
+		return nil, err
+	}
+	switch enc := strings.ToLower(r.Header.Get("Content-Encoding")); enc {
+	case "", "identity":
+		return b, nil
+	case "gzip":
+		return gunzipBytes(b, limit)
+	default:
+		return nil, fmt.Errorf("unsupported content encoding \"%s\"", enc)
+	}
+}
+
+// writeBodyError writes the response for an error returned by readBody
+func writeBodyError(w http.ResponseWriter, err error) {
+	if errors.Is(err, ErrBodyTooLarge) {
+		writeError(w, http.StatusRequestEntityTooLarge, ErrorCodeBodyTooLarge, fmt.Sprintf("Unable to read body: %s", err))
+		return
+	}
+	writeError(w, http.StatusUnprocessableEntity, ErrorCodeInvalidBody, fmt.Sprintf("Unable to read body: %s", err))
+}
+
+// acceptsGzip returns true if the request's Accept-Encoding header allows gzip
+func acceptsGzip(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		split := strings.SplitN(strings.TrimSpace(part), ";", 2)
+		if strings.EqualFold(split[0], "gzip") {
+			return len(split) == 1 || strings.Replace(split[1], " ", "", -1) != "q=0"
+		}
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+package lruchal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+var (
+	ErrNotFound   = errors.New("key not found")
+	ErrConflict   = errors.New("conflict")
+	ErrInvalidTTL = errors.New("invalid ttl")
+
+	ErrQuotaExceeded = errors.New("quota exceeded")
+
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+
+	ErrRateLimited = errors.New("rate limited")
+
+	ErrBodyTooLarge = errors.New("body too large")
+
+	ErrCircuitOpen      = errors.New("circuit breaker is open")
+	ErrNoAvailableNodes = errors.New("no available nodes")
+)
+
+// Error codes present in the body of all non-2xx responses from the server
+const (
+	ErrorCodeNotFound      = "not_found"
+	ErrorCodeConflict      = "conflict"
+	ErrorCodeInvalidTTL    = "invalid_ttl"
+	ErrorCodeInvalidBody   = "invalid_body"
+	ErrorCodeUnprocessable = "unprocessable"
+	ErrorCodeUnknownRoute  = "unknown_route"
+	ErrorCodeUnsupported   = "unsupported_media_type"
+	ErrorCodeQuota         = "quota_exceeded"
+	ErrorCodeUnauthorized  = "unauthorized"
+	ErrorCodeForbidden     = "forbidden"
+	ErrorCodeRateLimited   = "rate_limited"
+	ErrorCodeBodyTooLarge  = "body_too_large"
+	ErrorCodeStoreFailed   = "store_failed"
+)
+
+// ErrorResponse is the body of all non-2xx responses from the server
+type ErrorResponse struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ServerError is returned by Client when the server responds with an unexpected status code.  If the server provided
+// a recognized error code, the corresponding sentinel error (ErrNotFound, etc.) may be tested for with errors.Is.
+type ServerError struct {
+	StatusCode int
+	Code       string
+	Message    string
+	Body       []byte
+}
+
+func newServerError(statusCode int, body []byte) *ServerError {
+	se := &ServerError{
+		StatusCode: statusCode,
+		Body:       body,
+	}
+	er := new(ErrorResponse)
+	if err := json.Unmarshal(body, er); err == nil && er.Code != "" {
+		se.Code = er.Code
+		se.Message = er.Message
+	} else {
+		se.Message = string(body)
+	}
+	return se
+}
+
+func (e *ServerError) Error() string {
+	if e.Code != "" {
+		return fmt.Sprintf("%d %s: %s", e.StatusCode, e.Code, e.Message)
+	}
+	return fmt.Sprintf("%d: %s", e.StatusCode, e.Message)
+}
+
+// Unwrap returns the sentinel error matching this error's code, if there is one.  If the response did not contain an
+// error code, the status code is used instead.
+func (e *ServerError) Unwrap() error {
+	switch e.Code {
+	case ErrorCodeNotFound:
+		return ErrNotFound
+	case ErrorCodeConflict:
+		return ErrConflict
+	case ErrorCodeInvalidTTL:
+		return ErrInvalidTTL
+	case ErrorCodeQuota:
+		return ErrQuotaExceeded
+	case ErrorCodeUnauthorized:
+		return ErrUnauthorized
+	case ErrorCodeForbidden:
+		return ErrForbidden
+	case ErrorCodeRateLimited:
+		return ErrRateLimited
+	case ErrorCodeBodyTooLarge:
+		return ErrBodyTooLarge
+	case "":
+		switch e.StatusCode {
+		case http.StatusNotFound:
+			return ErrNotFound
+		case http.StatusConflict:
+			return ErrConflict
+		case http.StatusInsufficientStorage:
+			return ErrQuotaExceeded
+		case http.StatusUnauthorized:
+			return ErrUnauthorized
+		case http.StatusForbidden:
+			return ErrForbidden
+		case http.StatusTooManyRequests:
+			return ErrRateLimited
+		case http.StatusRequestEntityTooLarge:
+			return ErrBodyTooLarge
+		}
+	}
+	return nil
+}
+
+// writeError writes an ErrorResponse body to the response
+func writeError(w http.ResponseWriter, statusCode int, code, message string) {
+	b, _ := json.Marshal(ErrorResponse{Code: code, Message: message})
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(statusCode)
+	w.Write(b)
+}
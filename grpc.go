@@ -0,0 +1,272 @@
+package lruchal
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/dcarbone/lruchal/cachepb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const DefaultGRPCPort = 8184
+
+// ServeGRPC serves the grpc api described by cachepb/cache.proto on l until it is closed.  Serve calls this for
+// ServerConfig.GRPCPort, but it may be used to serve any other listener as well.
+func (srv *Server) ServeGRPC(l net.Listener) error {
+	gs := grpc.NewServer()
+	cachepb.RegisterCacheServer(gs, &grpcService{srv: srv})
+	return gs.Serve(l)
+}
+
+// grpcService implements cachepb.CacheServer on top of the server's namespaces
+type grpcService struct {
+	cachepb.UnimplementedCacheServer
+	srv *Server
+}
+
+func (gs *grpcService) namespace(name string) (*namespace, error) {
+	if name == "" {
+		name = DefaultNamespace
+	}
+	ns, ok := gs.srv.namespace(name)
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "namespace \"%s\" not found", name)
+	}
+	return ns, nil
+}
+
+// grpcValue encodes a cached value for a response.  Binary values are returned verbatim, anything else is encoded with
+// the codec negotiated from accept.
+func grpcValue(accept string, v interface{}) (*cachepb.Value, error) {
+	if bv, ok := v.(*BinaryValue); ok {
+		return &cachepb.Value{Data: bv.Data, ContentType: bv.ContentType}, nil
+	}
+	codec := negotiateCodec(accept)
+	b, err := codec.Marshal(v)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to marshal value: %s", err)
+	}
+	return &cachepb.Value{Data: b, ContentType: codec.ContentType()}, nil
+}
+
+// entryValue decodes the value of an entry for storage.  Values with the content type of a registered codec are
+// decoded unless the entry is raw, all others are stored as a BinaryValue.
+func entryValue(e *cachepb.Entry) (interface{}, error) {
+	if e.Value == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "entry \"%s\" has no value", e.Key)
+	}
+	if !e.Raw && e.Value.ContentType != "" {
+		if codec, ok := codecForContentType(e.Value.ContentType); ok {
+			var v interface{}
+			if err := codec.Unmarshal(e.Value.Data, &v); err != nil {
+				return nil, status.Errorf(codes.InvalidArgument, "unable to unmarshal value of \"%s\": %s", e.Key, err)
+			}
+			return v, nil
+		}
+	}
+	bv := &BinaryValue{
+		ContentType: e.Value.ContentType,
+		Data:        e.Value.Data,
+	}
+	if bv.ContentType == "" {
+		bv.ContentType = DefaultBinaryContentType
+	}
+	return bv, nil
+}
+
+// entryTTL returns the ttl of an entry, falling back to the namespace default if unset
+func entryTTL(ns *namespace, e *cachepb.Entry) (time.Duration, error) {
+	if e.Ttl == nil {
+		ttl, err := ns.ttl("")
+		if err != nil {
+			return 0, status.Errorf(codes.InvalidArgument, "entry \"%s\" has no ttl and namespace \"%s\" has no default", e.Key, ns.config.Name)
+		}
+		return ttl, nil
+	}
+	if err := e.Ttl.CheckValid(); err != nil {
+		return 0, status.Errorf(codes.InvalidArgument, "invalid ttl for \"%s\": %s", e.Key, err)
+	}
+	return e.Ttl.AsDuration(), nil
+}
+
+func (gs *grpcService) put(ns *namespace, e *cachepb.Entry) error {
+	if e == nil {
+		return status.Error(codes.InvalidArgument, "missing entry")
+	}
+	value, err := entryValue(e)
+	if err != nil {
+		return err
+	}
+	ttl, err := entryTTL(ns, e)
+	if err != nil {
+		return err
+	}
+	if err := gs.srv.store(ns, e.Key, value, ttl, e.Tags); err != nil {
+		if errors.Is(err, ErrQuotaExceeded) {
+			return status.Error(codes.ResourceExhausted, err.Error())
+		}
+		return status.Error(codes.Internal, err.Error())
+	}
+	return nil
+}
+
+func (gs *grpcService) Get(_ context.Context, req *cachepb.GetRequest) (*cachepb.GetResponse, error) {
+	ns, err := gs.namespace(req.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	value := gs.srv.load(ns, req.Key)
+	if value == nil {
+		return nil, status.Errorf(codes.NotFound, "key \"%s\" not found", req.Key)
+	}
+	v, err := grpcValue(req.Accept, value)
+	if err != nil {
+		return nil, err
+	}
+	return &cachepb.GetResponse{Value: v}, nil
+}
+
+func (gs *grpcService) Put(_ context.Context, req *cachepb.PutRequest) (*cachepb.PutResponse, error) {
+	ns, err := gs.namespace(req.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	if err := gs.put(ns, req.Entry); err != nil {
+		return nil, err
+	}
+	return &cachepb.PutResponse{}, nil
+}
+
+func (gs *grpcService) Delete(_ context.Context, req *cachepb.DeleteRequest) (*cachepb.DeleteResponse, error) {
+	ns, err := gs.namespace(req.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	value := gs.srv.unload(ns, req.Key)
+	if value == nil {
+		return nil, status.Errorf(codes.NotFound, "key \"%s\" not found", req.Key)
+	}
+	v, err := grpcValue(req.Accept, value)
+	if err != nil {
+		return nil, err
+	}
+	return &cachepb.DeleteResponse{Value: v}, nil
+}
+
+func (gs *grpcService) Has(_ context.Context, req *cachepb.HasRequest) (*cachepb.HasResponse, error) {
+	ns, err := gs.namespace(req.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	return &cachepb.HasResponse{Found: ns.cache.Has(req.Key)}, nil
+}
+
+func (gs *grpcService) GetMany(_ context.Context, req *cachepb.GetManyRequest) (*cachepb.GetManyResponse, error) {
+	ns, err := gs.namespace(req.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	resp := &cachepb.GetManyResponse{Values: make(map[string]*cachepb.Value, len(req.Keys))}
+	for _, key := range req.Keys {
+		value := gs.srv.load(ns, key)
+		if value == nil {
+			continue
+		}
+		if resp.Values[key], err = grpcValue(req.Accept, value); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+func (gs *grpcService) PutMany(_ context.Context, req *cachepb.PutManyRequest) (*cachepb.PutManyResponse, error) {
+	ns, err := gs.namespace(req.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range req.Entries {
+		if err := gs.put(ns, e); err != nil {
+			return nil, err
+		}
+	}
+	return &cachepb.PutManyResponse{}, nil
+}
+
+func (gs *grpcService) DeleteMany(_ context.Context, req *cachepb.DeleteManyRequest) (*cachepb.DeleteManyResponse, error) {
+	ns, err := gs.namespace(req.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	resp := new(cachepb.DeleteManyResponse)
+	for _, key := range req.Keys {
+		if ns.cache.Remove(key) != nil {
+			resp.Keys = append(resp.Keys, key)
+		}
+	}
+	return resp, nil
+}
+
+func (gs *grpcService) InvalidateTag(_ context.Context, req *cachepb.InvalidateTagRequest) (*cachepb.InvalidateTagResponse, error) {
+	ns, err := gs.namespace(req.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	removed := ns.cache.InvalidateTag(req.Tag)
+	resp := &cachepb.InvalidateTagResponse{Keys: make([]string, len(removed))}
+	for i, key := range removed {
+		resp.Keys[i] = remoteKey(key)
+	}
+	return resp, nil
+}
+
+func (gs *grpcService) Len(_ context.Context, req *cachepb.LenRequest) (*cachepb.LenResponse, error) {
+	ns, err := gs.namespace(req.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	return &cachepb.LenResponse{Len: int64(ns.cache.Len())}, nil
+}
+
+func (gs *grpcService) Expunge(_ context.Context, req *cachepb.ExpungeRequest) (*cachepb.ExpungeResponse, error) {
+	ns, err := gs.namespace(req.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	ns.cache.Expunge()
+	return &cachepb.ExpungeResponse{}, nil
+}
+
+func (gs *grpcService) Watch(req *cachepb.WatchRequest, stream cachepb.Cache_WatchServer) error {
+	ns, err := gs.namespace(req.Namespace)
+	if err != nil {
+		return err
+	}
+
+	ch, _, _ := ns.feed.subscribe(0, false)
+	if ch == nil {
+		return status.Errorf(codes.NotFound, "namespace \"%s\" has been deleted", req.Namespace)
+	}
+	defer ns.feed.unsubscribe(ch)
+
+	for {
+		select {
+		case ev, ok := <-ch:
+			if !ok {
+				return status.Error(codes.Aborted, "watcher fell too far behind or the namespace was deleted")
+			}
+			if !strings.HasPrefix(ev.Key, req.Prefix) {
+				continue
+			}
+			if err := stream.Send(&cachepb.WatchEvent{Key: ev.Key}); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return nil
+		}
+	}
+}
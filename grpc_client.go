@@ -0,0 +1,435 @@
+package lruchal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/dcarbone/lruchal/cachepb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+type GRPCClientConfig struct {
+	Address      string
+	ReadTimeout  time.Duration     // default timeout for calls that do not modify the cache
+	WriteTimeout time.Duration     // default timeout for calls that modify the cache
+	Codec        Codec             // encoding used for values on the wire, defaults to json
+	UseNumber    bool              // if true and using the default json codec, numbers are decoded as json.Number
+	Namespace    string            // optional, if set all calls operate on this namespace rather than the default
+	TLS          *ClientTLSConfig  // optional, if set the connection uses tls
+	DialOptions  []grpc.DialOption // optional, applied after the transport credentials, which are insecure unless TLS is set
+	Logger       Logger
+}
+
+func NewDefaultGRPCClientConfig() *GRPCClientConfig {
+	c := &GRPCClientConfig{
+		Address:      fmt.Sprintf("127.0.0.1:%d", DefaultGRPCPort),
+		ReadTimeout:  DefaultClientReadTimeout,
+		WriteTimeout: DefaultClientWriteTimeout,
+		Logger:       DefaultLogger("grpc-client"),
+	}
+	return c
+}
+
+// GRPCClient wraps the generated cachepb.CacheClient, offering the same calls as Client along with batched and
+// streaming calls.  Errors returned by the server may be tested for with errors.Is, as with Client.
+type GRPCClient struct {
+	log Logger
+
+	conn         *grpc.ClientConn
+	cache        cachepb.CacheClient
+	namespace    string
+	readTimeout  time.Duration
+	writeTimeout time.Duration
+	codec        Codec
+}
+
+func NewDefaultGRPCClient() (*GRPCClient, error) {
+	return newGRPCClient(NewDefaultGRPCClientConfig())
+}
+
+func NewGRPCClient(config *GRPCClientConfig) (*GRPCClient, error) {
+	return newGRPCClient(config)
+}
+
+func newGRPCClient(config *GRPCClientConfig) (*GRPCClient, error) {
+	def := NewDefaultGRPCClientConfig()
+	if config.Address != "" {
+		def.Address = config.Address
+	}
+	if config.ReadTimeout > 0 {
+		def.ReadTimeout = config.ReadTimeout
+	}
+	if config.WriteTimeout > 0 {
+		def.WriteTimeout = config.WriteTimeout
+	}
+	if config.Logger != nil {
+		def.Logger = config.Logger
+	}
+	if config.Codec != nil {
+		def.Codec = config.Codec
+	} else {
+		def.Codec = &JSONCodec{UseNumber: config.UseNumber}
+	}
+	creds := insecure.NewCredentials()
+	if config.TLS != nil {
+		tc, err := config.TLS.tlsConfig(def.Address)
+		if err != nil {
+			return nil, fmt.Errorf("unable to configure tls: %s", err)
+		}
+		creds = credentials.NewTLS(tc)
+	}
+	def.DialOptions = append([]grpc.DialOption{grpc.WithTransportCredentials(creds)}, config.DialOptions...)
+
+	conn, err := grpc.NewClient(def.Address, def.DialOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create grpc client: %s", err)
+	}
+
+	c := &GRPCClient{
+		log:          def.Logger,
+		conn:         conn,
+		cache:        cachepb.NewCacheClient(conn),
+		readTimeout:  def.ReadTimeout,
+		writeTimeout: def.WriteTimeout,
+		codec:        def.Codec,
+	}
+
+	if config.Namespace != DefaultNamespace {
+		c.namespace = config.Namespace
+	}
+
+	return c, nil
+}
+
+// Close will close the client's connection.  The client must not be used afterwards.
+func (c *GRPCClient) Close() error {
+	return c.conn.Close()
+}
+
+// withTimeout applies timeout to ctx, as Client does per attempt
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return ctx, func() {}
+}
+
+// grpcError converts a status returned by the server into an error wrapping the matching sentinel error, if any
+func grpcError(err error) error {
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	switch st.Code() {
+	case codes.NotFound:
+		return fmt.Errorf("%w: %s", ErrNotFound, st.Message())
+	case codes.ResourceExhausted:
+		return fmt.Errorf("%w: %s", ErrQuotaExceeded, st.Message())
+	case codes.AlreadyExists:
+		return fmt.Errorf("%w: %s", ErrConflict, st.Message())
+	}
+	return err
+}
+
+// decodeValue unmarshals a value from the server into v.  Values are decoded with the codec matching their content
+// type, which is this client's unless the value was stored verbatim.
+func (c *GRPCClient) decodeValue(value *cachepb.Value, v interface{}) error {
+	codec := c.codec
+	if ct := value.GetContentType(); ct != codec.ContentType() {
+		var ok bool
+		if codec, ok = codecForContentType(ct); !ok {
+			return fmt.Errorf("unable to decode value with content type \"%s\", use GetBytes instead", ct)
+		}
+	}
+	if err := codec.Unmarshal(value.GetData(), v); err != nil {
+		return fmt.Errorf("unable to unmarshal data: %s", err)
+	}
+	return nil
+}
+
+// entry converts an Item to it's grpc equivalent, encoding it's value with this client's codec
+func (c *GRPCClient) entry(item Item) (*cachepb.Entry, error) {
+	e := &cachepb.Entry{Key: item.Key, Tags: item.Tags}
+
+	// an empty ttl is left to the server, which will apply the namespace's default ttl if it has one
+	if item.TTL != "" {
+		ttl, err := time.ParseDuration(item.TTL)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidTTL, err)
+		}
+		e.Ttl = durationpb.New(ttl)
+	}
+
+	b, err := c.codec.Marshal(item.Value)
+	if err != nil {
+		return nil, fmt.Errorf("unable to serialize: %s", err)
+	}
+	e.Value = &cachepb.Value{Data: b, ContentType: c.codec.ContentType()}
+
+	return e, nil
+}
+
+func (c *GRPCClient) Get(key string) (interface{}, error) {
+	return c.GetContext(context.Background(), key)
+}
+
+func (c *GRPCClient) GetContext(ctx context.Context, key string) (interface{}, error) {
+	var data interface{}
+	if err := c.GetIntoContext(ctx, key, &data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// GetInto will decode the value of key directly into v, which must be a pointer
+func (c *GRPCClient) GetInto(key string, v interface{}) error {
+	return c.GetIntoContext(context.Background(), key, v)
+}
+
+func (c *GRPCClient) GetIntoContext(ctx context.Context, key string, v interface{}) error {
+	ctx, cancel := withTimeout(ctx, c.readTimeout)
+	defer cancel()
+
+	resp, err := c.cache.Get(ctx, &cachepb.GetRequest{Namespace: c.namespace, Key: key, Accept: c.codec.ContentType()})
+	if err != nil {
+		return grpcError(err)
+	}
+	return c.decodeValue(resp.Value, v)
+}
+
+func (c *GRPCClient) Put(item Item) error {
+	return c.PutContext(context.Background(), item)
+}
+
+func (c *GRPCClient) PutContext(ctx context.Context, item Item) error {
+	e, err := c.entry(item)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := withTimeout(ctx, c.writeTimeout)
+	defer cancel()
+
+	if _, err := c.cache.Put(ctx, &cachepb.PutRequest{Namespace: c.namespace, Entry: e}); err != nil {
+		return grpcError(err)
+	}
+	return nil
+}
+
+// Has will return true if the server has the specified key
+func (c *GRPCClient) Has(key string) (bool, error) {
+	return c.HasContext(context.Background(), key)
+}
+
+func (c *GRPCClient) HasContext(ctx context.Context, key string) (bool, error) {
+	ctx, cancel := withTimeout(ctx, c.readTimeout)
+	defer cancel()
+
+	resp, err := c.cache.Has(ctx, &cachepb.HasRequest{Namespace: c.namespace, Key: key})
+	if err != nil {
+		return false, grpcError(err)
+	}
+	return resp.Found, nil
+}
+
+// Remove will attempt to remove a key from the server, returning it's value.  Returns ErrNotFound if key not found.
+func (c *GRPCClient) Remove(key string) (interface{}, error) {
+	return c.RemoveContext(context.Background(), key)
+}
+
+func (c *GRPCClient) RemoveContext(ctx context.Context, key string) (interface{}, error) {
+	ctx, cancel := withTimeout(ctx, c.writeTimeout)
+	defer cancel()
+
+	resp, err := c.cache.Delete(ctx, &cachepb.DeleteRequest{Namespace: c.namespace, Key: key, Accept: c.codec.ContentType()})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	var data interface{}
+	if err := c.decodeValue(resp.Value, &data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// GetMany will return the values of every key found on the server.  Keys not found are absent from the result.
+func (c *GRPCClient) GetMany(keys []string) (map[string]interface{}, error) {
+	return c.GetManyContext(context.Background(), keys)
+}
+
+func (c *GRPCClient) GetManyContext(ctx context.Context, keys []string) (map[string]interface{}, error) {
+	ctx, cancel := withTimeout(ctx, c.readTimeout)
+	defer cancel()
+
+	resp, err := c.cache.GetMany(ctx, &cachepb.GetManyRequest{Namespace: c.namespace, Keys: keys, Accept: c.codec.ContentType()})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	out := make(map[string]interface{}, len(resp.Values))
+	for key, value := range resp.Values {
+		var data interface{}
+		if err := c.decodeValue(value, &data); err != nil {
+			return nil, err
+		}
+		out[key] = data
+	}
+	return out, nil
+}
+
+// PutMany will store every item in a single call.  If an item is refused, the items before it remain stored.
+func (c *GRPCClient) PutMany(items []Item) error {
+	return c.PutManyContext(context.Background(), items)
+}
+
+func (c *GRPCClient) PutManyContext(ctx context.Context, items []Item) error {
+	req := &cachepb.PutManyRequest{Namespace: c.namespace, Entries: make([]*cachepb.Entry, len(items))}
+	for i, item := range items {
+		e, err := c.entry(item)
+		if err != nil {
+			return err
+		}
+		req.Entries[i] = e
+	}
+
+	ctx, cancel := withTimeout(ctx, c.writeTimeout)
+	defer cancel()
+
+	if _, err := c.cache.PutMany(ctx, req); err != nil {
+		return grpcError(err)
+	}
+	return nil
+}
+
+// RemoveMany will remove every key found on the server, returning the keys that were removed
+func (c *GRPCClient) RemoveMany(keys []string) ([]string, error) {
+	return c.RemoveManyContext(context.Background(), keys)
+}
+
+func (c *GRPCClient) RemoveManyContext(ctx context.Context, keys []string) ([]string, error) {
+	ctx, cancel := withTimeout(ctx, c.writeTimeout)
+	defer cancel()
+
+	resp, err := c.cache.DeleteMany(ctx, &cachepb.DeleteManyRequest{Namespace: c.namespace, Keys: keys})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return resp.Keys, nil
+}
+
+// InvalidateTag will remove every key put with tag, returning the removed keys
+func (c *GRPCClient) InvalidateTag(tag string) ([]string, error) {
+	return c.InvalidateTagContext(context.Background(), tag)
+}
+
+func (c *GRPCClient) InvalidateTagContext(ctx context.Context, tag string) ([]string, error) {
+	ctx, cancel := withTimeout(ctx, c.writeTimeout)
+	defer cancel()
+
+	resp, err := c.cache.InvalidateTag(ctx, &cachepb.InvalidateTagRequest{Namespace: c.namespace, Tag: tag})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return resp.Keys, nil
+}
+
+// Len will return the number of keys currently held by the server, including expired keys not yet expunged
+func (c *GRPCClient) Len() (int, error) {
+	return c.LenContext(context.Background())
+}
+
+func (c *GRPCClient) LenContext(ctx context.Context) (int, error) {
+	ctx, cancel := withTimeout(ctx, c.readTimeout)
+	defer cancel()
+
+	resp, err := c.cache.Len(ctx, &cachepb.LenRequest{Namespace: c.namespace})
+	if err != nil {
+		return 0, grpcError(err)
+	}
+	return int(resp.Len), nil
+}
+
+// Expunge will ask the server to remove all expired keys
+func (c *GRPCClient) Expunge() error {
+	return c.ExpungeContext(context.Background())
+}
+
+func (c *GRPCClient) ExpungeContext(ctx context.Context) error {
+	ctx, cancel := withTimeout(ctx, c.writeTimeout)
+	defer cancel()
+
+	if _, err := c.cache.Expunge(ctx, &cachepb.ExpungeRequest{Namespace: c.namespace}); err != nil {
+		return grpcError(err)
+	}
+	return nil
+}
+
+// PutBytes will store data verbatim under key, to be returned with the provided content type by GetBytes.  A ttl of 0
+// uses the default ttl of the client's namespace.
+func (c *GRPCClient) PutBytes(key string, data []byte, contentType string, ttl time.Duration) error {
+	return c.PutBytesContext(context.Background(), key, data, contentType, ttl)
+}
+
+func (c *GRPCClient) PutBytesContext(ctx context.Context, key string, data []byte, contentType string, ttl time.Duration) error {
+	if ttl < 0 {
+		return fmt.Errorf("%w: ttl cannot be negative", ErrInvalidTTL)
+	}
+	if contentType == "" {
+		contentType = DefaultBinaryContentType
+	}
+
+	e := &cachepb.Entry{Key: key, Value: &cachepb.Value{Data: data, ContentType: contentType}, Raw: true}
+	if ttl > 0 {
+		e.Ttl = durationpb.New(ttl)
+	}
+
+	ctx, cancel := withTimeout(ctx, c.writeTimeout)
+	defer cancel()
+
+	if _, err := c.cache.Put(ctx, &cachepb.PutRequest{Namespace: c.namespace, Entry: e}); err != nil {
+		return grpcError(err)
+	}
+	return nil
+}
+
+// GetBytes will return the value of key along with it's content type.  Values stored with Put are returned as json.
+func (c *GRPCClient) GetBytes(key string) ([]byte, string, error) {
+	return c.GetBytesContext(context.Background(), key)
+}
+
+func (c *GRPCClient) GetBytesContext(ctx context.Context, key string) ([]byte, string, error) {
+	ctx, cancel := withTimeout(ctx, c.readTimeout)
+	defer cancel()
+
+	resp, err := c.cache.Get(ctx, &cachepb.GetRequest{Namespace: c.namespace, Key: key, Accept: ContentTypeJSON})
+	if err != nil {
+		return nil, "", grpcError(err)
+	}
+	return resp.Value.GetData(), resp.Value.GetContentType(), nil
+}
+
+// Watch calls fn with the key of every change within the client's namespace beginning with prefix, until ctx
+// is done or the stream fails.  Returns nil if ctx is done.
+func (c *GRPCClient) Watch(ctx context.Context, prefix string, fn func(key string)) error {
+	stream, err := c.cache.Watch(ctx, &cachepb.WatchRequest{Namespace: c.namespace, Prefix: prefix})
+	if err != nil {
+		return grpcError(err)
+	}
+	for {
+		event, err := stream.Recv()
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, io.EOF) {
+				return nil
+			}
+			return grpcError(err)
+		}
+		fn(event.Key)
+	}
+}
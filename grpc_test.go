@@ -0,0 +1,222 @@
+package lruchal_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/dcarbone/lruchal"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newTestGRPCClient serves a new server's grpc api over an in memory listener, returning a client connected to it
+func newTestGRPCClient(t *testing.T, namespaces []lruchal.NamespaceConfig, config *lruchal.GRPCClientConfig) *lruchal.GRPCClient {
+	srv, err := lruchal.NewServer(&lruchal.ServerConfig{
+		Namespaces: namespaces,
+		Logger:     log.New(ioutil.Discard, "", 0),
+	})
+	if err != nil {
+		t.Logf("Unable to create server: %s", err)
+		t.FailNow()
+	}
+
+	lis := bufconn.Listen(1 << 20)
+	go srv.ServeGRPC(lis)
+
+	if config == nil {
+		config = new(lruchal.GRPCClientConfig)
+	}
+	config.Address = "passthrough:///bufconn"
+	config.DialOptions = append(config.DialOptions, grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+		return lis.DialContext(ctx)
+	}))
+	client, err := lruchal.NewGRPCClient(config)
+	if err != nil {
+		t.Logf("Unable to create grpc client: %s", err)
+		t.FailNow()
+	}
+
+	t.Cleanup(func() {
+		client.Close()
+		lis.Close()
+	})
+	return client
+}
+
+func TestGRPC(t *testing.T) {
+	t.Run("RoundTrip", func(t *testing.T) {
+		client := newTestGRPCClient(t, nil, &lruchal.GRPCClientConfig{UseNumber: true})
+
+		if err := client.Put(lruchal.Item{Key: "key1", Value: map[string]interface{}{"a": 1}, TTL: "1m", Tags: []string{"tag1"}}); err != nil {
+			t.Logf("Unable to put: %s", err)
+			t.FailNow()
+		}
+
+		var v struct{ A int }
+		if err := client.GetInto("key1", &v); err != nil || v.A != 1 {
+			t.Logf("Expected {A: 1}, saw %+v (err=%v)", v, err)
+			t.FailNow()
+		}
+		if ok, err := client.Has("key1"); err != nil || !ok {
+			t.Logf("Expected Has to return true, saw %t (err=%v)", ok, err)
+			t.FailNow()
+		}
+		if l, err := client.Len(); err != nil || l != 1 {
+			t.Logf("Expected Len of 1, saw %d (err=%v)", l, err)
+			t.FailNow()
+		}
+		if keys, err := client.InvalidateTag("tag1"); err != nil || len(keys) != 1 || keys[0] != "key1" {
+			t.Logf("Expected [key1], saw %v (err=%v)", keys, err)
+			t.FailNow()
+		}
+		if _, err := client.Get("key1"); !errors.Is(err, lruchal.ErrNotFound) {
+			t.Logf("Expected ErrNotFound, saw %v", err)
+			t.FailNow()
+		}
+		if _, err := client.Remove("key1"); !errors.Is(err, lruchal.ErrNotFound) {
+			t.Logf("Expected ErrNotFound, saw %v", err)
+			t.FailNow()
+		}
+		if err := client.Expunge(); err != nil {
+			t.Logf("Unable to expunge: %s", err)
+			t.FailNow()
+		}
+	})
+
+	t.Run("Batch", func(t *testing.T) {
+		client := newTestGRPCClient(t, nil, nil)
+
+		err := client.PutMany([]lruchal.Item{
+			{Key: "key1", Value: "value1", TTL: "1m"},
+			{Key: "key2", Value: "value2", TTL: "1m"},
+		})
+		if err != nil {
+			t.Logf("Unable to put many: %s", err)
+			t.FailNow()
+		}
+
+		values, err := client.GetMany([]string{"key1", "key2", "missing"})
+		if err != nil || len(values) != 2 || values["key1"] != "value1" || values["key2"] != "value2" {
+			t.Logf("Expected key1 and key2, saw %v (err=%v)", values, err)
+			t.FailNow()
+		}
+
+		keys, err := client.RemoveMany([]string{"key1", "key2", "missing"})
+		sort.Strings(keys)
+		if err != nil || len(keys) != 2 || keys[0] != "key1" || keys[1] != "key2" {
+			t.Logf("Expected [key1 key2] removed, saw %v (err=%v)", keys, err)
+			t.FailNow()
+		}
+	})
+
+	t.Run("Bytes", func(t *testing.T) {
+		client := newTestGRPCClient(t, nil, nil)
+
+		// raw values are stored verbatim even with the content type of a codec
+		if err := client.PutBytes("key1", []byte(`{"a": 1}`), lruchal.ContentTypeJSON, time.Minute); err != nil {
+			t.Logf("Unable to put bytes: %s", err)
+			t.FailNow()
+		}
+		if b, ct, err := client.GetBytes("key1"); err != nil || string(b) != `{"a": 1}` || ct != lruchal.ContentTypeJSON {
+			t.Logf("Expected verbatim json, saw %q %q (err=%v)", b, ct, err)
+			t.FailNow()
+		}
+	})
+
+	t.Run("Namespace", func(t *testing.T) {
+		client := newTestGRPCClient(t, []lruchal.NamespaceConfig{{Name: "limited", DefaultTTL: time.Minute, MaxEntries: 1}}, &lruchal.GRPCClientConfig{Namespace: "limited"})
+
+		// the namespace's default ttl applies
+		if err := client.Put(lruchal.Item{Key: "key1", Value: 1}); err != nil {
+			t.Logf("Unable to put: %s", err)
+			t.FailNow()
+		}
+		if err := client.Put(lruchal.Item{Key: "key2", Value: 2}); !errors.Is(err, lruchal.ErrQuotaExceeded) {
+			t.Logf("Expected ErrQuotaExceeded, saw %v", err)
+			t.FailNow()
+		}
+	})
+
+	t.Run("Watch", func(t *testing.T) {
+		client := newTestGRPCClient(t, nil, nil)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		keys := make(chan string, 100)
+		errs := make(chan error, 1)
+		go func() {
+			errs <- client.Watch(ctx, "watched-", func(key string) { keys <- key })
+		}()
+
+		// puts are repeated until the watch has been established
+		for {
+			client.Put(lruchal.Item{Key: "ignored", Value: 1, TTL: "1m"})
+			client.Put(lruchal.Item{Key: "watched-1", Value: 1, TTL: "1m"})
+			select {
+			case key := <-keys:
+				if key != "watched-1" {
+					t.Logf("Expected watched-1, saw %s", key)
+					t.FailNow()
+				}
+				cancel()
+				if err := <-errs; err != nil {
+					t.Logf("Expected nil error once cancelled, saw %s", err)
+					t.FailNow()
+				}
+				return
+			case <-time.After(10 * time.Millisecond):
+			case <-ctx.Done():
+				t.Log("Timed out waiting for watched key")
+				t.FailNow()
+			}
+		}
+	})
+
+	t.Run("WatchExpiry", func(t *testing.T) {
+		client := newTestGRPCClient(t, nil, nil)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		keys := make(chan string, 100)
+		go client.Watch(ctx, "expiring-", func(key string) { keys <- key })
+
+		// wait for the watch to be established before putting the key which expires
+		for established := false; !established; {
+			client.Put(lruchal.Item{Key: "expiring-sync", Value: 1, TTL: "1m"})
+			select {
+			case <-keys:
+				established = true
+			case <-time.After(10 * time.Millisecond):
+			case <-ctx.Done():
+				t.Log("Timed out establishing watch")
+				t.FailNow()
+			}
+		}
+		for len(keys) > 0 {
+			<-keys
+		}
+
+		// the watch shares the change feed of /watch, so sees the expiry as well as the put
+		client.Put(lruchal.Item{Key: "expiring-1", Value: 1, TTL: "50ms"})
+		for i := 0; i < 2; i++ {
+			select {
+			case key := <-keys:
+				if key != "expiring-1" {
+					t.Logf("Expected expiring-1, saw %s", key)
+					t.FailNow()
+				}
+			case <-ctx.Done():
+				t.Logf("Timed out waiting for change %d", i+1)
+				t.FailNow()
+			}
+		}
+	})
+}
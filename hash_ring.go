@@ -0,0 +1,76 @@
+package lruchal
+
+import (
+	"hash/fnv"
+	"sort"
+	"strconv"
+)
+
+// hashRing implements consistent hashing with virtual nodes.  It is not safe for concurrent use.
+type hashRing struct {
+	vnodes int
+	points []uint64
+	owners map[uint64]string
+}
+
+func newHashRing(vnodes int) *hashRing {
+	hr := &hashRing{
+		vnodes: vnodes,
+		owners: make(map[uint64]string),
+	}
+	return hr
+}
+
+func ringHash(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	// fnv alone clusters similar inputs, so finish with a mixer to spread vnodes evenly around the ring
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
+
+func (hr *hashRing) add(node string) {
+	for i := 0; i < hr.vnodes; i++ {
+		p := ringHash(node + "#" + strconv.Itoa(i))
+		if _, ok := hr.owners[p]; ok {
+			continue
+		}
+		hr.owners[p] = node
+		hr.points = append(hr.points, p)
+	}
+	sort.Slice(hr.points, func(i, j int) bool { return hr.points[i] < hr.points[j] })
+}
+
+func (hr *hashRing) remove(node string) {
+	points := hr.points[:0]
+	for _, p := range hr.points {
+		if hr.owners[p] == node {
+			delete(hr.owners, p)
+		} else {
+			points = append(points, p)
+		}
+	}
+	hr.points = points
+}
+
+// get returns the node owning key, walking clockwise past any node for which skip returns true.  Returns "" if the
+// ring is empty or every node is skipped.
+func (hr *hashRing) get(key string, skip func(node string) bool) string {
+	if len(hr.points) == 0 {
+		return ""
+	}
+	h := ringHash(key)
+	start := sort.Search(len(hr.points), func(i int) bool { return hr.points[i] >= h })
+	for i := 0; i < len(hr.points); i++ {
+		node := hr.owners[hr.points[(start+i)%len(hr.points)]]
+		if skip == nil || !skip(node) {
+			return node
+		}
+	}
+	return ""
+}
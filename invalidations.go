@@ -0,0 +1,57 @@
+package lruchal
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// invalidation is a single line of the /invalidations stream
+type invalidation struct {
+	Key string `json:"key"`
+}
+
+// invalidations streams the key of every change within the namespace as newline delimited json until the client goes
+// away.  It is a view of the same change feed as /watch, without the change type or resumption, kept as the simplest
+// stream for near caches which only need to know which keys to drop.
+func (srv *Server) invalidations(w http.ResponseWriter, r *http.Request, ns *namespace) {
+	if r.URL.Path != "/invalidations" {
+		writeError(w, http.StatusNotFound, ErrorCodeUnknownRoute, http.StatusText(http.StatusNotFound))
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, ErrorCodeUnprocessable, "Streaming not supported")
+		return
+	}
+
+	srv.log.Printf("handling: GET %s", r.RequestURI)
+
+	ch, _, _ := ns.feed.subscribe(0, false)
+	if ch == nil {
+		writeError(w, http.StatusNotFound, ErrorCodeNotFound, "Namespace has been deleted")
+		return
+	}
+	defer ns.feed.unsubscribe(ch)
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	enc := json.NewEncoder(w)
+	for {
+		select {
+		case ev, ok := <-ch:
+			if !ok {
+				// the namespace was deleted or the client fell too far behind to know what it missed
+				return
+			}
+			if err := enc.Encode(invalidation{Key: ev.Key}); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
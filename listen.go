@@ -0,0 +1,106 @@
+package lruchal
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/netutil"
+)
+
+// DefaultUnixSocketMode is the file mode given to unix sockets the server listens on, allowing the server's user and
+// group to connect
+const DefaultUnixSocketMode os.FileMode = 0660
+
+// listen opens a listener on address, limited to limit concurrent connections.  Addresses take one of the forms:
+//
+//	tcp://host:port      listen on a tcp address, host may be empty to listen on all interfaces
+//	unix:///path/to/sock listen on a unix socket created with mode
+//	systemd://           use the first socket passed by systemd socket activation
+//	systemd://name       use the socket passed by systemd socket activation with the FileDescriptorName name
+func listen(address string, limit int, mode os.FileMode) (net.Listener, error) {
+	i := strings.Index(address, "://")
+	if i < 0 {
+		return nil, fmt.Errorf("address \"%s\" has no scheme, expected one of tcp://, unix:// or systemd://", address)
+	}
+	scheme, rest := address[:i], address[i+3:]
+
+	var listener net.Listener
+	var err error
+	switch scheme {
+	case "tcp":
+		listener, err = net.Listen("tcp", rest)
+	case "unix":
+		listener, err = listenUnix(rest, mode)
+	case "systemd":
+		listener, err = listenSystemd(rest)
+	default:
+		return nil, fmt.Errorf("unsupported address scheme \"%s\"", scheme)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to listen: %s", err)
+	}
+
+	return netutil.LimitListener(listener, limit), nil
+}
+
+// listenUnix listens on a unix socket at path, replacing any stale socket left behind by a previous process
+func listenUnix(path string, mode os.FileMode) (net.Listener, error) {
+	if path == "" {
+		return nil, fmt.Errorf("unix address has no path")
+	}
+	if fi, err := os.Lstat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		// a socket nobody is listening on is left over from an unclean shutdown
+		if conn, err := net.Dial("unix", path); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("socket \"%s\" is in use", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("unable to remove stale socket \"%s\": %s", path, err)
+		}
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("unable to set mode of socket \"%s\": %s", path, err)
+	}
+	return listener, nil
+}
+
+// listenSystemdFDStart is the first file descriptor passed by systemd socket activation, see sd_listen_fds(3)
+const listenSystemdFDStart = 3
+
+// listenSystemd returns the socket passed by systemd with the given name, or the first socket if name is empty
+func listenSystemd(name string) (net.Listener, error) {
+	if pid, err := strconv.Atoi(os.Getenv("LISTEN_PID")); err != nil || pid != os.Getpid() {
+		return nil, fmt.Errorf("no sockets were passed by systemd")
+	}
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n < 1 {
+		return nil, fmt.Errorf("no sockets were passed by systemd")
+	}
+
+	offset := 0
+	if name != "" {
+		offset = -1
+		for j, fdName := range strings.Split(os.Getenv("LISTEN_FDNAMES"), ":") {
+			if fdName == name && j < n {
+				offset = j
+				break
+			}
+		}
+		if offset < 0 {
+			return nil, fmt.Errorf("no socket named \"%s\" was passed by systemd", name)
+		}
+	}
+
+	f := os.NewFile(uintptr(listenSystemdFDStart+offset), name)
+	defer f.Close()
+	return net.FileListener(f)
+}
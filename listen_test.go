@@ -0,0 +1,124 @@
+package lruchal_test
+
+import (
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dcarbone/lruchal"
+)
+
+func TestListen(t *testing.T) {
+	roundTrip := func(t *testing.T, address string) {
+		client, err := lruchal.NewClient(&lruchal.ClientConfig{Address: address})
+		if err != nil {
+			t.Logf("Unable to create client: %s", err)
+			t.FailNow()
+		}
+		defer client.Close()
+
+		if err := client.Put(lruchal.Item{Key: "key1", Value: "value1", TTL: "1m"}); err != nil {
+			t.Logf("Unable to put: %s", err)
+			t.FailNow()
+		}
+		if v, err := client.Get("key1"); err != nil || v != "value1" {
+			t.Logf("Expected value1, saw %v (err=%v)", v, err)
+			t.FailNow()
+		}
+	}
+
+	t.Run("Unix", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "lruchal")
+		if err != nil {
+			t.Logf("Unable to create temp dir: %s", err)
+			t.FailNow()
+		}
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "lruchal.sock")
+
+		srv := newTestServer(t, &lruchal.ServerConfig{Address: "unix://" + path, UnixSocketMode: 0600})
+		if err := srv.Listen(); err != nil {
+			t.Logf("Unable to listen: %s", err)
+			t.FailNow()
+		}
+		go srv.Serve()
+
+		if fi, err := os.Stat(path); err != nil || fi.Mode().Perm() != 0600 {
+			t.Logf("Expected socket with mode 0600, saw %v (err=%v)", fi, err)
+			t.FailNow()
+		}
+		if err := newTestServer(t, &lruchal.ServerConfig{Address: "unix://" + path}).Listen(); err == nil || !strings.Contains(err.Error(), "in use") {
+			t.Logf("Expected socket in use error, saw %v", err)
+			t.FailNow()
+		}
+
+		roundTrip(t, "unix://"+path)
+		srv.Close()
+	})
+
+	t.Run("Listener", func(t *testing.T) {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Logf("Unable to listen: %s", err)
+			t.FailNow()
+		}
+		srv := newTestServer(t, &lruchal.ServerConfig{Listener: l})
+		go srv.Serve()
+		defer srv.Close()
+
+		roundTrip(t, "tcp://"+l.Addr().String())
+	})
+
+	t.Run("RandomPort", func(t *testing.T) {
+		srv := newTestServer(t, &lruchal.ServerConfig{Port: lruchal.RandomPort, RESPPort: lruchal.RandomPort, BindAddress: "127.0.0.1"})
+		if srv.Addr() != nil {
+			t.Logf("Expected no address before listening, saw %s", srv.Addr())
+			t.FailNow()
+		}
+		if err := srv.Listen(); err != nil {
+			t.Logf("Unable to listen: %s", err)
+			t.FailNow()
+		}
+		defer srv.Close()
+		go srv.Serve()
+
+		addr, ok := srv.Addr().(*net.TCPAddr)
+		if !ok || !addr.IP.IsLoopback() || addr.Port == 0 {
+			t.Logf("Expected a loopback address with a chosen port, saw %v", srv.Addr())
+			t.FailNow()
+		}
+		if resp := srv.RESPAddr(); resp == nil || resp.String() == addr.String() {
+			t.Logf("Expected a separate resp address, saw %v", resp)
+			t.FailNow()
+		}
+		if srv.BinaryAddr() != nil {
+			t.Logf("Expected no binary address, saw %s", srv.BinaryAddr())
+			t.FailNow()
+		}
+
+		roundTrip(t, addr.String())
+	})
+
+	t.Run("InvalidAddress", func(t *testing.T) {
+		for _, address := range []string{"127.0.0.1:8182", "udp://127.0.0.1:8182", "systemd://"} {
+			if err := newTestServer(t, &lruchal.ServerConfig{Address: address}).Listen(); err == nil {
+				t.Logf("Expected error listening on \"%s\"", address)
+				t.FailNow()
+			}
+		}
+	})
+}
+
+func newTestServer(t *testing.T, config *lruchal.ServerConfig) *lruchal.Server {
+	config.Logger = log.New(ioutil.Discard, "", 0)
+	srv, err := lruchal.NewServer(config)
+	if err != nil {
+		t.Logf("Unable to create server: %s", err)
+		t.FailNow()
+	}
+	return srv
+}
@@ -0,0 +1,533 @@
+package lruchal
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	memcachedMaxKeyLen   = 250
+	memcachedMaxItemSize = 1 << 20
+	memcachedMaxLineLen  = 64 << 10
+
+	// exptimes greater than this are unix timestamps rather than a number of seconds
+	memcachedMaxRelativeExptime = 60 * 60 * 24 * 30
+
+	memcachedVersion = "1.6.0"
+)
+
+// memcachedStats are the counters reported by the memcached stats command
+type memcachedStats struct {
+	currConnections  int64
+	totalConnections int64
+	cmdGet           int64
+	cmdSet           int64
+	cmdTouch         int64
+	getHits          int64
+	getMisses        int64
+
+	started time.Time
+}
+
+// memcachedFlushes are the delayed flush_all commands pending on each namespace.  As with memcached there is at most
+// one per namespace, any later flush_all replacing it.
+type memcachedFlushes struct {
+	mu     *sync.Mutex
+	timers map[*namespace]*time.Timer
+}
+
+// memcachedConn is a single client connection to the memcached listener
+type memcachedConn struct {
+	srv     *Server
+	ns      *namespace
+	stats   *memcachedStats
+	flushes *memcachedFlushes
+	r       *bufio.Reader
+	w       *bufio.Writer
+}
+
+// serveMemcached accepts memcached text protocol connections on l until it is closed
+func (srv *Server) serveMemcached(l net.Listener) error {
+	stats := &memcachedStats{started: time.Now()}
+	flushes := &memcachedFlushes{mu: new(sync.Mutex), timers: make(map[*namespace]*time.Timer)}
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go srv.handleMemcached(conn, stats, flushes)
+	}
+}
+
+func (srv *Server) handleMemcached(conn net.Conn, stats *memcachedStats, flushes *memcachedFlushes) {
+	defer conn.Close()
+
+	atomic.AddInt64(&stats.currConnections, 1)
+	atomic.AddInt64(&stats.totalConnections, 1)
+	defer atomic.AddInt64(&stats.currConnections, -1)
+
+	ns, _ := srv.namespace(DefaultNamespace)
+	mc := &memcachedConn{
+		srv:     srv,
+		ns:      ns,
+		stats:   stats,
+		flushes: flushes,
+		r:       bufio.NewReaderSize(conn, memcachedMaxLineLen),
+		w:       bufio.NewWriter(conn),
+	}
+
+	for {
+		line, err := mc.r.ReadSlice('\n')
+		if err != nil {
+			if err == bufio.ErrBufferFull {
+				mc.w.WriteString("CLIENT_ERROR line too long\r\n")
+				mc.w.Flush()
+			} else if err != io.EOF {
+				srv.log.Printf("memcached: unable to read from %s: %s", conn.RemoteAddr(), err)
+			}
+			return
+		}
+
+		fields := strings.Fields(string(line))
+		if len(fields) == 0 {
+			continue
+		}
+
+		quit, err := mc.exec(fields)
+		if err != nil {
+			// the connection can no longer be read from reliably, as with a malformed data block
+			mc.w.Flush()
+			return
+		}
+
+		// responses to pipelined commands are buffered until there are no more commands waiting to be read
+		if quit || mc.r.Buffered() == 0 {
+			if err := mc.w.Flush(); err != nil {
+				return
+			}
+		}
+		if quit {
+			return
+		}
+	}
+}
+
+// exec runs a single command, returning true if the connection should be closed
+func (mc *memcachedConn) exec(fields []string) (bool, error) {
+	cmd, args := fields[0], fields[1:]
+
+	switch cmd {
+	case "get", "gets":
+		mc.get(cmd, args)
+	case "set", "add", "replace", "cas":
+		return false, mc.storage(cmd, args)
+	case "delete":
+		mc.delete(args)
+	case "incr", "decr":
+		mc.incr(cmd, args)
+	case "touch":
+		mc.touch(args)
+	case "flush_all":
+		mc.flushAll(args)
+	case "stats":
+		mc.writeStats(args)
+	case "version":
+		mc.reply(false, "VERSION "+memcachedVersion)
+	case "verbosity":
+		mc.reply(noreply(args), "OK")
+	case "quit":
+		return true, nil
+	default:
+		mc.reply(false, "ERROR")
+	}
+	return false, nil
+}
+
+// reply writes line, unless the client asked for no reply
+func (mc *memcachedConn) reply(noreply bool, line string) {
+	if !noreply {
+		mc.w.WriteString(line + "\r\n")
+	}
+}
+
+// storeError replies to an error returned by store, which is reported as out of memory only if the namespace is full
+func (mc *memcachedConn) storeError(quiet bool, err error) {
+	if errors.Is(err, ErrQuotaExceeded) {
+		mc.reply(quiet, "SERVER_ERROR out of memory storing object")
+	} else {
+		mc.reply(quiet, "SERVER_ERROR unable to store object")
+	}
+}
+
+func noreply(args []string) bool {
+	return len(args) > 0 && args[len(args)-1] == "noreply"
+}
+
+func validMemcachedKey(key string) bool {
+	if len(key) == 0 || len(key) > memcachedMaxKeyLen {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] <= ' ' || key[i] == 0x7f {
+			return false
+		}
+	}
+	return true
+}
+
+// memcachedTTL converts an exptime into a ttl.  0 never expires, negative values have already expired and values
+// over 30 days are unix timestamps.
+func (mc *memcachedConn) memcachedTTL(exptime int64) time.Duration {
+	switch {
+	case exptime == 0:
+		return mc.ns.unboundedTTL()
+	case exptime < 0:
+		return 0
+	default:
+		return memcachedDuration(exptime)
+	}
+}
+
+// memcachedDuration converts a positive number of seconds, or a unix time if over 30 days, into a duration from now.
+// Durations too long to represent are capped at noExpiry.
+func memcachedDuration(n int64) time.Duration {
+	if n > memcachedMaxRelativeExptime {
+		n -= time.Now().Unix()
+	}
+	if n > int64(noExpiry/time.Second) {
+		return noExpiry
+	}
+	return time.Duration(n) * time.Second
+}
+
+func (mc *memcachedConn) get(cmd string, keys []string) {
+	if len(keys) == 0 {
+		mc.reply(false, "ERROR")
+		return
+	}
+	for _, key := range keys {
+		atomic.AddInt64(&mc.stats.cmdGet, 1)
+
+		var value interface{}
+		var cas uint64
+		if cmd == "gets" {
+			// hold the lock so the cas unique returned matches the value
+			unlock := mc.srv.lock(mc.ns)
+			value = mc.srv.load(mc.ns, key)
+			cas, _ = mc.ns.mem.CAS(key)
+			unlock()
+		} else {
+			value = mc.srv.load(mc.ns, key)
+		}
+
+		if value == nil {
+			atomic.AddInt64(&mc.stats.getMisses, 1)
+			continue
+		}
+		atomic.AddInt64(&mc.stats.getHits, 1)
+
+		var flags uint32
+		if bv, ok := value.(*BinaryValue); ok {
+			flags = bv.Flags
+		}
+		data := valueBytes(value)
+		if cmd == "gets" {
+			fmt.Fprintf(mc.w, "VALUE %s %d %d %d\r\n", key, flags, len(data), cas)
+		} else {
+			fmt.Fprintf(mc.w, "VALUE %s %d %d\r\n", key, flags, len(data))
+		}
+		mc.w.Write(data)
+		mc.w.WriteString("\r\n")
+	}
+	mc.w.WriteString("END\r\n")
+}
+
+// storage handles set, add, replace and cas.  An error is returned if the data block could not be read.
+func (mc *memcachedConn) storage(cmd string, args []string) error {
+	quiet := noreply(args)
+	want := 4
+	if cmd == "cas" {
+		want = 5
+	}
+	if quiet {
+		args = args[:len(args)-1]
+	}
+	if len(args) != want {
+		mc.reply(false, "ERROR")
+		return nil
+	}
+
+	key := args[0]
+	flags, ferr := strconv.ParseUint(args[1], 10, 32)
+	exptime, eerr := strconv.ParseInt(args[2], 10, 64)
+	size, serr := strconv.Atoi(args[3])
+	var casUnique uint64
+	var cerr error
+	if cmd == "cas" {
+		casUnique, cerr = strconv.ParseUint(args[4], 10, 64)
+	}
+	if ferr != nil || eerr != nil || serr != nil || cerr != nil || size < 0 {
+		// the data block size is unknown, so the connection cannot continue
+		mc.reply(false, "CLIENT_ERROR bad command line format")
+		return errors.New("bad storage command line")
+	}
+
+	if size > memcachedMaxItemSize {
+		if _, err := io.CopyN(io.Discard, mc.r, int64(size)+2); err != nil {
+			return err
+		}
+		mc.reply(quiet, "SERVER_ERROR object too large for cache")
+		return nil
+	}
+
+	data := make([]byte, size+2)
+	if _, err := io.ReadFull(mc.r, data); err != nil {
+		return err
+	}
+	if !bytes.HasSuffix(data, []byte("\r\n")) {
+		mc.reply(false, "CLIENT_ERROR bad data chunk")
+		return errors.New("bad data chunk")
+	}
+	data = data[:size]
+
+	if !validMemcachedKey(key) {
+		mc.reply(quiet, "CLIENT_ERROR bad command line format")
+		return nil
+	}
+
+	atomic.AddInt64(&mc.stats.cmdSet, 1)
+	ttl := mc.memcachedTTL(exptime)
+
+	unlock := mc.srv.lock(mc.ns)
+	current, exists := mc.ns.mem.CAS(key)
+	switch {
+	case cmd == "add" && exists, cmd == "replace" && !exists:
+		unlock()
+		mc.reply(quiet, "NOT_STORED")
+		return nil
+	case cmd == "cas" && !exists:
+		unlock()
+		mc.reply(quiet, "NOT_FOUND")
+		return nil
+	case cmd == "cas" && current != casUnique:
+		unlock()
+		mc.reply(quiet, "EXISTS")
+		return nil
+	}
+
+	var err error
+	if ttl <= 0 {
+		// already expired, so storing it is the same as removing any current value
+		mc.srv.unload(mc.ns, key)
+	} else {
+		value := &BinaryValue{ContentType: DefaultBinaryContentType, Data: data, Flags: uint32(flags)}
+		err = mc.srv.storeLocked(mc.ns, key, value, ttl, nil)
+	}
+	unlock()
+
+	if err != nil {
+		mc.storeError(quiet, err)
+		return nil
+	}
+	mc.reply(quiet, "STORED")
+	return nil
+}
+
+func (mc *memcachedConn) delete(args []string) {
+	quiet := noreply(args)
+	if quiet {
+		args = args[:len(args)-1]
+	}
+	// older clients may send a hold time of 0, which is ignored
+	if len(args) == 0 || len(args) > 2 || (len(args) == 2 && args[1] != "0") {
+		mc.reply(false, "CLIENT_ERROR bad command line format.  Usage: delete <key> [noreply]")
+		return
+	}
+
+	if mc.srv.unload(mc.ns, args[0]) == nil {
+		mc.reply(quiet, "NOT_FOUND")
+		return
+	}
+	mc.reply(quiet, "DELETED")
+}
+
+func (mc *memcachedConn) incr(cmd string, args []string) {
+	quiet := noreply(args)
+	if quiet {
+		args = args[:len(args)-1]
+	}
+	if len(args) != 2 {
+		mc.reply(false, "ERROR")
+		return
+	}
+	key := args[0]
+	delta, err := strconv.ParseUint(args[1], 10, 64)
+	if err != nil {
+		mc.reply(false, "CLIENT_ERROR invalid numeric delta argument")
+		return
+	}
+
+	unlock := mc.srv.lock(mc.ns)
+	defer unlock()
+
+	value := mc.srv.load(mc.ns, key)
+	if value == nil {
+		mc.reply(quiet, "NOT_FOUND")
+		return
+	}
+	n, err := strconv.ParseUint(strings.TrimSpace(string(valueBytes(value))), 10, 64)
+	if err != nil {
+		mc.reply(false, "CLIENT_ERROR cannot increment or decrement non-numeric value")
+		return
+	}
+
+	// as with memcached, incr wraps at 64 bits and decr stops at 0
+	if cmd == "incr" {
+		n += delta
+	} else if delta > n {
+		n = 0
+	} else {
+		n -= delta
+	}
+
+	ttl, ok := mc.ns.mem.TTL(key)
+	if !ok {
+		mc.reply(quiet, "NOT_FOUND")
+		return
+	}
+	out := &BinaryValue{ContentType: DefaultBinaryContentType, Data: []byte(strconv.FormatUint(n, 10))}
+	if bv, ok := value.(*BinaryValue); ok {
+		out.ContentType = bv.ContentType
+		out.Flags = bv.Flags
+	}
+	if err := mc.srv.storeLocked(mc.ns, key, out, ttl, nil); err != nil {
+		mc.storeError(quiet, err)
+		return
+	}
+	mc.reply(quiet, string(out.Data))
+}
+
+func (mc *memcachedConn) touch(args []string) {
+	quiet := noreply(args)
+	if quiet {
+		args = args[:len(args)-1]
+	}
+	if len(args) != 2 {
+		mc.reply(false, "ERROR")
+		return
+	}
+	exptime, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		mc.reply(false, "CLIENT_ERROR invalid exptime argument")
+		return
+	}
+
+	atomic.AddInt64(&mc.stats.cmdTouch, 1)
+	key := args[0]
+	touched := false
+	if ttl := mc.memcachedTTL(exptime); ttl <= 0 {
+		touched = mc.srv.unload(mc.ns, key) != nil
+	} else {
+		touched = mc.ns.mem.Expire(key, ttl)
+	}
+
+	if touched {
+		mc.reply(quiet, "TOUCHED")
+	} else {
+		mc.reply(quiet, "NOT_FOUND")
+	}
+}
+
+// flushAll removes every key from the namespace, either now or after a delay.  A delay over 30 days is a unix time,
+// as with exptimes.
+func (mc *memcachedConn) flushAll(args []string) {
+	quiet := noreply(args)
+	if quiet {
+		args = args[:len(args)-1]
+	}
+	var delay time.Duration
+	if len(args) > 0 {
+		n, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil || n < 0 || len(args) > 1 {
+			mc.reply(false, "CLIENT_ERROR bad command line format")
+			return
+		}
+		delay = memcachedDuration(n)
+	}
+
+	mc.flushes.schedule(mc.srv, mc.ns, delay)
+	mc.reply(quiet, "OK")
+}
+
+// schedule purges ns once delay has passed, replacing any flush already pending on it.  When it fires every key then
+// held was stored before the deadline, so as with memcached keys stored after the command but before the deadline
+// are flushed too, while those stored after the deadline are kept.
+func (f *memcachedFlushes) schedule(srv *Server, ns *namespace, delay time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if pending, ok := f.timers[ns]; ok {
+		pending.Stop()
+		delete(f.timers, ns)
+	}
+	if delay <= 0 {
+		srv.purge(ns)
+		return
+	}
+
+	var t *time.Timer
+	t = time.AfterFunc(delay, func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		// a flush replaced after it's timer fired, but before it acquired the lock, must not purge
+		if f.timers[ns] != t {
+			return
+		}
+		delete(f.timers, ns)
+		srv.purge(ns)
+	})
+	f.timers[ns] = t
+}
+
+func (mc *memcachedConn) writeStats(args []string) {
+	// only general purpose stats are provided
+	if len(args) > 0 {
+		mc.reply(false, "END")
+		return
+	}
+
+	now := time.Now()
+	stats := []struct {
+		name  string
+		value interface{}
+	}{
+		{"pid", os.Getpid()},
+		{"uptime", int64(now.Sub(mc.stats.started) / time.Second)},
+		{"time", now.Unix()},
+		{"version", memcachedVersion},
+		{"curr_connections", atomic.LoadInt64(&mc.stats.currConnections)},
+		{"total_connections", atomic.LoadInt64(&mc.stats.totalConnections)},
+		{"cmd_get", atomic.LoadInt64(&mc.stats.cmdGet)},
+		{"cmd_set", atomic.LoadInt64(&mc.stats.cmdSet)},
+		{"cmd_touch", atomic.LoadInt64(&mc.stats.cmdTouch)},
+		{"get_hits", atomic.LoadInt64(&mc.stats.getHits)},
+		{"get_misses", atomic.LoadInt64(&mc.stats.getMisses)},
+		{"curr_items", len(mc.ns.mem.Keys())},
+		{"bytes", mc.ns.mem.Bytes()},
+		{"limit_maxbytes", mc.ns.config.MaxBytes},
+	}
+	for _, stat := range stats {
+		fmt.Fprintf(mc.w, "STAT %s %v\r\n", stat.name, stat.value)
+	}
+	mc.w.WriteString("END\r\n")
+}
@@ -0,0 +1,254 @@
+package lruchal_test
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dcarbone/lruchal"
+)
+
+// memcachedTestConn is a raw connection to the memcached listener, so that the bytes on the wire are exactly those
+// written
+type memcachedTestConn struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func startMemcachedTestServer(t *testing.T) *lruchal.Server {
+	srv := newTestServer(t, &lruchal.ServerConfig{
+		Port:          lruchal.RandomPort,
+		MemcachedPort: lruchal.RandomPort,
+		BindAddress:   "127.0.0.1",
+	})
+	if err := srv.Listen(); err != nil {
+		t.Logf("Unable to listen: %s", err)
+		t.FailNow()
+	}
+	go srv.Serve()
+	t.Cleanup(func() { srv.Close() })
+	return srv
+}
+
+func dialMemcached(t *testing.T, srv *lruchal.Server) *memcachedTestConn {
+	conn, err := net.Dial("tcp", srv.MemcachedAddr().String())
+	if err != nil {
+		t.Logf("Unable to dial: %s", err)
+		t.FailNow()
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	return &memcachedTestConn{t: t, conn: conn, r: bufio.NewReader(conn)}
+}
+
+func (c *memcachedTestConn) write(s string) {
+	if _, err := io.WriteString(c.conn, s); err != nil {
+		c.t.Logf("Unable to write: %s", err)
+		c.t.FailNow()
+	}
+}
+
+func (c *memcachedTestConn) readLine() string {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		c.t.Logf("Unable to read reply: %s", err)
+		c.t.FailNow()
+	}
+	if !strings.HasSuffix(line, "\r\n") {
+		c.t.Logf("Expected reply %q to be terminated by CRLF", line)
+		c.t.FailNow()
+	}
+	return strings.TrimSuffix(line, "\r\n")
+}
+
+// expect writes input and checks the reply lines which follow
+func (c *memcachedTestConn) expect(input string, expected ...string) {
+	c.write(input)
+	for _, e := range expected {
+		if line := c.readLine(); line != e {
+			c.t.Logf("Expected %q in reply to %q, saw %q", e, input, line)
+			c.t.FailNow()
+		}
+	}
+}
+
+// expectClosed checks the server has closed the connection
+func (c *memcachedTestConn) expectClosed() {
+	if line, err := c.r.ReadString('\n'); err != io.EOF {
+		c.t.Logf("Expected the connection to be closed, saw %q (err=%v)", line, err)
+		c.t.FailNow()
+	}
+}
+
+// gets returns the cas unique of key
+func (c *memcachedTestConn) gets(key string) uint64 {
+	c.write("gets " + key + "\r\n")
+	fields := strings.Fields(c.readLine())
+	if len(fields) != 5 || fields[0] != "VALUE" || fields[1] != key {
+		c.t.Logf("Expected a value for %s, saw %q", key, fields)
+		c.t.FailNow()
+	}
+	cas, err := strconv.ParseUint(fields[4], 10, 64)
+	if err != nil {
+		c.t.Logf("Invalid cas unique %q", fields[4])
+		c.t.FailNow()
+	}
+	c.readLine()
+	if line := c.readLine(); line != "END" {
+		c.t.Logf("Expected END, saw %q", line)
+		c.t.FailNow()
+	}
+	return cas
+}
+
+func TestMemcached(t *testing.T) {
+	srv := startMemcachedTestServer(t)
+
+	t.Run("Commands", func(t *testing.T) {
+		c := dialMemcached(t, srv)
+
+		c.expect("version\r\n", "VERSION 1.6.0")
+		c.expect("set mc-key1 42 60 6\r\nvalue1\r\n", "STORED")
+		c.expect("get mc-key1\r\n", "VALUE mc-key1 42 6", "value1", "END")
+		c.expect("get mc-missing\r\n", "END")
+		c.expect("set mc-key2 0 0 6\r\nvalue2\r\n", "STORED")
+		c.expect("get mc-key1 mc-missing mc-key2\r\n", "VALUE mc-key1 42 6", "value1", "VALUE mc-key2 0 6", "value2", "END")
+		c.expect("add mc-key1 0 0 1\r\nx\r\n", "NOT_STORED")
+		c.expect("replace mc-missing 0 0 1\r\nx\r\n", "NOT_STORED")
+		c.expect("replace mc-key1 0 0 8\r\nreplaced\r\n", "STORED")
+		c.expect("get mc-key1\r\n", "VALUE mc-key1 0 8", "replaced", "END")
+
+		c.expect("set mc-counter 0 0 2\r\n10\r\n", "STORED")
+		c.expect("incr mc-counter 5\r\n", "15")
+		c.expect("decr mc-counter 20\r\n", "0")
+		c.expect("incr mc-missing 1\r\n", "NOT_FOUND")
+
+		c.expect("touch mc-key2 1\r\n", "TOUCHED")
+		c.expect("touch mc-missing 1\r\n", "NOT_FOUND")
+		c.expect("delete mc-key1\r\n", "DELETED")
+		c.expect("delete mc-key1\r\n", "NOT_FOUND")
+		// an exptime in the past stores nothing
+		c.expect("set mc-key1 0 -1 1\r\nx\r\n", "STORED")
+		c.expect("get mc-key1\r\n", "END")
+
+		c.expect("flush_all\r\n", "OK")
+		c.expect("get mc-key2 mc-counter\r\n", "END")
+		c.write("quit\r\n")
+		c.expectClosed()
+	})
+
+	t.Run("CAS", func(t *testing.T) {
+		c := dialMemcached(t, srv)
+
+		c.expect("set mc-cas 0 0 2\r\nv1\r\n", "STORED")
+		cas := c.gets("mc-cas")
+		c.expect(fmt.Sprintf("cas mc-cas 0 0 2 %d\r\nv2\r\n", cas), "STORED")
+		// the cas unique changes with the value, so the old one no longer matches
+		if next := c.gets("mc-cas"); next == cas {
+			t.Logf("Expected the cas unique to change from %d", cas)
+			t.FailNow()
+		}
+		c.expect(fmt.Sprintf("cas mc-cas 0 0 2 %d\r\nv3\r\n", cas), "EXISTS")
+		c.expect("get mc-cas\r\n", "VALUE mc-cas 0 2", "v2", "END")
+		c.expect(fmt.Sprintf("cas mc-missing 0 0 2 %d\r\nv1\r\n", cas), "NOT_FOUND")
+		c.expect("delete mc-cas\r\n", "DELETED")
+	})
+
+	t.Run("NoReply", func(t *testing.T) {
+		c := dialMemcached(t, srv)
+
+		// commands sent with noreply have no response, so the next reply read is that of the get
+		c.write("set mc-quiet 0 0 5 noreply\r\n12345\r\n")
+		c.write("add mc-quiet 0 0 1 noreply\r\nx\r\n")
+		c.write("incr mc-quiet 1 noreply\r\n")
+		c.write("touch mc-quiet 60 noreply\r\n")
+		c.expect("get mc-quiet\r\n", "VALUE mc-quiet 0 5", "12346", "END")
+		c.write("delete mc-quiet noreply\r\n")
+		c.write("delete mc-quiet noreply\r\n")
+		c.expect("get mc-quiet\r\n", "END")
+	})
+
+	t.Run("Pipelining", func(t *testing.T) {
+		c := dialMemcached(t, srv)
+
+		var b strings.Builder
+		var expected []string
+		for i := 0; i < 100; i++ {
+			value := strconv.Itoa(i)
+			fmt.Fprintf(&b, "set mc-pipe%d 0 0 %d\r\n%s\r\nget mc-pipe%d\r\n", i, len(value), value, i)
+			expected = append(expected, "STORED", fmt.Sprintf("VALUE mc-pipe%d 0 %d", i, len(value)), value, "END")
+		}
+		b.WriteString("flush_all\r\n")
+		c.expect(b.String(), append(expected, "OK")...)
+	})
+
+	t.Run("ErrorReplies", func(t *testing.T) {
+		c := dialMemcached(t, srv)
+
+		// errors which leave the data block, if any, readable are replied to without closing the connection
+		c.expect("bogus\r\n", "ERROR")
+		c.expect("get\r\n", "ERROR")
+		c.expect("set mc-key 0 0\r\n", "ERROR")
+		c.expect("delete\r\n", "CLIENT_ERROR bad command line format.  Usage: delete <key> [noreply]")
+		c.expect("incr mc-key one\r\n", "CLIENT_ERROR invalid numeric delta argument")
+		c.expect("touch mc-key soon\r\n", "CLIENT_ERROR invalid exptime argument")
+		c.expect("set "+strings.Repeat("k", 251)+" 0 0 1\r\nx\r\n", "CLIENT_ERROR bad command line format")
+		c.expect("set mc-key 0 0 5\r\nabcde\r\nincr mc-key 1\r\n", "STORED", "CLIENT_ERROR cannot increment or decrement non-numeric value")
+		c.expect(fmt.Sprintf("set mc-large 0 0 %d\r\n%s\r\n", 1<<20+1, strings.Repeat("v", 1<<20+1)), "SERVER_ERROR object too large for cache")
+		c.expect("get mc-large\r\n", "END")
+		c.expect("delete mc-key\r\n", "DELETED")
+	})
+
+	t.Run("DelayedFlush", func(t *testing.T) {
+		c := dialMemcached(t, srv)
+
+		c.expect("set mc-flush1 0 0 1\r\nx\r\n", "STORED")
+		c.expect("flush_all 1\r\n", "OK")
+		c.expect("set mc-flush2 0 0 1\r\nx\r\n", "STORED")
+		c.expect("get mc-flush1\r\n", "VALUE mc-flush1 0 1", "x", "END")
+		time.Sleep(1200 * time.Millisecond)
+		// keys stored after the command but before the deadline are flushed too
+		c.expect("get mc-flush1 mc-flush2\r\n", "END")
+
+		// a later flush_all replaces the pending one, so a delay far in the future means nothing is flushed yet
+		c.expect("set mc-flush1 0 0 1\r\nx\r\n", "STORED")
+		c.expect("flush_all 1\r\n", "OK")
+		c.expect("flush_all 9223372036854775807\r\n", "OK")
+		time.Sleep(1200 * time.Millisecond)
+		c.expect("get mc-flush1\r\n", "VALUE mc-flush1 0 1", "x", "END")
+		c.expect("flush_all\r\n", "OK")
+		c.expect("get mc-flush1\r\n", "END")
+
+		// exptimes too far in the future to represent are capped rather than wrapping into the past
+		c.expect("set mc-flush1 0 9223372036854775807 1\r\nx\r\n", "STORED")
+		c.expect("get mc-flush1\r\n", "VALUE mc-flush1 0 1", "x", "END")
+
+		c.expect("flush_all -1\r\n", "CLIENT_ERROR bad command line format")
+		c.expect("flush_all 99999999999999999999\r\n", "CLIENT_ERROR bad command line format")
+	})
+
+	t.Run("BadInput", func(t *testing.T) {
+		// the connection is closed once it can no longer be read reliably
+		for name, test := range map[string]struct {
+			input, reply string
+		}{
+			"BadLength":   {"set mc-key 0 0 x\r\n", "CLIENT_ERROR bad command line format"},
+			"BadFlags":    {"set mc-key x 0 1\r\n", "CLIENT_ERROR bad command line format"},
+			"BadCAS":      {"cas mc-key 0 0 1 x\r\n", "CLIENT_ERROR bad command line format"},
+			"BadChunk":    {"set mc-key 0 0 3\r\nabcd\n", "CLIENT_ERROR bad data chunk"},
+			"LineTooLong": {strings.Repeat("k", 64<<10), "CLIENT_ERROR line too long"},
+		} {
+			t.Run(name, func(t *testing.T) {
+				c := dialMemcached(t, srv)
+				c.expect(test.input, test.reply)
+				c.expectClosed()
+			})
+		}
+	})
+}
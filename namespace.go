@@ -0,0 +1,309 @@
+package lruchal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultNamespace is used by requests that do not specify a namespace
+const DefaultNamespace = "default"
+
+// NamespaceConfig describes a named cache within Server.  Each namespace has it's own keys, size and eviction policy.
+type NamespaceConfig struct {
+	Name           string
+	CacheSize      int            // maximum number of records allowable in the namespace, defaults to the server's
+	DefaultTTL     time.Duration  // ttl used when a put does not specify one.  If 0, a ttl is required
+	EvictionPolicy EvictionPolicy // defaults to EvictionLRU
+	MaxEntries     int            // optional, if > 0 puts of new keys are refused once the namespace holds this many
+	MaxBytes       int64          // optional, if > 0 puts are refused once the namespace's values would exceed this size
+}
+
+// NamespaceInfo describes a namespace in admin api and /stats responses
+type NamespaceInfo struct {
+	Name           string         `json:"name"`
+	CacheSize      int            `json:"cache_size,omitempty"`
+	DefaultTTL     string         `json:"default_ttl,omitempty"`
+	EvictionPolicy EvictionPolicy `json:"eviction_policy,omitempty"`
+	MaxEntries     int            `json:"max_entries,omitempty"`
+	MaxBytes       int64          `json:"max_bytes,omitempty"`
+	Len            int            `json:"len"`
+	Bytes          int64          `json:"bytes"`
+}
+
+type namespace struct {
+	mu     *sync.Mutex // serializes quota checks with puts
+	config NamespaceConfig
+	mem    *MemoryCache
+	cache  TaggedCache     // mem, possibly wrapped with encryption
+	enc    *EncryptedCache // cache, if encryption is enabled
+	feed   *changeFeed     // changes made to mem, streamed by /watch
+}
+
+func (ns *namespace) info() NamespaceInfo {
+	ni := NamespaceInfo{
+		Name:           ns.config.Name,
+		CacheSize:      ns.config.CacheSize,
+		EvictionPolicy: ns.config.EvictionPolicy,
+		MaxEntries:     ns.config.MaxEntries,
+		MaxBytes:       ns.config.MaxBytes,
+		Len:            ns.mem.Len(),
+		Bytes:          ns.mem.Bytes(),
+	}
+	if ns.config.DefaultTTL > 0 {
+		ni.DefaultTTL = ns.config.DefaultTTL.String()
+	}
+	return ni
+}
+
+// ttl parses the ttl of a put, falling back to the namespace default if empty
+func (ns *namespace) ttl(ttl string) (time.Duration, error) {
+	if ttl == "" && ns.config.DefaultTTL > 0 {
+		return ns.config.DefaultTTL, nil
+	}
+	return time.ParseDuration(ttl)
+}
+
+// noExpiry is the ttl given to keys set without an expiry over the redis and memcached protocols, as every key in the
+// cache must have one.  Keys with at least half of this remaining are reported as having no expiry.
+const noExpiry = 100 * 365 * 24 * time.Hour
+
+// unboundedTTL returns the ttl for keys set over the redis and memcached protocols without an expiry
+func (ns *namespace) unboundedTTL() time.Duration {
+	if ns.config.DefaultTTL > 0 {
+		return ns.config.DefaultTTL
+	}
+	return noExpiry
+}
+
+func validNamespaceName(name string) bool {
+	return name != "" && !strings.ContainsAny(name, "/ ")
+}
+
+// CreateNamespace adds a namespace to the server.  Returns an error wrapping ErrConflict if it already exists.
+func (srv *Server) CreateNamespace(config NamespaceConfig) error {
+	_, err := srv.createNamespace(config)
+	return err
+}
+
+// createNamespace adds a namespace to the server, returning it so callers need not look it up again
+func (srv *Server) createNamespace(config NamespaceConfig) (*namespace, error) {
+	if !validNamespaceName(config.Name) {
+		return nil, fmt.Errorf("invalid namespace name \"%s\"", config.Name)
+	}
+	if config.CacheSize <= 0 {
+		config.CacheSize = srv.cacheSize
+	}
+	if config.EvictionPolicy == "" {
+		config.EvictionPolicy = EvictionLRU
+	}
+	if config.EvictionPolicy != EvictionLRU && config.EvictionPolicy != EvictionFIFO {
+		return nil, fmt.Errorf("unknown eviction policy \"%s\"", config.EvictionPolicy)
+	}
+	if config.DefaultTTL < 0 {
+		return nil, fmt.Errorf("%w: default ttl cannot be negative", ErrInvalidTTL)
+	}
+
+	if config.MaxEntries < 0 || config.MaxBytes < 0 {
+		return nil, errors.New("namespace quotas cannot be negative")
+	}
+
+	srv.nsMu.Lock()
+	defer srv.nsMu.Unlock()
+	if _, ok := srv.namespaces[config.Name]; ok {
+		return nil, fmt.Errorf("%w: namespace \"%s\" already exists", ErrConflict, config.Name)
+	}
+
+	mem := NewMemoryCacheWithPolicy(config.CacheSize, config.EvictionPolicy)
+	mem.SetSizer(sizeOf)
+	feed := newChangeFeed()
+	mem.SetOnChange(feed.publish)
+	var cache TaggedCache = mem
+	var enc *EncryptedCache
+	if srv.encryption != nil {
+		enc = NewEncryptedCache(cache, srv.encryption, srv.log)
+		cache = enc
+	}
+
+	ns := &namespace{mu: new(sync.Mutex), config: config, mem: mem, cache: cache, enc: enc, feed: feed}
+	srv.namespaces[config.Name] = ns
+	return ns, nil
+}
+
+// DeleteNamespace removes a namespace and all of it's keys.  The default namespace cannot be deleted.
+func (srv *Server) DeleteNamespace(name string) error {
+	if name == DefaultNamespace {
+		return fmt.Errorf("%w: the default namespace cannot be deleted", ErrConflict)
+	}
+	srv.nsMu.Lock()
+	defer srv.nsMu.Unlock()
+	ns, ok := srv.namespaces[name]
+	if !ok {
+		return fmt.Errorf("%w: namespace \"%s\"", ErrNotFound, name)
+	}
+	delete(srv.namespaces, name)
+	// purging stops every item's expiry timer, which would otherwise keep the cache reachable until they fire
+	ns.mem.Purge()
+	ns.feed.close()
+	return nil
+}
+
+// Namespaces returns the configuration of every namespace, sorted by name
+func (srv *Server) Namespaces() []NamespaceConfig {
+	srv.nsMu.RLock()
+	defer srv.nsMu.RUnlock()
+	out := make([]NamespaceConfig, 0, len(srv.namespaces))
+	for _, ns := range srv.namespaces {
+		out = append(out, ns.config)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+func (srv *Server) namespaceInfos() []NamespaceInfo {
+	srv.nsMu.RLock()
+	infos := make([]NamespaceInfo, 0, len(srv.namespaces))
+	for _, ns := range srv.namespaces {
+		infos = append(infos, ns.info())
+	}
+	srv.nsMu.RUnlock()
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+func (srv *Server) namespace(name string) (*namespace, bool) {
+	srv.nsMu.RLock()
+	defer srv.nsMu.RUnlock()
+	ns, ok := srv.namespaces[name]
+	return ns, ok
+}
+
+// splitNamespace separates the /ns/{namespace} prefix from an escaped request path, returning the unescaped namespace
+// and the still escaped remainder of the path.  Paths without the prefix belong to the default namespace.
+func splitNamespace(path string) (string, string, bool) {
+	if !strings.HasPrefix(path, "/ns/") {
+		return DefaultNamespace, path, true
+	}
+	split := strings.SplitN(path[len("/ns/"):], "/", 2)
+	if len(split) != 2 {
+		return "", "", false
+	}
+	name, err := url.PathUnescape(split[0])
+	if err != nil || name == "" {
+		return "", "", false
+	}
+	return name, "/" + split[1], true
+}
+
+// splitPath splits an escaped request path into it's unescaped segments, so that keys may contain "/" or any other
+// character once escaped by the client
+func splitPath(path string) ([]string, bool) {
+	split := strings.Split(path, "/")
+	for i, s := range split {
+		u, err := url.PathUnescape(s)
+		if err != nil {
+			return nil, false
+		}
+		split[i] = u
+	}
+	return split, true
+}
+
+// admin handles /admin/ns and /admin/ns/{namespace}
+func (srv *Server) admin(w http.ResponseWriter, r *http.Request) {
+	split, ok := splitPath(r.URL.EscapedPath())
+	if !ok || len(split) < 3 || len(split) > 4 || split[1] != "admin" || split[2] != "ns" {
+		writeError(w, http.StatusNotFound, ErrorCodeUnknownRoute, http.StatusText(http.StatusNotFound))
+		return
+	}
+
+	srv.log.Printf("handling: %s %s", r.Method, r.RequestURI)
+
+	name := ""
+	if len(split) == 4 {
+		name = split[3]
+	}
+	if !srv.authorizeAdmin(w, r, name) {
+		return
+	}
+
+	if name == "" {
+		if r.Method != "GET" {
+			writeError(w, http.StatusMethodNotAllowed, ErrorCodeUnknownRoute, http.StatusText(http.StatusMethodNotAllowed))
+			return
+		}
+		srv.writeValue(w, r, http.StatusOK, srv.namespaceInfos())
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		if ns, ok := srv.namespace(name); ok {
+			srv.writeValue(w, r, http.StatusOK, ns.info())
+		} else {
+			writeError(w, http.StatusNotFound, ErrorCodeNotFound, fmt.Sprintf("Namespace \"%s\" not found", name))
+		}
+
+	case "PUT":
+		b, err := readBody(w, r, srv.maxBodySize)
+		if err != nil {
+			writeBodyError(w, err)
+			return
+		}
+		ni := new(NamespaceInfo)
+		if len(b) > 0 {
+			if err := json.Unmarshal(b, ni); err != nil {
+				writeError(w, http.StatusUnprocessableEntity, ErrorCodeInvalidBody, fmt.Sprintf("Unable to unmarshal body: %s", err))
+				return
+			}
+		}
+		config := NamespaceConfig{
+			Name:           name,
+			CacheSize:      ni.CacheSize,
+			EvictionPolicy: ni.EvictionPolicy,
+			MaxEntries:     ni.MaxEntries,
+			MaxBytes:       ni.MaxBytes,
+		}
+		if ni.DefaultTTL != "" {
+			if config.DefaultTTL, err = time.ParseDuration(ni.DefaultTTL); err != nil {
+				writeError(w, http.StatusNotAcceptable, ErrorCodeInvalidTTL, fmt.Sprintf("Invalid TTL format specified: %s", err))
+				return
+			}
+		}
+		ns, err := srv.createNamespace(config)
+		if err != nil {
+			writeNamespaceError(w, err)
+			return
+		}
+		srv.writeValue(w, r, http.StatusCreated, ns.info())
+
+	case "DELETE":
+		if err := srv.DeleteNamespace(name); err != nil {
+			writeNamespaceError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, ErrorCodeUnknownRoute, http.StatusText(http.StatusMethodNotAllowed))
+	}
+}
+
+func writeNamespaceError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrConflict):
+		writeError(w, http.StatusConflict, ErrorCodeConflict, err.Error())
+	case errors.Is(err, ErrNotFound):
+		writeError(w, http.StatusNotFound, ErrorCodeNotFound, err.Error())
+	case errors.Is(err, ErrInvalidTTL):
+		writeError(w, http.StatusNotAcceptable, ErrorCodeInvalidTTL, err.Error())
+	default:
+		writeError(w, http.StatusBadRequest, ErrorCodeInvalidBody, err.Error())
+	}
+}
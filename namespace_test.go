@@ -0,0 +1,64 @@
+package lruchal_test
+
+import (
+	"errors"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/dcarbone/lruchal"
+)
+
+func TestDeleteNamespace(t *testing.T) {
+	srv := newTestServer(t, &lruchal.ServerConfig{
+		Port:        lruchal.RandomPort,
+		BindAddress: "127.0.0.1",
+		Namespaces:  []lruchal.NamespaceConfig{{Name: "doomed"}},
+	})
+	if err := srv.Listen(); err != nil {
+		t.Logf("Unable to listen: %s", err)
+		t.FailNow()
+	}
+	go srv.Serve()
+	defer srv.Close()
+
+	client, err := lruchal.NewClient(&lruchal.ClientConfig{Address: srv.Addr().String(), Namespace: "doomed"})
+	if err != nil {
+		t.Logf("Unable to create client: %s", err)
+		t.FailNow()
+	}
+	defer client.Close()
+
+	const items = 200
+	for i := 0; i < items; i++ {
+		if err := client.Put(lruchal.Item{Key: "key" + strconv.Itoa(i), Value: i, TTL: "1h"}); err != nil {
+			t.Logf("Unable to put: %s", err)
+			t.FailNow()
+		}
+	}
+
+	before := runtime.NumGoroutine()
+	if err := srv.DeleteNamespace("doomed"); err != nil {
+		t.Logf("Unable to delete namespace: %s", err)
+		t.FailNow()
+	}
+	// each item holds a goroutine until it expires or is removed, so they must all exit once the namespace is deleted
+	deadline := time.Now().Add(5 * time.Second)
+	for runtime.NumGoroutine() > before-items {
+		if time.Now().After(deadline) {
+			t.Logf("Expected at least %d goroutines to exit, %d before and %d after", items, before, runtime.NumGoroutine())
+			t.FailNow()
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := srv.CreateNamespace(lruchal.NamespaceConfig{Name: "doomed"}); err != nil {
+		t.Logf("Unable to recreate namespace: %s", err)
+		t.FailNow()
+	}
+	if _, err := client.Get("key0"); !errors.Is(err, lruchal.ErrNotFound) {
+		t.Logf("Expected recreated namespace to be empty, saw %v", err)
+		t.FailNow()
+	}
+}
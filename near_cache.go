@@ -0,0 +1,112 @@
+package lruchal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const (
+	DefaultNearCacheSize         = 100
+	DefaultNearCacheMaxStaleness = 5 * time.Second
+
+	nearCacheReconnectBackoff = time.Second
+)
+
+// NearCacheConfig enables a small local cache inside Client, allowing hot keys to be served without a round trip to
+// the server.  Keys are populated on Get and Put and dropped on the client's own Remove.  Writes made by other clients
+// are only seen once the local copy is older than MaxStaleness, unless Invalidations is enabled.
+type NearCacheConfig struct {
+	Size          int           // maximum number of keys held locally
+	MaxStaleness  time.Duration // maximum length of time a key is served locally before being fetched again
+	Invalidations bool          // if true, keys modified on the server by anyone are dropped as they happen
+}
+
+type nearCache struct {
+	cache        *MemoryCache
+	maxStaleness time.Duration
+}
+
+func newNearCache(config *NearCacheConfig) *nearCache {
+	size := DefaultNearCacheSize
+	if config.Size > 0 {
+		size = config.Size
+	}
+	maxStaleness := DefaultNearCacheMaxStaleness
+	if config.MaxStaleness > 0 {
+		maxStaleness = config.MaxStaleness
+	}
+	nc := &nearCache{
+		cache:        NewMemoryCache(size),
+		maxStaleness: maxStaleness,
+	}
+	return nc
+}
+
+func (nc *nearCache) get(key string) ([]byte, bool) {
+	if b, ok := nc.cache.Get(key).([]byte); ok {
+		return b, true
+	}
+	return nil, false
+}
+
+// put stores the encoded value of a key for at most ttl, or the max staleness if ttl is 0 or longer.
+func (nc *nearCache) put(key string, b []byte, ttl time.Duration) {
+	if ttl <= 0 || ttl > nc.maxStaleness {
+		ttl = nc.maxStaleness
+	}
+	nc.cache.Put(key, b, ttl)
+}
+
+func (nc *nearCache) remove(key string) {
+	nc.cache.Remove(key)
+}
+
+// watchInvalidations will consume the server's invalidation stream until ctx is done, reconnecting as necessary.  As
+// keys may be missed while disconnected, the near cache is purged every time the stream is (re)established.
+func (c *Client) watchInvalidations(ctx context.Context) {
+	for {
+		if err := c.consumeInvalidations(ctx); err != nil && ctx.Err() == nil {
+			c.log.Printf("invalidation stream closed: %s", err)
+		}
+		c.near.cache.Purge()
+
+		timer := time.NewTimer(nearCacheReconnectBackoff)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+	}
+}
+
+func (c *Client) consumeInvalidations(ctx context.Context) error {
+	req, err := c.newRequest("GET", "/invalidations", nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.client.Do(req.WithContext(ctx))
+	if resp != nil {
+		defer resp.Body.Close()
+	}
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("unexpected status: %d", resp.StatusCode)
+	}
+
+	c.near.cache.Purge()
+
+	dec := json.NewDecoder(resp.Body)
+	for {
+		inv := new(invalidation)
+		if err := dec.Decode(inv); err != nil {
+			return err
+		}
+		c.near.remove(inv.Key)
+	}
+}
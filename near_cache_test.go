@@ -0,0 +1,111 @@
+package lruchal_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dcarbone/lruchal"
+)
+
+func TestClientNearCache(t *testing.T) {
+	var gets int32
+	invalidate := make(chan string)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/invalidations":
+			w.WriteHeader(http.StatusOK)
+			w.(http.Flusher).Flush()
+			for {
+				select {
+				case key := <-invalidate:
+					w.Write([]byte(`{"key":"` + key + `"}` + "\n"))
+					w.(http.Flusher).Flush()
+				case <-r.Context().Done():
+					return
+				}
+			}
+		case "/put":
+			w.WriteHeader(http.StatusNoContent)
+		case "/remove/key1":
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`"value1"`))
+		default:
+			atomic.AddInt32(&gets, 1)
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`"remote"`))
+		}
+	}))
+	defer ts.Close()
+
+	t.Run("PopulatedOnGet", func(t *testing.T) {
+		atomic.StoreInt32(&gets, 0)
+		client := newTestClient(t, ts, &lruchal.ClientConfig{NearCache: &lruchal.NearCacheConfig{MaxStaleness: 50 * time.Millisecond}})
+		defer client.Close()
+
+		for i := 0; i < 3; i++ {
+			if v, err := client.Get("key1"); err != nil || v != "remote" {
+				t.Logf("Expected \"remote\", saw %#v (%v)", v, err)
+				t.FailNow()
+			}
+		}
+		if g := atomic.LoadInt32(&gets); g != 1 {
+			t.Logf("Expected 1 remote get, saw %d", g)
+			t.FailNow()
+		}
+
+		time.Sleep(75 * time.Millisecond)
+		client.Get("key1")
+		if g := atomic.LoadInt32(&gets); g != 2 {
+			t.Logf("Expected stale key to be fetched again, saw %d remote gets", g)
+			t.FailNow()
+		}
+	})
+
+	t.Run("PopulatedOnPutAndDroppedOnRemove", func(t *testing.T) {
+		atomic.StoreInt32(&gets, 0)
+		client := newTestClient(t, ts, &lruchal.ClientConfig{NearCache: new(lruchal.NearCacheConfig)})
+		defer client.Close()
+
+		if err := client.Put(lruchal.Item{Key: "key1", Value: "local", TTL: "1m"}); err != nil {
+			t.Logf("Unexpected put error: %s", err)
+			t.FailNow()
+		}
+		if v, _ := client.Get("key1"); v != "local" {
+			t.Logf("Expected \"local\" from near cache, saw %#v", v)
+			t.FailNow()
+		}
+		client.Remove("key1")
+		if v, _ := client.Get("key1"); v != "remote" {
+			t.Logf("Expected \"remote\" after remove, saw %#v", v)
+			t.FailNow()
+		}
+	})
+
+	t.Run("Invalidations", func(t *testing.T) {
+		atomic.StoreInt32(&gets, 0)
+		client := newTestClient(t, ts, &lruchal.ClientConfig{NearCache: &lruchal.NearCacheConfig{MaxStaleness: time.Minute, Invalidations: true}})
+		defer client.Close()
+
+		// give the stream a moment to connect, as connecting purges the near cache
+		time.Sleep(50 * time.Millisecond)
+
+		client.Get("key1")
+		client.Get("key1")
+		if g := atomic.LoadInt32(&gets); g != 1 {
+			t.Logf("Expected 1 remote get, saw %d", g)
+			t.FailNow()
+		}
+
+		invalidate <- "key1"
+		time.Sleep(25 * time.Millisecond)
+
+		client.Get("key1")
+		if g := atomic.LoadInt32(&gets); g != 2 {
+			t.Logf("Expected invalidated key to be fetched again, saw %d remote gets", g)
+			t.FailNow()
+		}
+	})
+}
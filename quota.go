@@ -0,0 +1,103 @@
+package lruchal
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// admit returns an error wrapping ErrQuotaExceeded if putting a value of size bytes under key would take the namespace
+// over either of it's quotas.  Caller must hold ns.mu.
+func (ns *namespace) admit(key string, size int) error {
+	if ns.config.MaxEntries <= 0 && ns.config.MaxBytes <= 0 {
+		return nil
+	}
+	err := ns.checkQuota(key, size)
+	if err == nil {
+		return nil
+	}
+	// expired keys count against the quota until they are expunged, so clear them out before refusing
+	ns.mem.Expunge()
+	return ns.checkQuota(key, size)
+}
+
+func (ns *namespace) checkQuota(key string, size int) error {
+	exists := ns.mem.Has(key)
+	if max := ns.config.MaxEntries; max > 0 && !exists && ns.mem.Len() >= max {
+		return fmt.Errorf("%w: namespace \"%s\" is limited to %d entries", ErrQuotaExceeded, ns.config.Name, max)
+	}
+	if max := ns.config.MaxBytes; max > 0 && ns.mem.Bytes()-int64(ns.mem.sizeOfKey(key))+int64(size) > max {
+		return fmt.Errorf("%w: namespace \"%s\" is limited to %d bytes", ErrQuotaExceeded, ns.config.Name, max)
+	}
+	return nil
+}
+
+// reclaim makes room for one more entry when fair sharing is enabled and the server is at capacity, by evicting from
+// the namespace furthest over it's fair share.  Each namespace's share of the capacity is in proportion to it's
+// configured CacheSize.  Caller must hold srv.capMu.
+func (srv *Server) reclaim() {
+	srv.nsMu.RLock()
+	defer srv.nsMu.RUnlock()
+
+	total := 0
+	var weights int64
+	for _, ns := range srv.namespaces {
+		total += ns.mem.Len()
+		weights += int64(ns.config.CacheSize)
+	}
+	if total < srv.cacheSize {
+		return
+	}
+
+	var victim *namespace
+	var victimOver int64
+	for _, ns := range srv.namespaces {
+		share := int64(srv.cacheSize) * int64(ns.config.CacheSize) / weights
+		if over := int64(ns.mem.Len()) - share; victim == nil || over > victimOver {
+			victim, victimOver = ns, over
+		}
+	}
+	victim.mem.Evict()
+}
+
+// sizeOf approximates the number of bytes held by v, counting the contents of strings, slices and maps
+func sizeOf(v interface{}) int {
+	return sizeOfValue(reflect.ValueOf(v))
+}
+
+func sizeOfValue(rv reflect.Value) int {
+	switch rv.Kind() {
+	case reflect.Invalid:
+		return 0
+	case reflect.String:
+		return rv.Len()
+	case reflect.Slice, reflect.Array:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return rv.Len()
+		}
+		n := 0
+		for i := 0; i < rv.Len(); i++ {
+			n += sizeOfValue(rv.Index(i))
+		}
+		return n
+	case reflect.Map:
+		n := 0
+		iter := rv.MapRange()
+		for iter.Next() {
+			n += sizeOfValue(iter.Key()) + sizeOfValue(iter.Value())
+		}
+		return n
+	case reflect.Struct:
+		n := 0
+		for i := 0; i < rv.NumField(); i++ {
+			n += sizeOfValue(rv.Field(i))
+		}
+		return n
+	case reflect.Ptr, reflect.Interface:
+		if rv.IsNil() {
+			return 0
+		}
+		return sizeOfValue(rv.Elem())
+	default:
+		return int(rv.Type().Size())
+	}
+}
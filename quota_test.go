@@ -0,0 +1,162 @@
+package lruchal_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/dcarbone/lruchal"
+)
+
+func TestQuotas(t *testing.T) {
+	startServer := func(t *testing.T, config *lruchal.ServerConfig) (*lruchal.Server, func(namespace string) *lruchal.Client) {
+		config.Port = lruchal.RandomPort
+		config.BindAddress = "127.0.0.1"
+		srv := newTestServer(t, config)
+		if err := srv.Listen(); err != nil {
+			t.Logf("Unable to listen: %s", err)
+			t.FailNow()
+		}
+		go srv.Serve()
+		t.Cleanup(func() { srv.Close() })
+		return srv, func(namespace string) *lruchal.Client {
+			client, err := lruchal.NewClient(&lruchal.ClientConfig{Address: srv.Addr().String(), Namespace: namespace})
+			if err != nil {
+				t.Logf("Unable to create client: %s", err)
+				t.FailNow()
+			}
+			t.Cleanup(func() { client.Close() })
+			return client
+		}
+	}
+	info := func(t *testing.T, srv *lruchal.Server, name string) lruchal.NamespaceInfo {
+		for _, ni := range srv.Stats().Namespaces {
+			if ni.Name == name {
+				return ni
+			}
+		}
+		t.Logf("Namespace %s not found", name)
+		t.FailNow()
+		return lruchal.NamespaceInfo{}
+	}
+
+	t.Run("MaxEntries", func(t *testing.T) {
+		_, newClient := startServer(t, &lruchal.ServerConfig{Namespaces: []lruchal.NamespaceConfig{{Name: "small", MaxEntries: 2}}})
+		client := newClient("small")
+
+		for i := 0; i < 2; i++ {
+			if err := client.Put(lruchal.Item{Key: fmt.Sprintf("key%d", i), Value: i, TTL: "1m"}); err != nil {
+				t.Logf("Unable to put within quota: %s", err)
+				t.FailNow()
+			}
+		}
+		err := client.Put(lruchal.Item{Key: "key2", Value: 2, TTL: "1m"})
+		var se *lruchal.ServerError
+		if !errors.Is(err, lruchal.ErrQuotaExceeded) || !errors.As(err, &se) || se.StatusCode != 507 {
+			t.Logf("Expected 507 ErrQuotaExceeded, saw %v", err)
+			t.FailNow()
+		}
+		// replacing an existing key does not add an entry
+		if err := client.Put(lruchal.Item{Key: "key0", Value: "replaced", TTL: "1m"}); err != nil {
+			t.Logf("Unable to replace within quota: %s", err)
+			t.FailNow()
+		}
+	})
+
+	t.Run("MaxBytes", func(t *testing.T) {
+		keys, err := lruchal.NewKeyRing("k1", []byte(strings.Repeat("k", 32)))
+		if err != nil {
+			t.Logf("Unable to create key ring: %s", err)
+			t.FailNow()
+		}
+		for _, encryption := range []lruchal.KeyProvider{nil, keys} {
+			srv, newClient := startServer(t, &lruchal.ServerConfig{
+				Encryption: encryption,
+				Namespaces: []lruchal.NamespaceConfig{{Name: "bytes", MaxBytes: 1000}},
+			})
+			client := newClient("bytes")
+
+			refused := false
+			for i := 0; i < 20 && !refused; i++ {
+				err := client.Put(lruchal.Item{Key: fmt.Sprintf("key%d", i), Value: strings.Repeat("v", 90), TTL: "1m"})
+				if errors.Is(err, lruchal.ErrQuotaExceeded) {
+					refused = true
+				} else if err != nil {
+					t.Logf("Unable to put: %s", err)
+					t.FailNow()
+				}
+			}
+			if !refused {
+				t.Logf("Expected quota to be exceeded with encryption %v", encryption != nil)
+				t.FailNow()
+			}
+			// the quota must be enforced against the size actually held, which grows once encrypted
+			if ni := info(t, srv, "bytes"); ni.Bytes > ni.MaxBytes || ni.Bytes == 0 {
+				t.Logf("Expected bytes held to be within %d with encryption %v, saw %d", ni.MaxBytes, encryption != nil, ni.Bytes)
+				t.FailNow()
+			}
+		}
+	})
+
+	t.Run("FairShare", func(t *testing.T) {
+		srv, newClient := startServer(t, &lruchal.ServerConfig{
+			CacheSize:  10,
+			FairShare:  true,
+			Namespaces: []lruchal.NamespaceConfig{{Name: "greedy"}, {Name: "modest"}},
+		})
+		greedy, modest := newClient("greedy"), newClient("modest")
+
+		for i := 0; i < 10; i++ {
+			if err := greedy.Put(lruchal.Item{Key: fmt.Sprintf("key%d", i), Value: i, TTL: "1m"}); err != nil {
+				t.Logf("Unable to put: %s", err)
+				t.FailNow()
+			}
+		}
+		for i := 0; i < 3; i++ {
+			if err := modest.Put(lruchal.Item{Key: fmt.Sprintf("key%d", i), Value: i, TTL: "1m"}); err != nil {
+				t.Logf("Unable to put: %s", err)
+				t.FailNow()
+			}
+		}
+
+		// the server is full, so each put to modest evicts from greedy as it is furthest over it's share
+		if g, m := info(t, srv, "greedy").Len, info(t, srv, "modest").Len; g != 7 || m != 3 {
+			t.Logf("Expected greedy to hold 7 and modest 3, saw %d and %d", g, m)
+			t.FailNow()
+		}
+		if _, err := greedy.Get("key0"); !errors.Is(err, lruchal.ErrNotFound) {
+			t.Logf("Expected greedy's least recently used key to be evicted, saw %v", err)
+			t.FailNow()
+		}
+	})
+
+	t.Run("WeightedFairShare", func(t *testing.T) {
+		srv, newClient := startServer(t, &lruchal.ServerConfig{
+			CacheSize:  12,
+			FairShare:  true,
+			Namespaces: []lruchal.NamespaceConfig{{Name: "wide", CacheSize: 16}, {Name: "narrow", CacheSize: 8}},
+		})
+		wide, narrow := newClient("wide"), newClient("narrow")
+
+		for i := 0; i < 6; i++ {
+			for _, client := range []*lruchal.Client{narrow, wide} {
+				if err := client.Put(lruchal.Item{Key: fmt.Sprintf("key%d", i), Value: i, TTL: "1m"}); err != nil {
+					t.Logf("Unable to put: %s", err)
+					t.FailNow()
+				}
+			}
+		}
+		// wide is entitled to twice narrow's share, so narrow is further over it's share and is evicted from
+		for i := 6; i < 8; i++ {
+			if err := wide.Put(lruchal.Item{Key: fmt.Sprintf("key%d", i), Value: i, TTL: "1m"}); err != nil {
+				t.Logf("Unable to put: %s", err)
+				t.FailNow()
+			}
+		}
+		if w, n := info(t, srv, "wide").Len, info(t, srv, "narrow").Len; w != 8 || n != 4 {
+			t.Logf("Expected wide to hold 8 and narrow 4, saw %d and %d", w, n)
+			t.FailNow()
+		}
+	})
+}
@@ -0,0 +1,231 @@
+package lruchal
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rateLimitSweepInterval is how often idle buckets are dropped, so clients that have gone away are not held forever
+const rateLimitSweepInterval = time.Minute
+
+// DefaultAuthRateLimit limits failed authentication attempts per ip address when tokens are configured and
+// RateLimitConfig.Auth is not set
+var DefaultAuthRateLimit = RateLimit{Rate: 1, Burst: 10}
+
+// RateLimit is a token bucket allowing Rate requests per second on average, with bursts of up to Burst requests
+type RateLimit struct {
+	Rate  float64 // requests per second a client's bucket is refilled by
+	Burst int     // maximum size of a client's bucket, defaults to the ceiling of Rate
+}
+
+// RateLimitConfig limits how often each client may call the server's http api.  Clients are identified by the name of
+// their token if authentication is enabled, otherwise by their ip address.  Each route class has it's own bucket per
+// client, and a nil class is not limited.
+type RateLimitConfig struct {
+	Read  *RateLimit // GET routes, other than the admin api.  A /watch stream counts as a single request
+	Write *RateLimit // PUT, POST and DELETE routes, other than the admin api
+	Admin *RateLimit // all /admin/ routes
+	Auth  *RateLimit // failed authentication attempts per ip address, defaults to DefaultAuthRateLimit with tokens
+}
+
+type routeClass int
+
+const (
+	routeRead routeClass = iota
+	routeWrite
+	routeAdmin
+	routeAuth
+)
+
+func (rc routeClass) String() string {
+	switch rc {
+	case routeRead:
+		return "read"
+	case routeWrite:
+		return "write"
+	case routeAdmin:
+		return "admin"
+	default:
+		return "authentication"
+	}
+}
+
+func classifyRoute(r *http.Request) routeClass {
+	if strings.HasPrefix(r.URL.Path, "/admin/") {
+		return routeAdmin
+	}
+	if r.Method == "GET" {
+		return routeRead
+	}
+	return routeWrite
+}
+
+type bucketKey struct {
+	class  routeClass
+	client string
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+type rateLimiter struct {
+	limits [4]*RateLimit
+
+	mu      *sync.Mutex
+	buckets map[bucketKey]*bucket
+	swept   time.Time
+}
+
+// newRateLimiter builds a limiter from config, which may be nil if only authentication failures are to be limited
+func newRateLimiter(config *RateLimitConfig, auth bool) (*rateLimiter, error) {
+	rl := &rateLimiter{
+		mu:      new(sync.Mutex),
+		buckets: make(map[bucketKey]*bucket),
+		swept:   time.Now(),
+	}
+	if config == nil {
+		config = new(RateLimitConfig)
+	}
+	limits := map[routeClass]*RateLimit{routeRead: config.Read, routeWrite: config.Write, routeAdmin: config.Admin, routeAuth: config.Auth}
+	if auth && config.Auth == nil {
+		limits[routeAuth] = &DefaultAuthRateLimit
+	}
+	for class, limit := range limits {
+		if limit == nil {
+			continue
+		}
+		if limit.Rate <= 0 || math.IsInf(limit.Rate, 0) || math.IsNaN(limit.Rate) {
+			return nil, fmt.Errorf("%s rate must be a positive number, saw %v", class, limit.Rate)
+		}
+		if limit.Burst < 0 {
+			return nil, fmt.Errorf("%s burst must not be negative, saw %d", class, limit.Burst)
+		}
+		l := *limit
+		if l.Burst == 0 {
+			l.Burst = int(math.Ceil(l.Rate))
+		}
+		rl.limits[class] = &l
+	}
+	return rl, nil
+}
+
+// take removes a token from the client's bucket for the class, returning how long the client must wait before
+// retrying if the bucket is empty
+func (rl *rateLimiter) take(class routeClass, client string, now time.Time) (time.Duration, bool) {
+	return rl.check(class, client, now, true)
+}
+
+// peek reports whether the client's bucket for the class has a token without removing it
+func (rl *rateLimiter) peek(class routeClass, client string, now time.Time) (time.Duration, bool) {
+	return rl.check(class, client, now, false)
+}
+
+func (rl *rateLimiter) check(class routeClass, client string, now time.Time, take bool) (time.Duration, bool) {
+	limit := rl.limits[class]
+	if limit == nil {
+		return 0, true
+	}
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	if now.Sub(rl.swept) >= rateLimitSweepInterval {
+		rl.sweep(now)
+	}
+
+	key := bucketKey{class: class, client: client}
+	b, ok := rl.buckets[key]
+	if !ok {
+		if !take {
+			return 0, true
+		}
+		b = &bucket{tokens: float64(limit.Burst), last: now}
+		rl.buckets[key] = b
+	} else if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = math.Min(float64(limit.Burst), b.tokens+elapsed.Seconds()*limit.Rate)
+		b.last = now
+	}
+	if b.tokens >= 1 {
+		if take {
+			b.tokens--
+		}
+		return 0, true
+	}
+	return time.Duration((1 - b.tokens) / limit.Rate * float64(time.Second)), false
+}
+
+// sweep drops buckets which have refilled, as they are no different from a new one.  Caller must hold lock.
+func (rl *rateLimiter) sweep(now time.Time) {
+	rl.swept = now
+	for key, b := range rl.buckets {
+		limit := rl.limits[key.class]
+		if b.tokens+now.Sub(b.last).Seconds()*limit.Rate >= float64(limit.Burst) {
+			delete(rl.buckets, key)
+		}
+	}
+}
+
+// rateLimitClient identifies the client a request is counted against
+func rateLimitClient(r *http.Request) string {
+	if t := requestToken(r); t != nil {
+		return "token:" + t.Name
+	}
+	return "ip:" + remoteHost(r)
+}
+
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		// e.g. unix sockets, where every client shares the same address
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// rateLimit counts the request against it's client, writing a 429 with a Retry-After header if the client has exceeded
+// the limit for the route.  Requests are always allowed if rate limiting is disabled.
+func (srv *Server) rateLimit(w http.ResponseWriter, r *http.Request) bool {
+	if srv.limiter == nil {
+		return true
+	}
+	class := classifyRoute(r)
+	wait, ok := srv.limiter.take(class, rateLimitClient(r), time.Now())
+	if ok {
+		return true
+	}
+	writeRateLimited(w, wait, fmt.Sprintf("Too many %s requests", class))
+	return false
+}
+
+// allowAuthAttempt writes a 429 if the request's ip address has failed authentication too often, before it's token is
+// checked so tokens cannot be guessed at any faster
+func (srv *Server) allowAuthAttempt(w http.ResponseWriter, r *http.Request) bool {
+	if srv.limiter == nil {
+		return true
+	}
+	wait, ok := srv.limiter.peek(routeAuth, remoteHost(r), time.Now())
+	if ok {
+		return true
+	}
+	writeRateLimited(w, wait, "Too many failed authentication attempts")
+	return false
+}
+
+// authFailed counts a failed authentication attempt against the request's ip address
+func (srv *Server) authFailed(r *http.Request) {
+	if srv.limiter != nil {
+		srv.limiter.take(routeAuth, remoteHost(r), time.Now())
+	}
+}
+
+func writeRateLimited(w http.ResponseWriter, wait time.Duration, reason string) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	writeError(w, http.StatusTooManyRequests, ErrorCodeRateLimited, fmt.Sprintf("%s, retry in %s", reason, wait.Round(time.Millisecond)))
+}
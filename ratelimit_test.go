@@ -0,0 +1,143 @@
+package lruchal_test
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/dcarbone/lruchal"
+)
+
+func TestRateLimit(t *testing.T) {
+	startServer := func(t *testing.T, config *lruchal.ServerConfig) *lruchal.Server {
+		config.Port = lruchal.RandomPort
+		config.BindAddress = "127.0.0.1"
+		srv := newTestServer(t, config)
+		if err := srv.Listen(); err != nil {
+			t.Logf("Unable to listen: %s", err)
+			t.FailNow()
+		}
+		go srv.Serve()
+		t.Cleanup(func() { srv.Close() })
+		return srv
+	}
+	newClient := func(t *testing.T, srv *lruchal.Server, token string) *lruchal.Client {
+		client, err := lruchal.NewClient(&lruchal.ClientConfig{Address: srv.Addr().String(), Token: token})
+		if err != nil {
+			t.Logf("Unable to create client: %s", err)
+			t.FailNow()
+		}
+		t.Cleanup(func() { client.Close() })
+		return client
+	}
+	put := func(client *lruchal.Client) error {
+		return client.Put(lruchal.Item{Key: "key1", Value: "value1", TTL: "1m"})
+	}
+
+	t.Run("RouteClasses", func(t *testing.T) {
+		srv := startServer(t, &lruchal.ServerConfig{
+			RateLimit: &lruchal.RateLimitConfig{Write: &lruchal.RateLimit{Rate: 0.01, Burst: 2}},
+		})
+		client := newClient(t, srv, "")
+
+		for i := 0; i < 2; i++ {
+			if err := put(client); err != nil {
+				t.Logf("Unable to put within burst: %s", err)
+				t.FailNow()
+			}
+		}
+		if err := put(client); !errors.Is(err, lruchal.ErrRateLimited) {
+			t.Logf("Expected ErrRateLimited, saw %v", err)
+			t.FailNow()
+		}
+		// reads have no limit configured
+		for i := 0; i < 10; i++ {
+			if _, err := client.Get("key1"); err != nil {
+				t.Logf("Unable to get: %s", err)
+				t.FailNow()
+			}
+		}
+
+		req, _ := http.NewRequest("PUT", "http://"+srv.Addr().String()+"/put", strings.NewReader(`{"key":"key1","value":"value1","ttl":"1m"}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Logf("Unable to put: %s", err)
+			t.FailNow()
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusTooManyRequests {
+			t.Logf("Expected 429, saw %d", resp.StatusCode)
+			t.FailNow()
+		}
+		if after, err := strconv.Atoi(resp.Header.Get("Retry-After")); err != nil || after < 1 || after > 100 {
+			t.Logf("Expected Retry-After within 100 seconds, saw %q", resp.Header.Get("Retry-After"))
+			t.FailNow()
+		}
+	})
+
+	t.Run("Tokens", func(t *testing.T) {
+		srv := startServer(t, &lruchal.ServerConfig{
+			Tokens: []lruchal.Token{
+				{Name: "a", Token: "a-secret", Scopes: []lruchal.Scope{lruchal.ScopeWrite}},
+				{Name: "b", Token: "b-secret", Scopes: []lruchal.Scope{lruchal.ScopeWrite}},
+			},
+			RateLimit: &lruchal.RateLimitConfig{Write: &lruchal.RateLimit{Rate: 0.01, Burst: 1}},
+		})
+		a, b := newClient(t, srv, "a-secret"), newClient(t, srv, "b-secret")
+
+		if err := put(a); err != nil {
+			t.Logf("Unable to put with first token: %s", err)
+			t.FailNow()
+		}
+		if err := put(a); !errors.Is(err, lruchal.ErrRateLimited) {
+			t.Logf("Expected ErrRateLimited, saw %v", err)
+			t.FailNow()
+		}
+		// clients sharing an address are still limited by their own token
+		if err := put(b); err != nil {
+			t.Logf("Unable to put with second token: %s", err)
+			t.FailNow()
+		}
+	})
+
+	t.Run("FailedAuthentication", func(t *testing.T) {
+		srv := startServer(t, &lruchal.ServerConfig{
+			Tokens:    []lruchal.Token{{Name: "a", Token: "a-secret", Scopes: []lruchal.Scope{lruchal.ScopeRead}}},
+			RateLimit: &lruchal.RateLimitConfig{Auth: &lruchal.RateLimit{Rate: 0.01, Burst: 3}},
+		})
+		guesser, valid := newClient(t, srv, "guess"), newClient(t, srv, "a-secret")
+
+		for i := 0; i < 3; i++ {
+			if _, err := guesser.Len(); !errors.Is(err, lruchal.ErrUnauthorized) {
+				t.Logf("Expected ErrUnauthorized on attempt %d, saw %v", i+1, err)
+				t.FailNow()
+			}
+		}
+		if _, err := guesser.Len(); !errors.Is(err, lruchal.ErrRateLimited) {
+			t.Logf("Expected ErrRateLimited after repeated failures, saw %v", err)
+			t.FailNow()
+		}
+		// the address is refused before any token is checked, so guessing the right one does not help
+		if _, err := valid.Len(); !errors.Is(err, lruchal.ErrRateLimited) {
+			t.Logf("Expected ErrRateLimited for the address, saw %v", err)
+			t.FailNow()
+		}
+	})
+
+	t.Run("InvalidConfig", func(t *testing.T) {
+		for _, config := range []*lruchal.RateLimitConfig{
+			{Read: &lruchal.RateLimit{}},
+			{Write: &lruchal.RateLimit{Rate: -1}},
+			{Admin: &lruchal.RateLimit{Rate: 1, Burst: -1}},
+			{Auth: &lruchal.RateLimit{Rate: -1}},
+		} {
+			if _, err := lruchal.NewServer(&lruchal.ServerConfig{RateLimit: config}); err == nil {
+				t.Logf("Expected error with %+v", config)
+				t.FailNow()
+			}
+		}
+	})
+}
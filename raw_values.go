@@ -0,0 +1,207 @@
+package lruchal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// TTLHeader may be used to specify the ttl of a value put via /key/{key}.  The "ttl" query parameter may be used
+	// instead.
+	TTLHeader = "X-Cache-TTL"
+	// TagsHeader may be used to provide a comma separated list of tags for a value put via /key/{key}.  The "tag"
+	// query parameter may be repeated instead.
+	TagsHeader = "X-Cache-Tags"
+
+	DefaultBinaryContentType = "application/octet-stream"
+)
+
+// BinaryValue is stored for values put via /key/{key} or the memcached listener, preserving the data and content type
+// verbatim
+type BinaryValue struct {
+	ContentType string `json:"content_type"`
+	Data        []byte `json:"data"`
+	Flags       uint32 `json:"flags,omitempty"` // opaque flags set by memcached clients
+}
+
+func (srv *Server) getKey(w http.ResponseWriter, r *http.Request, ns *namespace) {
+	key, ok := keyFromPath(r, "key")
+	if !ok {
+		writeError(w, http.StatusNotFound, ErrorCodeUnknownRoute, http.StatusText(http.StatusNotFound))
+		return
+	}
+
+	srv.log.Printf("handling: GET %s", r.RequestURI)
+
+	switch value := srv.load(ns, key).(type) {
+	case nil:
+		writeError(w, http.StatusNotFound, ErrorCodeNotFound, fmt.Sprintf("Key \"%s\" not found", key))
+	case *BinaryValue:
+		srv.writeBody(w, r, http.StatusOK, value.ContentType, value.Data)
+	default:
+		srv.writeValue(w, r, http.StatusOK, value)
+	}
+}
+
+func (srv *Server) putKey(w http.ResponseWriter, r *http.Request, ns *namespace) {
+	key, ok := keyFromPath(r, "key")
+	if !ok {
+		writeError(w, http.StatusNotFound, ErrorCodeUnknownRoute, http.StatusText(http.StatusNotFound))
+		return
+	}
+
+	ttl := r.Header.Get(TTLHeader)
+	if ttl == "" {
+		ttl = r.URL.Query().Get("ttl")
+	}
+	duration, err := ns.ttl(ttl)
+	if err != nil {
+		writeError(w, http.StatusNotAcceptable, ErrorCodeInvalidTTL, fmt.Sprintf("Invalid TTL format specified: %s", err))
+		return
+	}
+
+	b, err := readBody(w, r, srv.maxBodySize)
+	if err != nil {
+		writeBodyError(w, err)
+		return
+	}
+
+	srv.log.Printf("handling: PUT %s (%d bytes)", r.RequestURI, len(b))
+
+	value := &BinaryValue{
+		ContentType: r.Header.Get("Content-Type"),
+		Data:        b,
+	}
+	if value.ContentType == "" {
+		value.ContentType = DefaultBinaryContentType
+	}
+
+	if err := srv.store(ns, key, value, duration, tagsFromRequest(r)); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	w.Header().Set("Content-Length", "0")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// tagsFromRequest returns the tags provided with the TagsHeader header or tag query parameters
+func tagsFromRequest(r *http.Request) []string {
+	var tags []string
+	if h := r.Header.Get(TagsHeader); h != "" {
+		for _, tag := range strings.Split(h, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	for _, tag := range r.URL.Query()["tag"] {
+		if tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+func (srv *Server) deleteKey(w http.ResponseWriter, r *http.Request, ns *namespace) {
+	key, ok := keyFromPath(r, "key")
+	if !ok {
+		writeError(w, http.StatusNotFound, ErrorCodeUnknownRoute, http.StatusText(http.StatusNotFound))
+		return
+	}
+
+	srv.log.Printf("handling: DELETE %s", r.RequestURI)
+
+	if value := srv.unload(ns, key); value == nil {
+		writeError(w, http.StatusNotFound, ErrorCodeNotFound, fmt.Sprintf("Key \"%s\" not found", key))
+	} else {
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// PutBytes will store data verbatim under key, to be returned with the provided content type by GetBytes.  A ttl of 0
+// uses the default ttl of the client's namespace.
+func (c *Client) PutBytes(key string, data []byte, contentType string, ttl time.Duration) error {
+	return c.PutBytesContext(context.Background(), key, data, contentType, ttl)
+}
+
+func (c *Client) PutBytesContext(ctx context.Context, key string, data []byte, contentType string, ttl time.Duration) error {
+	if ttl < 0 {
+		return fmt.Errorf("%w: ttl cannot be negative", ErrInvalidTTL)
+	}
+	if contentType == "" {
+		contentType = DefaultBinaryContentType
+	}
+	if data == nil {
+		data = []byte{}
+	}
+
+	if c.near != nil {
+		defer c.near.remove(key)
+	}
+
+	header := make(http.Header)
+	header.Set("Content-Type", contentType)
+	if ttl > 0 {
+		header.Set(TTLHeader, ttl.String())
+	}
+
+	resp, err := c.do(ctx, &request{method: "PUT", path: "/key/" + url.PathEscape(key), header: header, body: data, timeout: c.writeTimeout, idempotent: true})
+	if err != nil {
+		return err
+	}
+
+	if resp.code == 204 {
+		return nil
+	}
+
+	return newServerError(resp.code, resp.body)
+}
+
+// GetBytes will return the value of key along with it's content type.  Values stored with Put are returned as json.
+func (c *Client) GetBytes(key string) ([]byte, string, error) {
+	return c.GetBytesContext(context.Background(), key)
+}
+
+func (c *Client) GetBytesContext(ctx context.Context, key string) ([]byte, string, error) {
+	resp, err := c.do(ctx, &request{method: "GET", path: "/key/" + url.PathEscape(key), timeout: c.readTimeout, idempotent: true})
+	if err != nil {
+		return nil, "", err
+	}
+
+	if resp.code == 200 {
+		return resp.body, resp.header.Get("Content-Type"), nil
+	}
+
+	return nil, "", newServerError(resp.code, resp.body)
+}
+
+// valueBytes converts a cached value into the bytes returned by the redis and memcached listeners.  Values put over
+// http which are not strings or binary are returned json encoded.
+func valueBytes(v interface{}) []byte {
+	switch v := v.(type) {
+	case string:
+		return []byte(v)
+	case []byte:
+		return v
+	case *BinaryValue:
+		return v.Data
+	case json.Number:
+		return []byte(v.String())
+	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return []byte(fmt.Sprint(v))
+	case float32:
+		return []byte(strconv.FormatFloat(float64(v), 'f', -1, 32))
+	case float64:
+		return []byte(strconv.FormatFloat(v, 'f', -1, 64))
+	}
+	if b, err := json.Marshal(v); err == nil {
+		return b
+	}
+	return []byte(fmt.Sprint(v))
+}
@@ -0,0 +1,140 @@
+package lruchal_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dcarbone/lruchal"
+)
+
+func TestRawValues(t *testing.T) {
+	srv := newTestServer(t, &lruchal.ServerConfig{Port: lruchal.RandomPort, BindAddress: "127.0.0.1"})
+	if err := srv.Listen(); err != nil {
+		t.Logf("Unable to listen: %s", err)
+		t.FailNow()
+	}
+	go srv.Serve()
+	defer srv.Close()
+
+	client, err := lruchal.NewClient(&lruchal.ClientConfig{Address: srv.Addr().String()})
+	if err != nil {
+		t.Logf("Unable to create client: %s", err)
+		t.FailNow()
+	}
+	defer client.Close()
+
+	do := func(t *testing.T, method, path string, header http.Header, body string) (*http.Response, string) {
+		req, _ := http.NewRequest(method, "http://"+srv.Addr().String()+path, strings.NewReader(body))
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Logf("Unable to %s %s: %s", method, path, err)
+			t.FailNow()
+		}
+		defer resp.Body.Close()
+		b, _ := ioutil.ReadAll(resp.Body)
+		return resp, string(b)
+	}
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		data := []byte{0x00, 0xff, 0x10, 'r', 'a', 'w'}
+		if err := client.PutBytes("raw1", data, "image/png", time.Minute); err != nil {
+			t.Logf("Unable to put bytes: %s", err)
+			t.FailNow()
+		}
+		b, contentType, err := client.GetBytes("raw1")
+		if err != nil || string(b) != string(data) || contentType != "image/png" {
+			t.Logf("Expected %v as image/png, saw %v as %s (err=%v)", data, b, contentType, err)
+			t.FailNow()
+		}
+
+		if err := client.PutBytes("raw2", []byte("x"), "", time.Minute); err != nil {
+			t.Logf("Unable to put bytes: %s", err)
+			t.FailNow()
+		}
+		if _, contentType, _ := client.GetBytes("raw2"); contentType != lruchal.DefaultBinaryContentType {
+			t.Logf("Expected default content type, saw %s", contentType)
+			t.FailNow()
+		}
+
+		// values put as items are returned json encoded
+		if err := client.Put(lruchal.Item{Key: "raw3", Value: map[string]int{"a": 1}, TTL: "1m"}); err != nil {
+			t.Logf("Unable to put: %s", err)
+			t.FailNow()
+		}
+		if b, contentType, err := client.GetBytes("raw3"); err != nil || string(b) != `{"a":1}` || !strings.HasPrefix(contentType, "application/json") {
+			t.Logf("Expected json, saw %s as %s (err=%v)", b, contentType, err)
+			t.FailNow()
+		}
+
+		if _, _, err := client.GetBytes("raw-missing"); !errors.Is(err, lruchal.ErrNotFound) {
+			t.Logf("Expected ErrNotFound, saw %v", err)
+			t.FailNow()
+		}
+		if err := client.PutBytes("raw4", []byte("x"), "", -time.Second); !errors.Is(err, lruchal.ErrInvalidTTL) {
+			t.Logf("Expected ErrInvalidTTL, saw %v", err)
+			t.FailNow()
+		}
+	})
+
+	t.Run("HTTP", func(t *testing.T) {
+		// tags may be given by header, query or both
+		header := http.Header{"Content-Type": {"text/plain"}, lruchal.TTLHeader: {"1m"}, lruchal.TagsHeader: {"red, blue"}}
+		for _, tag := range []string{"red", "blue", "green"} {
+			if resp, body := do(t, "PUT", "/key/raw5?tag=green", header, "hello"); resp.StatusCode != http.StatusNoContent {
+				t.Logf("Expected 204, saw %d: %s", resp.StatusCode, body)
+				t.FailNow()
+			}
+			resp, body := do(t, "GET", "/key/raw5", nil, "")
+			if resp.StatusCode != http.StatusOK || body != "hello" || resp.Header.Get("Content-Type") != "text/plain" {
+				t.Logf("Expected hello as text/plain, saw %d %q as %s", resp.StatusCode, body, resp.Header.Get("Content-Type"))
+				t.FailNow()
+			}
+			if removed, err := client.InvalidateTag(tag); err != nil || len(removed) != 1 || removed[0] != "raw5" {
+				t.Logf("Expected tag %s to remove raw5, saw %v (err=%v)", tag, removed, err)
+				t.FailNow()
+			}
+		}
+
+		if resp, body := do(t, "PUT", "/key/raw7", http.Header{lruchal.TTLHeader: {"soon"}}, "x"); resp.StatusCode != http.StatusNotAcceptable {
+			t.Logf("Expected 406 for an invalid ttl, saw %d: %s", resp.StatusCode, body)
+			t.FailNow()
+		}
+
+		if err := client.PutBytes("raw6", []byte("x"), "", time.Minute); err != nil {
+			t.Logf("Unable to put bytes: %s", err)
+			t.FailNow()
+		}
+
+		if resp, _ := do(t, "DELETE", "/key/raw6", nil, ""); resp.StatusCode != http.StatusNoContent {
+			t.Logf("Expected 204 deleting raw6, saw %d", resp.StatusCode)
+			t.FailNow()
+		}
+		if resp, _ := do(t, "DELETE", "/key/raw6", nil, ""); resp.StatusCode != http.StatusNotFound {
+			t.Logf("Expected 404 deleting raw6 again, saw %d", resp.StatusCode)
+			t.FailNow()
+		}
+		if resp, _ := do(t, "GET", "/key/", nil, ""); resp.StatusCode != http.StatusNotFound {
+			t.Logf("Expected 404 without a key, saw %d", resp.StatusCode)
+			t.FailNow()
+		}
+	})
+
+	t.Run("Expiry", func(t *testing.T) {
+		if err := client.PutBytes("raw8", []byte("x"), "", 50*time.Millisecond); err != nil {
+			t.Logf("Unable to put bytes: %s", err)
+			t.FailNow()
+		}
+		time.Sleep(100 * time.Millisecond)
+		if _, _, err := client.GetBytes("raw8"); !errors.Is(err, lruchal.ErrNotFound) {
+			t.Logf("Expected ErrNotFound after expiry, saw %v", err)
+			t.FailNow()
+		}
+	})
+}
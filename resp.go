@@ -0,0 +1,733 @@
+package lruchal
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	respMaxBulkLen  = 512 << 20
+	respMaxArrayLen = 1 << 20
+
+	// respReadChunk is the most allocated for a bulk string before it's data has actually arrived, so a header
+	// claiming a large bulk string cannot by itself make the reader allocate it
+	respReadChunk = 64 << 10
+	// respReadArgs is the most arguments allocated for before they have actually arrived
+	respReadArgs = 64
+)
+
+var errRESPProtocol = errors.New("protocol error")
+
+// respConn is a single client connection to the resp listener
+type respConn struct {
+	srv *Server
+	ns  *namespace
+	r   *bufio.Reader
+	w   *bufio.Writer
+}
+
+// serveRESP accepts redis protocol connections on l until it is closed
+func (srv *Server) serveRESP(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go srv.handleRESP(conn)
+	}
+}
+
+func (srv *Server) handleRESP(conn net.Conn) {
+	defer conn.Close()
+
+	ns, _ := srv.namespace(DefaultNamespace)
+	rc := &respConn{
+		srv: srv,
+		ns:  ns,
+		r:   bufio.NewReader(conn),
+		w:   bufio.NewWriter(conn),
+	}
+
+	for {
+		args, err := rc.readCommand()
+		if err != nil {
+			if errors.Is(err, errRESPProtocol) {
+				rc.writeError(fmt.Sprintf("ERR %s", err))
+				rc.w.Flush()
+			} else if err != io.EOF {
+				srv.log.Printf("resp: unable to read from %s: %s", conn.RemoteAddr(), err)
+			}
+			return
+		}
+		if len(args) == 0 {
+			continue
+		}
+
+		quit := rc.exec(args)
+
+		// responses to pipelined commands are buffered until there are no more commands waiting to be read
+		if quit || rc.r.Buffered() == 0 {
+			if err := rc.w.Flush(); err != nil {
+				return
+			}
+		}
+		if quit {
+			return
+		}
+	}
+}
+
+// readCommand reads either a resp array of bulk strings, or an inline command as sent by telnet
+func (rc *respConn) readCommand() ([][]byte, error) {
+	line, err := rc.readLine()
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 {
+		return nil, nil
+	}
+
+	if line[0] != '*' {
+		fields := strings.Fields(string(line))
+		args := make([][]byte, len(fields))
+		for i, f := range fields {
+			args[i] = []byte(f)
+		}
+		return args, nil
+	}
+
+	n, err := strconv.Atoi(string(line[1:]))
+	if err != nil || n > respMaxArrayLen {
+		return nil, fmt.Errorf("%w: invalid multibulk length", errRESPProtocol)
+	}
+	if n <= 0 {
+		return nil, nil
+	}
+
+	var args [][]byte
+	if n > respReadArgs {
+		args = make([][]byte, 0, respReadArgs)
+	} else {
+		args = make([][]byte, 0, n)
+	}
+	for i := 0; i < n; i++ {
+		line, err := rc.readLine()
+		if err != nil {
+			return nil, err
+		}
+		if len(line) == 0 || line[0] != '$' {
+			return nil, fmt.Errorf("%w: expected '$', got '%s'", errRESPProtocol, line)
+		}
+		l, err := strconv.Atoi(string(line[1:]))
+		if err != nil || l < 0 || l > respMaxBulkLen {
+			return nil, fmt.Errorf("%w: invalid bulk length", errRESPProtocol)
+		}
+		buf := new(bytes.Buffer)
+		if l+2 > respReadChunk {
+			buf.Grow(respReadChunk)
+		} else {
+			buf.Grow(l + 2)
+		}
+		if _, err := io.CopyN(buf, rc.r, int64(l)+2); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		b := buf.Bytes()
+		if b[l] != '\r' || b[l+1] != '\n' {
+			return nil, fmt.Errorf("%w: bulk string not terminated by CRLF", errRESPProtocol)
+		}
+		args = append(args, b[:l])
+	}
+	return args, nil
+}
+
+func (rc *respConn) readLine() ([]byte, error) {
+	line, err := rc.r.ReadSlice('\n')
+	if err == bufio.ErrBufferFull {
+		return nil, fmt.Errorf("%w: line too long", errRESPProtocol)
+	} else if err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(line, "\r\n"), nil
+}
+
+func (rc *respConn) writeSimple(s string) {
+	rc.w.WriteString("+" + s + "\r\n")
+}
+
+func (rc *respConn) writeError(s string) {
+	rc.w.WriteString("-" + s + "\r\n")
+}
+
+func (rc *respConn) writeInt(n int64) {
+	rc.w.WriteString(":" + strconv.FormatInt(n, 10) + "\r\n")
+}
+
+func (rc *respConn) writeBulk(b []byte) {
+	rc.w.WriteString("$" + strconv.Itoa(len(b)) + "\r\n")
+	rc.w.Write(b)
+	rc.w.WriteString("\r\n")
+}
+
+func (rc *respConn) writeNil() {
+	rc.w.WriteString("$-1\r\n")
+}
+
+func (rc *respConn) writeArrayLen(n int) {
+	rc.w.WriteString("*" + strconv.Itoa(n) + "\r\n")
+}
+
+// writeStoreError replies to an error returned by store, which is reported as OOM only if the namespace is full
+func (rc *respConn) writeStoreError(err error) {
+	if errors.Is(err, ErrQuotaExceeded) {
+		rc.writeError(fmt.Sprintf("OOM %s", err))
+	} else {
+		rc.writeError(fmt.Sprintf("ERR %s", err))
+	}
+}
+
+func (rc *respConn) writeArity(cmd string) {
+	rc.writeError(fmt.Sprintf("ERR wrong number of arguments for '%s' command", strings.ToLower(cmd)))
+}
+
+// exec runs a single command, returning true if the connection should be closed
+func (rc *respConn) exec(args [][]byte) bool {
+	cmd := strings.ToUpper(string(args[0]))
+	args = args[1:]
+
+	switch cmd {
+	case "PING":
+		switch len(args) {
+		case 0:
+			rc.writeSimple("PONG")
+		case 1:
+			rc.writeBulk(args[0])
+		default:
+			rc.writeArity(cmd)
+		}
+	case "ECHO":
+		if len(args) != 1 {
+			rc.writeArity(cmd)
+			return false
+		}
+		rc.writeBulk(args[0])
+	case "QUIT":
+		rc.writeSimple("OK")
+		return true
+	case "SELECT":
+		rc.selectNamespace(cmd, args)
+	case "COMMAND":
+		// sent by redis-cli on startup to fetch command docs, which are not provided
+		rc.writeArrayLen(0)
+	case "CLIENT":
+		rc.writeSimple("OK")
+	case "GET":
+		rc.get(cmd, args)
+	case "SET":
+		rc.set(cmd, args)
+	case "MGET":
+		rc.mget(cmd, args)
+	case "MSET":
+		rc.mset(cmd, args)
+	case "DEL":
+		rc.del(cmd, args)
+	case "EXISTS":
+		rc.exists(cmd, args)
+	case "TTL", "PTTL":
+		rc.ttl(cmd, args)
+	case "EXPIRE", "PEXPIRE":
+		rc.expire(cmd, args)
+	case "PERSIST":
+		rc.persist(cmd, args)
+	case "INCR", "DECR", "INCRBY", "DECRBY":
+		rc.incr(cmd, args)
+	case "KEYS":
+		rc.keys(cmd, args)
+	case "SCAN":
+		rc.scan(cmd, args)
+	case "DBSIZE":
+		if len(args) != 0 {
+			rc.writeArity(cmd)
+			return false
+		}
+		rc.writeInt(int64(len(rc.ns.mem.Keys())))
+	case "FLUSHDB":
+		rc.srv.purge(rc.ns)
+		rc.writeSimple("OK")
+	case "FLUSHALL":
+		rc.srv.nsMu.RLock()
+		namespaces := make([]*namespace, 0, len(rc.srv.namespaces))
+		for _, ns := range rc.srv.namespaces {
+			namespaces = append(namespaces, ns)
+		}
+		rc.srv.nsMu.RUnlock()
+		for _, ns := range namespaces {
+			rc.srv.purge(ns)
+		}
+		rc.writeSimple("OK")
+	case "INFO":
+		rc.info()
+	default:
+		rc.writeError(fmt.Sprintf("ERR unknown command '%s'", strings.ToLower(cmd)))
+	}
+	return false
+}
+
+// selectNamespace switches the connection to another namespace.  Database 0 is the default namespace, any other
+// namespace may be selected by name.
+func (rc *respConn) selectNamespace(cmd string, args [][]byte) {
+	if len(args) != 1 {
+		rc.writeArity(cmd)
+		return
+	}
+	name := string(args[0])
+	if name == "0" {
+		name = DefaultNamespace
+	}
+	ns, ok := rc.srv.namespace(name)
+	if !ok {
+		rc.writeError("ERR DB index is out of range")
+		return
+	}
+	rc.ns = ns
+	rc.writeSimple("OK")
+}
+
+func (rc *respConn) get(cmd string, args [][]byte) {
+	if len(args) != 1 {
+		rc.writeArity(cmd)
+		return
+	}
+	if v := rc.srv.load(rc.ns, string(args[0])); v == nil {
+		rc.writeNil()
+	} else {
+		rc.writeBulk(valueBytes(v))
+	}
+}
+
+func (rc *respConn) set(cmd string, args [][]byte) {
+	if len(args) < 2 {
+		rc.writeArity(cmd)
+		return
+	}
+	key, value := string(args[0]), string(args[1])
+	ttl := rc.ns.unboundedTTL()
+	var nx, xx, keepTTL, expires bool
+	for i := 2; i < len(args); i++ {
+		switch opt := strings.ToUpper(string(args[i])); opt {
+		case "NX":
+			nx = true
+		case "XX":
+			xx = true
+		case "KEEPTTL":
+			keepTTL = true
+		case "EX", "PX":
+			if expires || i+1 == len(args) {
+				rc.writeError("ERR syntax error")
+				return
+			}
+			i++
+			n, err := strconv.ParseInt(string(args[i]), 10, 64)
+			unit := time.Second
+			if opt == "PX" {
+				unit = time.Millisecond
+			}
+			d, ok := respTTL(n, unit)
+			if err != nil || !ok || d <= 0 {
+				rc.writeError("ERR invalid expire time in 'set' command")
+				return
+			}
+			ttl, expires = d, true
+		default:
+			rc.writeError("ERR syntax error")
+			return
+		}
+	}
+	if (nx && xx) || (keepTTL && expires) {
+		rc.writeError("ERR syntax error")
+		return
+	}
+
+	unlock := rc.srv.lock(rc.ns)
+	remaining, exists := rc.ns.mem.TTL(key)
+	if (nx && exists) || (xx && !exists) {
+		unlock()
+		rc.writeNil()
+		return
+	}
+	if keepTTL && exists {
+		ttl = remaining
+	}
+	err := rc.srv.storeLocked(rc.ns, key, value, ttl, nil)
+	unlock()
+
+	if err != nil {
+		rc.writeStoreError(err)
+		return
+	}
+	rc.writeSimple("OK")
+}
+
+func (rc *respConn) mget(cmd string, args [][]byte) {
+	if len(args) == 0 {
+		rc.writeArity(cmd)
+		return
+	}
+	rc.writeArrayLen(len(args))
+	for _, key := range args {
+		if v := rc.srv.load(rc.ns, string(key)); v == nil {
+			rc.writeNil()
+		} else {
+			rc.writeBulk(valueBytes(v))
+		}
+	}
+}
+
+func (rc *respConn) mset(cmd string, args [][]byte) {
+	if len(args) == 0 || len(args)%2 != 0 {
+		rc.writeArity(cmd)
+		return
+	}
+	ttl := rc.ns.unboundedTTL()
+	for i := 0; i < len(args); i += 2 {
+		key := string(args[i])
+		if err := rc.srv.store(rc.ns, key, string(args[i+1]), ttl, nil); err != nil {
+			rc.writeStoreError(err)
+			return
+		}
+	}
+	rc.writeSimple("OK")
+}
+
+func (rc *respConn) del(cmd string, args [][]byte) {
+	if len(args) == 0 {
+		rc.writeArity(cmd)
+		return
+	}
+	var n int64
+	for _, key := range args {
+		if rc.srv.unload(rc.ns, string(key)) != nil {
+			n++
+		}
+	}
+	rc.writeInt(n)
+}
+
+func (rc *respConn) exists(cmd string, args [][]byte) {
+	if len(args) == 0 {
+		rc.writeArity(cmd)
+		return
+	}
+	var n int64
+	for _, key := range args {
+		if rc.ns.cache.Has(string(key)) {
+			n++
+		}
+	}
+	rc.writeInt(n)
+}
+
+func (rc *respConn) ttl(cmd string, args [][]byte) {
+	if len(args) != 1 {
+		rc.writeArity(cmd)
+		return
+	}
+	remaining, ok := rc.ns.mem.TTL(string(args[0]))
+	switch {
+	case !ok:
+		rc.writeInt(-2)
+	case remaining > noExpiry/2:
+		rc.writeInt(-1)
+	case cmd == "PTTL":
+		rc.writeInt(int64((remaining + time.Millisecond/2) / time.Millisecond))
+	default:
+		rc.writeInt(int64((remaining + time.Second/2) / time.Second))
+	}
+}
+
+func (rc *respConn) expire(cmd string, args [][]byte) {
+	if len(args) != 2 {
+		rc.writeArity(cmd)
+		return
+	}
+	key := string(args[0])
+	n, err := strconv.ParseInt(string(args[1]), 10, 64)
+	if err != nil {
+		rc.writeError("ERR value is not an integer or out of range")
+		return
+	}
+	unit := time.Second
+	if cmd == "PEXPIRE" {
+		unit = time.Millisecond
+	}
+	ttl, ok := respTTL(n, unit)
+	if !ok {
+		rc.writeError(fmt.Sprintf("ERR invalid expire time in '%s' command", strings.ToLower(cmd)))
+		return
+	}
+
+	// as with redis, a non-positive ttl deletes the key
+	if ttl <= 0 {
+		if rc.srv.unload(rc.ns, key) != nil {
+			rc.writeInt(1)
+		} else {
+			rc.writeInt(0)
+		}
+		return
+	}
+
+	if rc.ns.mem.Expire(key, ttl) {
+		rc.writeInt(1)
+	} else {
+		rc.writeInt(0)
+	}
+}
+
+// respTTL converts n seconds or milliseconds into a ttl, returning false if it is too large to be represented
+func respTTL(n int64, unit time.Duration) (time.Duration, bool) {
+	if n > int64(math.MaxInt64/unit) || n < int64(math.MinInt64/unit) {
+		return 0, false
+	}
+	return time.Duration(n) * unit, true
+}
+
+func (rc *respConn) persist(cmd string, args [][]byte) {
+	if len(args) != 1 {
+		rc.writeArity(cmd)
+		return
+	}
+	remaining, ok := rc.ns.mem.TTL(string(args[0]))
+	if ok && remaining <= noExpiry/2 && rc.ns.mem.Expire(string(args[0]), noExpiry) {
+		rc.writeInt(1)
+	} else {
+		rc.writeInt(0)
+	}
+}
+
+func (rc *respConn) incr(cmd string, args [][]byte) {
+	delta := int64(1)
+	switch cmd {
+	case "INCR", "DECR":
+		if len(args) != 1 {
+			rc.writeArity(cmd)
+			return
+		}
+	default:
+		if len(args) != 2 {
+			rc.writeArity(cmd)
+			return
+		}
+		var err error
+		if delta, err = strconv.ParseInt(string(args[1]), 10, 64); err != nil {
+			rc.writeError("ERR value is not an integer or out of range")
+			return
+		}
+	}
+	if cmd == "DECR" || cmd == "DECRBY" {
+		if delta == math.MinInt64 {
+			rc.writeError("ERR decrement would overflow")
+			return
+		}
+		delta = -delta
+	}
+
+	key := string(args[0])
+	unlock := rc.srv.lock(rc.ns)
+	defer unlock()
+
+	var n int64
+	ttl := rc.ns.unboundedTTL()
+	if v := rc.srv.load(rc.ns, key); v != nil {
+		var err error
+		if n, err = strconv.ParseInt(string(valueBytes(v)), 10, 64); err != nil {
+			rc.writeError("ERR value is not an integer or out of range")
+			return
+		}
+		if remaining, ok := rc.ns.mem.TTL(key); ok {
+			ttl = remaining
+		}
+	}
+	if (delta > 0 && n > math.MaxInt64-delta) || (delta < 0 && n < math.MinInt64-delta) {
+		rc.writeError("ERR increment or decrement would overflow")
+		return
+	}
+	n += delta
+
+	if err := rc.srv.storeLocked(rc.ns, key, strconv.FormatInt(n, 10), ttl, nil); err != nil {
+		rc.writeStoreError(err)
+		return
+	}
+	rc.writeInt(n)
+}
+
+// sortedKeys returns the keys of the namespace matching pattern, sorted
+func (rc *respConn) sortedKeys(pattern string) []string {
+	all := rc.ns.mem.Keys()
+	keys := make([]string, 0, len(all))
+	for _, key := range all {
+		if k := remoteKey(key); respMatch(pattern, k) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (rc *respConn) keys(cmd string, args [][]byte) {
+	if len(args) != 1 {
+		rc.writeArity(cmd)
+		return
+	}
+	keys := rc.sortedKeys(string(args[0]))
+	rc.writeArrayLen(len(keys))
+	for _, key := range keys {
+		rc.writeBulk([]byte(key))
+	}
+}
+
+// scan treats the cursor as an offset into the sorted keys.  Keys added or removed between calls may cause others
+// to be skipped or returned twice, which redis also permits.
+func (rc *respConn) scan(cmd string, args [][]byte) {
+	if len(args) == 0 {
+		rc.writeArity(cmd)
+		return
+	}
+	cursor, err := strconv.Atoi(string(args[0]))
+	if err != nil || cursor < 0 {
+		rc.writeError("ERR invalid cursor")
+		return
+	}
+	pattern, count := "*", 10
+	for i := 1; i < len(args); i += 2 {
+		if i+1 == len(args) {
+			rc.writeError("ERR syntax error")
+			return
+		}
+		switch strings.ToUpper(string(args[i])) {
+		case "MATCH":
+			pattern = string(args[i+1])
+		case "COUNT":
+			if count, err = strconv.Atoi(string(args[i+1])); err != nil || count < 1 {
+				rc.writeError("ERR syntax error")
+				return
+			}
+		default:
+			rc.writeError("ERR syntax error")
+			return
+		}
+	}
+
+	keys := rc.sortedKeys(pattern)
+	if cursor > len(keys) {
+		cursor = len(keys)
+	}
+	end := cursor + count
+	next := end
+	if end >= len(keys) {
+		end, next = len(keys), 0
+	}
+
+	rc.writeArrayLen(2)
+	rc.writeBulk([]byte(strconv.Itoa(next)))
+	rc.writeArrayLen(end - cursor)
+	for _, key := range keys[cursor:end] {
+		rc.writeBulk([]byte(key))
+	}
+}
+
+func (rc *respConn) info() {
+	var b strings.Builder
+	b.WriteString("# Server\r\n")
+	// some client libraries refuse to talk to servers not reporting a version
+	b.WriteString("redis_version:6.0.0\r\n")
+	b.WriteString("redis_mode:standalone\r\n")
+	b.WriteString("server_name:lruchal\r\n")
+	b.WriteString("\r\n# Keyspace\r\n")
+	for i, ni := range rc.srv.namespaceInfos() {
+		fmt.Fprintf(&b, "db%d:keys=%d,expires=%d,avg_ttl=0,namespace=%s\r\n", i, ni.Len, ni.Len, ni.Name)
+	}
+	rc.writeBulk([]byte(b.String()))
+}
+
+// respMatch reports whether s matches the redis glob style pattern, supporting *, ?, [...] and \ escapes.  Only the
+// most recent * is backtracked to, so matching takes at most len(pattern)*len(s) steps however many *s there are.
+func respMatch(pattern, s string) bool {
+	p, i := 0, 0
+	star, starI := -1, 0
+	for i < len(s) {
+		if p < len(pattern) {
+			if pattern[p] == '*' {
+				for p < len(pattern) && pattern[p] == '*' {
+					p++
+				}
+				star, starI = p, i
+				continue
+			}
+			if n, ok := respMatchOne(pattern[p:], s[i]); ok {
+				p += n
+				i++
+				continue
+			}
+		}
+		// on a mismatch, let the most recent * consume one more character and retry from there
+		if star < 0 {
+			return false
+		}
+		starI++
+		p, i = star, starI
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
+
+// respMatchOne reports whether c matches the ?, [...], escaped or literal character at the start of pattern, along
+// with how much of pattern it took up
+func respMatchOne(pattern string, c byte) (int, bool) {
+	switch pattern[0] {
+	case '?':
+		return 1, true
+	case '[':
+		end := strings.IndexByte(pattern[1:], ']')
+		if end < 0 {
+			// unterminated class, match '[' literally
+			return 1, c == '['
+		}
+		class := pattern[1 : end+1]
+		negate := len(class) > 0 && class[0] == '^'
+		if negate {
+			class = class[1:]
+		}
+		matched := false
+		for i := 0; i < len(class); i++ {
+			if i+2 < len(class) && class[i+1] == '-' {
+				if class[i] <= c && c <= class[i+2] {
+					matched = true
+				}
+				i += 2
+			} else if class[i] == c {
+				matched = true
+			}
+		}
+		return end + 2, matched != negate
+	case '\\':
+		if len(pattern) > 1 {
+			return 2, pattern[1] == c
+		}
+		return 1, c == '\\'
+	default:
+		return 1, pattern[0] == c
+	}
+}
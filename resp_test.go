@@ -0,0 +1,317 @@
+package lruchal_test
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dcarbone/lruchal"
+)
+
+// respError is a resp error reply, distinguished from a simple string reply
+type respError string
+
+// respTestConn is a raw connection to the resp listener, so that the bytes on the wire are exactly those written
+type respTestConn struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func startRESPTestServer(t *testing.T) *lruchal.Server {
+	srv := newTestServer(t, &lruchal.ServerConfig{
+		Port:        lruchal.RandomPort,
+		RESPPort:    lruchal.RandomPort,
+		BindAddress: "127.0.0.1",
+		Namespaces:  []lruchal.NamespaceConfig{{Name: "other"}},
+	})
+	if err := srv.Listen(); err != nil {
+		t.Logf("Unable to listen: %s", err)
+		t.FailNow()
+	}
+	go srv.Serve()
+	t.Cleanup(func() { srv.Close() })
+	return srv
+}
+
+func dialRESP(t *testing.T, srv *lruchal.Server) *respTestConn {
+	conn, err := net.Dial("tcp", srv.RESPAddr().String())
+	if err != nil {
+		t.Logf("Unable to dial: %s", err)
+		t.FailNow()
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	return &respTestConn{t: t, conn: conn, r: bufio.NewReader(conn)}
+}
+
+func (c *respTestConn) write(s string) {
+	if _, err := io.WriteString(c.conn, s); err != nil {
+		c.t.Logf("Unable to write: %s", err)
+		c.t.FailNow()
+	}
+}
+
+// send writes args as a resp array of bulk strings
+func (c *respTestConn) send(args ...string) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	c.write(b.String())
+}
+
+// read reads a single reply, returning a string, respError, int64, []byte, nil or []interface{}
+func (c *respTestConn) read() interface{} {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		c.t.Logf("Unable to read reply: %s", err)
+		c.t.FailNow()
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	switch line[0] {
+	case '+':
+		return line[1:]
+	case '-':
+		return respError(line[1:])
+	case ':':
+		n, err := strconv.ParseInt(line[1:], 10, 64)
+		if err != nil {
+			c.t.Logf("Invalid integer reply %q", line)
+			c.t.FailNow()
+		}
+		return n
+	case '$':
+		n, _ := strconv.Atoi(line[1:])
+		if n < 0 {
+			return nil
+		}
+		b := make([]byte, n+2)
+		if _, err := io.ReadFull(c.r, b); err != nil {
+			c.t.Logf("Unable to read bulk reply: %s", err)
+			c.t.FailNow()
+		}
+		return b[:n]
+	case '*':
+		n, _ := strconv.Atoi(line[1:])
+		out := make([]interface{}, n)
+		for i := range out {
+			out[i] = c.read()
+		}
+		return out
+	}
+	c.t.Logf("Unexpected reply %q", line)
+	c.t.FailNow()
+	return nil
+}
+
+// expect sends a command and checks it's reply
+func (c *respTestConn) expect(expected interface{}, args ...string) {
+	c.send(args...)
+	if reply := c.read(); !reflect.DeepEqual(reply, expected) {
+		c.t.Logf("Expected %#v in reply to %q, saw %#v", expected, args, reply)
+		c.t.FailNow()
+	}
+}
+
+// expectClosed checks the server has closed the connection
+func (c *respTestConn) expectClosed() {
+	if line, err := c.r.ReadString('\n'); err != io.EOF {
+		c.t.Logf("Expected the connection to be closed, saw %q (err=%v)", line, err)
+		c.t.FailNow()
+	}
+}
+
+func TestRESP(t *testing.T) {
+	srv := startRESPTestServer(t)
+
+	t.Run("Commands", func(t *testing.T) {
+		c := dialRESP(t, srv)
+
+		c.expect("PONG", "PING")
+		c.expect([]byte("hello"), "ping", "hello")
+		c.expect([]byte("hello world"), "ECHO", "hello world")
+		c.expect("OK", "SET", "resp-key1", "value1")
+		c.expect([]byte("value1"), "GET", "resp-key1")
+		c.expect(nil, "GET", "resp-missing")
+		c.expect(nil, "SET", "resp-key1", "value2", "NX")
+		c.expect(nil, "SET", "resp-missing", "value2", "XX")
+		c.expect("OK", "SET", "resp-key2", "value2", "EX", "60")
+		c.expect([]interface{}{[]byte("value1"), nil, []byte("value2")}, "MGET", "resp-key1", "resp-missing", "resp-key2")
+		c.expect(int64(-1), "TTL", "resp-key1")
+		c.expect(int64(60), "TTL", "resp-key2")
+		c.expect(int64(-2), "TTL", "resp-missing")
+		c.expect(int64(1), "PERSIST", "resp-key2")
+		c.expect(int64(-1), "TTL", "resp-key2")
+		c.expect(int64(2), "EXISTS", "resp-key1", "resp-key2", "resp-missing")
+		c.expect([]interface{}{[]byte("resp-key1"), []byte("resp-key2")}, "KEYS", "resp-key*")
+		c.expect(int64(2), "DEL", "resp-key1", "resp-key2", "resp-missing")
+
+		c.expect(int64(1), "INCR", "resp-counter")
+		c.expect(int64(11), "INCRBY", "resp-counter", "10")
+		c.expect(int64(6), "DECRBY", "resp-counter", "5")
+		c.expect([]byte("6"), "GET", "resp-counter")
+		c.expect(int64(1), "PEXPIRE", "resp-counter", "50")
+		time.Sleep(100 * time.Millisecond)
+		c.expect(nil, "GET", "resp-counter")
+
+		// each namespace is selected as a database by name
+		c.expect("OK", "SET", "resp-key1", "default")
+		c.expect("OK", "SELECT", "other")
+		c.expect(nil, "GET", "resp-key1")
+		c.expect(int64(0), "DBSIZE")
+		c.expect("OK", "SELECT", "0")
+		c.expect([]byte("default"), "GET", "resp-key1")
+		c.expect("OK", "FLUSHDB")
+		c.expect(int64(0), "DBSIZE")
+
+		c.expect("OK", "QUIT")
+		c.expectClosed()
+	})
+
+	t.Run("Patterns", func(t *testing.T) {
+		c := dialRESP(t, srv)
+
+		keys := []string{"pat-hello", "pat-hallo", "pat-hxllo", "pat-[x]", "pat-" + strings.Repeat("a", 60)}
+		for _, key := range keys {
+			c.expect("OK", "SET", key, "value")
+		}
+		bulks := func(keys ...string) []interface{} {
+			out := make([]interface{}, len(keys))
+			for i, key := range keys {
+				out[i] = []byte(key)
+			}
+			return out
+		}
+		c.expect(bulks("pat-hallo", "pat-hello", "pat-hxllo"), "KEYS", "pat-h?llo")
+		c.expect(bulks("pat-hallo", "pat-hello"), "KEYS", "pat-h[ae]llo")
+		c.expect(bulks("pat-hallo", "pat-hxllo"), "KEYS", "pat-h[^e]llo")
+		c.expect(bulks("pat-hallo"), "KEYS", "pat-h[a-b]llo")
+		c.expect(bulks("pat-[x]"), "KEYS", `pat-\[x\]`)
+		c.expect(bulks("pat-hallo", "pat-hello", "pat-hxllo"), "KEYS", "pat-*l*o")
+		// patterns with many *s must not take exponential time to fail to match
+		c.expect(bulks(), "KEYS", "pat-"+strings.Repeat("*a", 20)+"*b")
+		c.expect(bulks("pat-"+strings.Repeat("a", 60)), "KEYS", "pat-"+strings.Repeat("*a", 20)+"*")
+
+		c.expect(int64(len(keys)), append([]string{"DEL"}, keys...)...)
+	})
+
+	t.Run("Inline", func(t *testing.T) {
+		c := dialRESP(t, srv)
+
+		c.write("SET resp-inline value\r\n")
+		if reply := c.read(); reply != "OK" {
+			t.Logf("Expected OK, saw %#v", reply)
+			t.FailNow()
+		}
+		// blank lines are ignored as they are by redis
+		c.write("\r\nGET resp-inline\n")
+		if reply := c.read(); !reflect.DeepEqual(reply, []byte("value")) {
+			t.Logf("Expected value, saw %#v", reply)
+			t.FailNow()
+		}
+		c.expect(int64(1), "DEL", "resp-inline")
+	})
+
+	t.Run("Pipelining", func(t *testing.T) {
+		c := dialRESP(t, srv)
+
+		// every command is written before any reply is read, and the replies must arrive in order
+		var b strings.Builder
+		for i := 0; i < 100; i++ {
+			fmt.Fprintf(&b, "*3\r\n$3\r\nSET\r\n$%d\r\nresp-pipe%d\r\n$%d\r\n%d\r\n", len(fmt.Sprintf("resp-pipe%d", i)), i, len(strconv.Itoa(i)), i)
+			fmt.Fprintf(&b, "*2\r\n$4\r\nINCR\r\n$%d\r\nresp-pipe%d\r\n", len(fmt.Sprintf("resp-pipe%d", i)), i)
+		}
+		b.WriteString("*1\r\n$6\r\nDBSIZE\r\n")
+		c.write(b.String())
+
+		for i := 0; i < 100; i++ {
+			if reply := c.read(); reply != "OK" {
+				t.Logf("Expected OK for set %d, saw %#v", i, reply)
+				t.FailNow()
+			}
+			if reply := c.read(); reply != int64(i+1) {
+				t.Logf("Expected %d for incr %d, saw %#v", i+1, i, reply)
+				t.FailNow()
+			}
+		}
+		if reply := c.read(); reply != int64(100) {
+			t.Logf("Expected dbsize 100, saw %#v", reply)
+			t.FailNow()
+		}
+		c.expect("OK", "FLUSHDB")
+	})
+
+	t.Run("ErrorReplies", func(t *testing.T) {
+		c := dialRESP(t, srv)
+
+		// errors in a command are replied to without closing the connection
+		c.expect(respError("ERR unknown command 'nosuchcommand'"), "NOSUCHCOMMAND")
+		c.expect(respError("ERR wrong number of arguments for 'get' command"), "GET")
+		c.expect(respError("ERR wrong number of arguments for 'set' command"), "SET", "resp-key")
+		c.expect(respError("ERR syntax error"), "SET", "resp-key", "value", "NX", "XX")
+		c.expect(respError("ERR syntax error"), "SET", "resp-key", "value", "EX")
+		c.expect(respError("ERR syntax error"), "SET", "resp-key", "value", "BOGUS")
+		c.expect(respError("ERR invalid expire time in 'set' command"), "SET", "resp-key", "value", "EX", "0")
+		c.expect(respError("ERR invalid expire time in 'set' command"), "SET", "resp-key", "value", "EX", "9223372036854775807")
+		c.expect(respError("ERR value is not an integer or out of range"), "INCRBY", "resp-key", "one")
+		c.expect(respError("ERR DB index is out of range"), "SELECT", "missing")
+		c.expect(respError("ERR invalid cursor"), "SCAN", "-1")
+		c.expect("OK", "SET", "resp-key", "not a number")
+		c.expect(respError("ERR value is not an integer or out of range"), "INCR", "resp-key")
+		// a ttl too large to represent must not overflow into a negative ttl, which would delete the key
+		c.expect(respError("ERR invalid expire time in 'expire' command"), "EXPIRE", "resp-key", "9223372036854775807")
+		c.expect(respError("ERR invalid expire time in 'pexpire' command"), "PEXPIRE", "resp-key", "-9223372036854775808")
+		c.expect(int64(1), "DEL", "resp-key")
+		c.expect("PONG", "PING")
+	})
+
+	t.Run("BadInput", func(t *testing.T) {
+		// protocol errors are replied to, then the connection is closed as it can no longer be parsed
+		for name, input := range map[string]string{
+			"MultibulkLength": "*x\r\n",
+			"MissingBulk":     "*1\r\n:1\r\n",
+			"BulkLength":      "*1\r\n$-5\r\n",
+			"OversizedBulk":   fmt.Sprintf("*1\r\n$%d\r\n", 1<<30),
+			"Unterminated":    "*1\r\n$4\r\nPINGxx",
+			"LineTooLong":     "*1\r\n$" + strings.Repeat("1", 4095),
+		} {
+			t.Run(name, func(t *testing.T) {
+				c := dialRESP(t, srv)
+				c.write(input)
+				if reply, ok := c.read().(respError); !ok || !strings.HasPrefix(string(reply), "ERR protocol error") {
+					t.Logf("Expected a protocol error, saw %#v", reply)
+					t.FailNow()
+				}
+				c.expectClosed()
+			})
+		}
+	})
+
+	t.Run("TruncatedBulk", func(t *testing.T) {
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+
+		// headers claiming bulk strings near the maximum length, followed by far less data
+		for i := 0; i < 4; i++ {
+			c := dialRESP(t, srv)
+			c.write(fmt.Sprintf("*1\r\n$%d\r\nabc", 500<<20))
+			c.conn.(*net.TCPConn).CloseWrite()
+			c.expectClosed()
+		}
+
+		runtime.ReadMemStats(&after)
+		if n := after.TotalAlloc - before.TotalAlloc; n > 64<<20 {
+			t.Logf("Expected the claimed lengths not to be allocated, saw %d bytes allocated", n)
+			t.FailNow()
+		}
+	})
+}
@@ -0,0 +1,117 @@
+package lruchal
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"net"
+	"time"
+)
+
+const (
+	DefaultRetryMaxAttempts    = 3
+	DefaultRetryInitialBackoff = 50 * time.Millisecond
+	DefaultRetryMaxBackoff     = 2 * time.Second
+	DefaultRetryMultiplier     = 2.0
+	DefaultRetryJitter         = 0.2
+)
+
+// RetryPolicy controls how Client retries failed requests.
+//
+// Idempotent operations (Get, Has, Len, Put, Expunge) are retried whenever the error or status code is retryable.
+// Non-idempotent operations (Remove, as the response depends on whether a prior attempt succeeded) are only retried
+// when the connection could not be established, as the server cannot have seen the request.  Set RetryNonIdempotent
+// to retry them the same as everything else.
+type RetryPolicy struct {
+	MaxAttempts          int              // total number of attempts, including the first.  <= 1 disables retries
+	InitialBackoff       time.Duration    // wait before the first retry
+	MaxBackoff           time.Duration    // upper bound on the wait between attempts
+	Multiplier           float64          // backoff growth factor per attempt
+	Jitter               float64          // fraction [0, 1] of each backoff that is randomized
+	RetryableStatusCodes []int            // response status codes that may be retried
+	RetryableError       func(error) bool // transport errors that may be retried, defaults to all non-context errors
+	RetryNonIdempotent   bool             // if true, non-idempotent operations are retried as idempotent ones are
+}
+
+func NewDefaultRetryPolicy() *RetryPolicy {
+	rp := &RetryPolicy{
+		MaxAttempts:          DefaultRetryMaxAttempts,
+		InitialBackoff:       DefaultRetryInitialBackoff,
+		MaxBackoff:           DefaultRetryMaxBackoff,
+		Multiplier:           DefaultRetryMultiplier,
+		Jitter:               DefaultRetryJitter,
+		RetryableStatusCodes: []int{502, 503, 504},
+		RetryableError:       defaultRetryableError,
+	}
+	return rp
+}
+
+func defaultRetryableError(err error) bool {
+	return !errors.Is(err, context.Canceled)
+}
+
+// isDialError returns true if err occurred while establishing a connection, meaning the request was never sent
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+func (rp *RetryPolicy) retryableStatus(code int) bool {
+	for _, c := range rp.RetryableStatusCodes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// shouldRetry determines whether another attempt should be made after the given attempt (starting at 1) failed with
+// either a transport error or a status code
+func (rp *RetryPolicy) shouldRetry(attempt int, idempotent bool, code int, err error) bool {
+	if rp == nil || attempt >= rp.MaxAttempts {
+		return false
+	}
+	if err != nil {
+		if !idempotent && !rp.RetryNonIdempotent {
+			return isDialError(err)
+		}
+		if rp.RetryableError != nil {
+			return rp.RetryableError(err)
+		}
+		return defaultRetryableError(err)
+	}
+	if !idempotent && !rp.RetryNonIdempotent {
+		return false
+	}
+	return rp.retryableStatus(code)
+}
+
+// backoff returns the time to wait after the given attempt (starting at 1) before making another
+func (rp *RetryPolicy) backoff(attempt int) time.Duration {
+	mult := rp.Multiplier
+	if mult < 1 {
+		mult = 1
+	}
+	d := float64(rp.InitialBackoff) * math.Pow(mult, float64(attempt-1))
+	if rp.MaxBackoff > 0 && d > float64(rp.MaxBackoff) {
+		d = float64(rp.MaxBackoff)
+	}
+	if rp.Jitter > 0 {
+		jitter := math.Min(rp.Jitter, 1)
+		d -= d * jitter * rand.Float64()
+	}
+	return time.Duration(d)
+}
+
+// wait blocks for the backoff duration of the given attempt, returning early with an error if ctx is done
+func (rp *RetryPolicy) wait(ctx context.Context, attempt int) error {
+	timer := time.NewTimer(rp.backoff(attempt))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,161 @@
+package lruchal_test
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dcarbone/lruchal"
+)
+
+// failingHandler will fail the first n requests it receives with the provided status code, or by dropping the
+// connection if code is 0, before responding with a successful get
+func failingHandler(n int32, code int, attempts *int32) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(attempts, 1) <= n {
+			if code == 0 {
+				conn, _, err := w.(http.Hijacker).Hijack()
+				if err == nil {
+					conn.Close()
+				}
+				return
+			}
+			w.WriteHeader(code)
+			w.Write([]byte(`{"code":"unavailable","message":"try again"}`))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`"value1"`))
+	}
+}
+
+func testRetryPolicy() *lruchal.RetryPolicy {
+	rp := lruchal.NewDefaultRetryPolicy()
+	rp.InitialBackoff = time.Millisecond
+	rp.MaxBackoff = 5 * time.Millisecond
+	return rp
+}
+
+func TestClientRetry(t *testing.T) {
+	t.Run("RetriesStatusCode", func(t *testing.T) {
+		var attempts int32
+		ts := httptest.NewServer(failingHandler(2, http.StatusServiceUnavailable, &attempts))
+		defer ts.Close()
+
+		client := newTestClient(t, ts, &lruchal.ClientConfig{Retry: testRetryPolicy()})
+		v, err := client.Get("key1")
+		if err != nil {
+			t.Logf("Expected get to succeed after retries, saw %s", err)
+			t.FailNow()
+		}
+		if v != "value1" {
+			t.Logf("Expected \"value1\", saw %#v", v)
+			t.FailNow()
+		}
+		if a := atomic.LoadInt32(&attempts); a != 3 {
+			t.Logf("Expected 3 attempts, saw %d", a)
+			t.FailNow()
+		}
+	})
+
+	t.Run("RetriesDroppedConnection", func(t *testing.T) {
+		var attempts int32
+		ts := httptest.NewServer(failingHandler(1, 0, &attempts))
+		defer ts.Close()
+
+		client := newTestClient(t, ts, &lruchal.ClientConfig{Retry: testRetryPolicy()})
+		if _, err := client.Get("key1"); err != nil {
+			t.Logf("Expected get to succeed after retry, saw %s", err)
+			t.FailNow()
+		}
+	})
+
+	t.Run("GivesUpAfterMaxAttempts", func(t *testing.T) {
+		var attempts int32
+		ts := httptest.NewServer(failingHandler(10, http.StatusServiceUnavailable, &attempts))
+		defer ts.Close()
+
+		client := newTestClient(t, ts, &lruchal.ClientConfig{Retry: testRetryPolicy()})
+		_, err := client.Get("key1")
+		se := new(lruchal.ServerError)
+		if !errors.As(err, &se) || se.StatusCode != http.StatusServiceUnavailable {
+			t.Logf("Expected 503 ServerError, saw %v", err)
+			t.FailNow()
+		}
+		if a := atomic.LoadInt32(&attempts); a != lruchal.DefaultRetryMaxAttempts {
+			t.Logf("Expected %d attempts, saw %d", lruchal.DefaultRetryMaxAttempts, a)
+			t.FailNow()
+		}
+	})
+
+	t.Run("NonRetryableStatusCode", func(t *testing.T) {
+		var attempts int32
+		ts := httptest.NewServer(failingHandler(10, http.StatusInternalServerError, &attempts))
+		defer ts.Close()
+
+		client := newTestClient(t, ts, &lruchal.ClientConfig{Retry: testRetryPolicy()})
+		if _, err := client.Get("key1"); err == nil {
+			t.Log("Expected error")
+			t.FailNow()
+		}
+		if a := atomic.LoadInt32(&attempts); a != 1 {
+			t.Logf("Expected 1 attempt, saw %d", a)
+			t.FailNow()
+		}
+	})
+
+	t.Run("NonIdempotentNotRetried", func(t *testing.T) {
+		var attempts int32
+		ts := httptest.NewServer(failingHandler(1, http.StatusServiceUnavailable, &attempts))
+		defer ts.Close()
+
+		client := newTestClient(t, ts, &lruchal.ClientConfig{Retry: testRetryPolicy()})
+		if _, err := client.Remove("key1"); err == nil {
+			t.Log("Expected remove to fail without retry")
+			t.FailNow()
+		}
+		if a := atomic.LoadInt32(&attempts); a != 1 {
+			t.Logf("Expected 1 attempt, saw %d", a)
+			t.FailNow()
+		}
+
+		atomic.StoreInt32(&attempts, 0)
+		rp := testRetryPolicy()
+		rp.RetryNonIdempotent = true
+		client = newTestClient(t, ts, &lruchal.ClientConfig{Retry: rp})
+		if _, err := client.Remove("key1"); err != nil {
+			t.Logf("Expected remove to succeed after retry, saw %s", err)
+			t.FailNow()
+		}
+	})
+
+	t.Run("NonIdempotentRetriedOnDialError", func(t *testing.T) {
+		var attempts int32
+		httpClient := &http.Client{
+			Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+				atomic.AddInt32(&attempts, 1)
+				return nil, &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+			}),
+		}
+
+		client, _ := lruchal.NewClient(&lruchal.ClientConfig{HttpClient: httpClient, Retry: testRetryPolicy()})
+		if _, err := client.Remove("key1"); err == nil {
+			t.Log("Expected dial error")
+			t.FailNow()
+		}
+		if a := atomic.LoadInt32(&attempts); a != lruchal.DefaultRetryMaxAttempts {
+			t.Logf("Expected %d attempts, saw %d", lruchal.DefaultRetryMaxAttempts, a)
+			t.FailNow()
+		}
+	})
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
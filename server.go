@@ -2,13 +2,14 @@ package lruchal
 
 import (
 	"context"
-	"encoding/json"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"golang.org/x/net/netutil"
-	"io/ioutil"
 	"net"
 	"net/http"
+	"net/url"
+	"os"
 	"strconv"
 	"strings"
 	"sync"
@@ -19,18 +20,41 @@ type Item struct {
 	Key   string      `json:"key"`
 	Value interface{} `json:"value"`
 	TTL   string      `json:"ttl"`
+	Tags  []string    `json:"tags,omitempty"`
 }
 
 const (
 	DefaultPort            = 8182
 	DefaultCacheSize       = 1000
 	DefaultConnectionLimit = 50
+	DefaultMaxBodySize     = 16 << 20
+
+	// RandomPort may be used for any of ServerConfig's ports to have the os choose a free port, which may then be found
+	// with the server's Addr methods once it is listening
+	RandomPort = -1
 )
 
 type ServerConfig struct {
-	Port            int // port to present http api to
-	ConnectionLimit int // maximum number of concurrent connections to perform
-	CacheSize       int // maximum number of records allowable in cache
+	Port            int                // port to present http api to, may be RandomPort
+	BindAddress     string             // optional, host or ip the server's tcp ports are bound to, e.g. 127.0.0.1.  Defaults to all interfaces
+	Address         string             // optional, if set the http api is presented on this address rather than Port, e.g. unix:///run/lruchal.sock or systemd://
+	UnixSocketMode  os.FileMode        // mode given to the socket when Address is a unix socket, defaults to DefaultUnixSocketMode
+	Listener        net.Listener       // optional, if set the http api is presented on this listener rather than Address or Port
+	ConnectionLimit int                // maximum number of concurrent connections to perform
+	MaxBodySize     int64              // maximum size of a request body, both as sent and once decompressed
+	CacheSize       int                // maximum number of records allowable in cache
+	Compression     *CompressionConfig // optional, if nil values are stored and sent uncompressed
+	Encryption      KeyProvider        // optional, if set values are held encrypted in memory
+	TLS             *ServerTLSConfig   // optional, if set every listener requires tls
+	Tokens          []Token            // optional, if set or loaded from TokenFile the http api requires a bearer token
+	TokenFile       string             // optional, json file of additional tokens, see LoadTokens
+	RateLimit       *RateLimitConfig   // optional, if set each client's requests to the http api are rate limited
+	Namespaces      []NamespaceConfig  // optional, additional namespaces to create at startup
+	FairShare       bool               // if true, CacheSize is shared by all namespaces and eviction prefers the namespace furthest over it's share
+	RESPPort        int                // optional, if set a redis protocol (RESP2) listener is opened on this port
+	MemcachedPort   int                // optional, if set a memcached text protocol listener is opened on this port
+	BinaryPort      int                // optional, if set a binary protocol listener is opened on this port, typically DefaultBinaryPort
+	GRPCPort        int                // optional, if set a grpc listener is opened on this port, typically DefaultGRPCPort
 	Logger          Logger
 }
 
@@ -39,6 +63,7 @@ func NewDefaultServerConfig() *ServerConfig {
 		Port:            DefaultPort,
 		CacheSize:       DefaultCacheSize,
 		ConnectionLimit: DefaultConnectionLimit,
+		MaxBodySize:     DefaultMaxBodySize,
 		Logger:          DefaultLogger("server"),
 	}
 
@@ -46,12 +71,32 @@ func NewDefaultServerConfig() *ServerConfig {
 }
 
 type Server struct {
-	mu       *sync.Mutex
-	ctx      context.Context
-	log      Logger
-	cache    Cache
-	listener net.Listener
-	running  bool
+	mu           *sync.Mutex
+	ctx          context.Context
+	log          Logger
+	listenConfig *ServerConfig
+	listener     net.Listener
+	listening    bool
+	running      bool
+
+	respListener      net.Listener
+	memcachedListener net.Listener
+	binaryListener    net.Listener
+	grpcListener      net.Listener
+
+	nsMu        *sync.RWMutex
+	namespaces  map[string]*namespace
+	cacheSize   int
+	maxBodySize int64
+	encryption  KeyProvider
+
+	fairShare bool
+	capMu     *sync.Mutex // serializes puts while fair sharing, so capacity is not overrun
+
+	compressor *compressor
+	tls        *tls.Config          // nil unless tls is enabled
+	tokens     map[tokenHash]*Token // nil unless authentication is enabled
+	limiter    *rateLimiter         // nil unless rate limiting is enabled
 }
 
 func NewDefaultServer() (*Server, error) {
@@ -66,27 +111,249 @@ func newServer(config *ServerConfig) (*Server, error) {
 	var err error
 
 	def := NewDefaultServerConfig()
-	if config.Port > 0 {
+	if portEnabled(config.Port) {
 		def.Port = config.Port
 	}
+	def.BindAddress = config.BindAddress
+	def.Listener = config.Listener
+	def.Address = config.Address
+	def.UnixSocketMode = DefaultUnixSocketMode
+	if config.UnixSocketMode != 0 {
+		def.UnixSocketMode = config.UnixSocketMode
+	}
 	if config.CacheSize > 0 {
 		def.CacheSize = config.CacheSize
 	}
 	if config.ConnectionLimit > 0 {
 		def.ConnectionLimit = config.ConnectionLimit
 	}
+	if config.MaxBodySize > 0 {
+		def.MaxBodySize = config.MaxBodySize
+	}
 	if config.Logger != nil {
 		def.Logger = config.Logger
 	}
+	if config.Compression != nil {
+		def.Compression = config.Compression
+	}
+	if config.Encryption != nil {
+		def.Encryption = config.Encryption
+	}
+	def.TLS = config.TLS
+	def.Tokens = config.Tokens
+	def.TokenFile = config.TokenFile
+	def.RateLimit = config.RateLimit
+	def.FairShare = config.FairShare
+	def.RESPPort = config.RESPPort
+	def.MemcachedPort = config.MemcachedPort
+	def.BinaryPort = config.BinaryPort
+	def.GRPCPort = config.GRPCPort
 
 	srv := &Server{
-		mu:    new(sync.Mutex),
-		ctx:   context.Background(),
-		log:   def.Logger,
-		cache: NewMemoryCache(def.CacheSize),
+		mu:  new(sync.Mutex),
+		ctx: context.Background(),
+		log: def.Logger,
+
+		nsMu:        new(sync.RWMutex),
+		namespaces:  make(map[string]*namespace),
+		cacheSize:   def.CacheSize,
+		maxBodySize: def.MaxBodySize,
+		encryption:  def.Encryption,
+
+		fairShare: def.FairShare,
+		capMu:     new(sync.Mutex),
+	}
+
+	if def.Compression != nil {
+		srv.compressor = newCompressor(def.Compression)
+	}
+	if def.TLS != nil {
+		if srv.tls, err = newServerTLSConfig(def.TLS, def.Logger); err != nil {
+			return nil, fmt.Errorf("unable to configure tls: %s", err)
+		}
+	}
+	if def.TokenFile != "" {
+		tokens, err := LoadTokens(def.TokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load tokens: %s", err)
+		}
+		if len(tokens) == 0 {
+			// an empty file would otherwise leave the server open
+			return nil, fmt.Errorf("no tokens found in \"%s\"", def.TokenFile)
+		}
+		def.Tokens = append(append([]Token(nil), def.Tokens...), tokens...)
+	}
+	if len(def.Tokens) > 0 {
+		if srv.tokens, err = newTokenIndex(def.Tokens); err != nil {
+			return nil, fmt.Errorf("unable to configure tokens: %s", err)
+		}
+		// the other protocols have no way to present a token, so would leave the cache open to anyone able to connect
+		if portEnabled(def.RESPPort) || portEnabled(def.MemcachedPort) || portEnabled(def.BinaryPort) || portEnabled(def.GRPCPort) {
+			return nil, errors.New("tokens may only be used with the http api, the redis, memcached, binary and grpc ports must not be set")
+		}
+	}
+	if def.RateLimit != nil || srv.tokens != nil {
+		if srv.limiter, err = newRateLimiter(def.RateLimit, srv.tokens != nil); err != nil {
+			return nil, fmt.Errorf("unable to configure rate limit: %s", err)
+		}
+	}
+
+	// the default namespace always exists, but may be configured like any other
+	namespaces := config.Namespaces
+	hasDefault := false
+	for _, nc := range namespaces {
+		if nc.Name == DefaultNamespace {
+			hasDefault = true
+		}
+	}
+	if !hasDefault {
+		namespaces = append([]NamespaceConfig{{Name: DefaultNamespace}}, namespaces...)
+	}
+	for _, nc := range namespaces {
+		if err = srv.CreateNamespace(nc); err != nil {
+			return nil, fmt.Errorf("unable to create namespace: %s", err)
+		}
+	}
+
+	srv.listenConfig = def
+
+	return srv, nil
+}
+
+// Listen opens all of the server's listeners without yet accepting connections.  Serve will call Listen if it has not
+// already been called, so it is only needed to find the address of a RandomPort or to report errors before serving.
+func (srv *Server) Listen() error {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if srv.listening {
+		return errors.New("server already listening")
+	}
+	return srv.listen()
+}
+
+// listen opens every configured listener.  Caller must hold lock.
+func (srv *Server) listen() error {
+	var err error
+	def := srv.listenConfig
+
+	switch {
+	case def.Listener != nil:
+		srv.listener = netutil.LimitListener(def.Listener, def.ConnectionLimit)
+	case def.Address != "":
+		srv.listener, err = listen(def.Address, def.ConnectionLimit, def.UnixSocketMode)
+	default:
+		srv.listener, err = listenTCP(def.BindAddress, def.Port, def.ConnectionLimit)
+	}
+	if err != nil {
+		return err
+	}
+	if portEnabled(def.RESPPort) {
+		if srv.respListener, err = listenTCP(def.BindAddress, def.RESPPort, def.ConnectionLimit); err != nil {
+			srv.closeListeners()
+			return err
+		}
+	}
+	if portEnabled(def.MemcachedPort) {
+		if srv.memcachedListener, err = listenTCP(def.BindAddress, def.MemcachedPort, def.ConnectionLimit); err != nil {
+			srv.closeListeners()
+			return err
+		}
+	}
+	if portEnabled(def.BinaryPort) {
+		if srv.binaryListener, err = listenTCP(def.BindAddress, def.BinaryPort, def.ConnectionLimit); err != nil {
+			srv.closeListeners()
+			return err
+		}
+	}
+	if portEnabled(def.GRPCPort) {
+		if srv.grpcListener, err = listenTCP(def.BindAddress, def.GRPCPort, def.ConnectionLimit); err != nil {
+			srv.closeListeners()
+			return err
+		}
+	}
+
+	if srv.tls != nil {
+		srv.listener = tls.NewListener(srv.listener, withNextProtos(srv.tls, "http/1.1"))
+		for _, l := range []*net.Listener{&srv.respListener, &srv.memcachedListener, &srv.binaryListener} {
+			if *l != nil {
+				*l = tls.NewListener(*l, srv.tls)
+			}
+		}
+		if srv.grpcListener != nil {
+			srv.grpcListener = tls.NewListener(srv.grpcListener, withNextProtos(srv.tls, "h2"))
+		}
+	}
+
+	srv.listening = true
+	return nil
+}
+
+// Addr returns the address of the http api, or nil if the server is not yet listening
+func (srv *Server) Addr() net.Addr {
+	return srv.addr(func() net.Listener { return srv.listener })
+}
+
+// RESPAddr returns the address of the redis protocol listener, or nil if there is none
+func (srv *Server) RESPAddr() net.Addr {
+	return srv.addr(func() net.Listener { return srv.respListener })
+}
+
+// MemcachedAddr returns the address of the memcached protocol listener, or nil if there is none
+func (srv *Server) MemcachedAddr() net.Addr {
+	return srv.addr(func() net.Listener { return srv.memcachedListener })
+}
+
+// BinaryAddr returns the address of the binary protocol listener, or nil if there is none
+func (srv *Server) BinaryAddr() net.Addr {
+	return srv.addr(func() net.Listener { return srv.binaryListener })
+}
+
+// GRPCAddr returns the address of the grpc listener, or nil if there is none
+func (srv *Server) GRPCAddr() net.Addr {
+	return srv.addr(func() net.Listener { return srv.grpcListener })
+}
+
+func (srv *Server) addr(listener func() net.Listener) net.Addr {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if l := listener(); l != nil {
+		return l.Addr()
 	}
+	return nil
+}
+
+// Close stops the server from accepting new connections on any of it's listeners, causing Serve to return
+func (srv *Server) Close() error {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	return srv.closeListeners()
+}
 
-	tcp, err := net.ResolveTCPAddr("tcp", fmt.Sprintf(":%d", def.Port))
+// closeListeners closes every listener opened so far, returning the first error seen
+func (srv *Server) closeListeners() error {
+	var err error
+	for _, l := range []net.Listener{srv.listener, srv.respListener, srv.memcachedListener, srv.binaryListener, srv.grpcListener} {
+		if l == nil {
+			continue
+		}
+		if cerr := l.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// portEnabled returns true if port has been set to a port number or RandomPort
+func portEnabled(port int) bool {
+	return port > 0 || port == RandomPort
+}
+
+// listenTCP opens a listener on port of the bind address, limited to limit concurrent connections
+func listenTCP(bind string, port, limit int) (net.Listener, error) {
+	if port == RandomPort {
+		port = 0
+	}
+	tcp, err := net.ResolveTCPAddr("tcp", net.JoinHostPort(bind, strconv.Itoa(port)))
 	if err != nil {
 		return nil, fmt.Errorf("unable to resolve tcp addr: %s", err)
 	}
@@ -96,94 +363,437 @@ func newServer(config *ServerConfig) (*Server, error) {
 		return nil, fmt.Errorf("unable to listen: %s", err)
 	}
 
-	srv.listener = netutil.LimitListener(listener, def.ConnectionLimit)
-
-	return srv, nil
+	return netutil.LimitListener(listener, limit), nil
 }
 
 func (srv *Server) Serve() error {
 	srv.mu.Lock()
 	if srv.running {
+		srv.mu.Unlock()
 		return errors.New("server already running")
 	}
+	if !srv.listening {
+		if err := srv.listen(); err != nil {
+			srv.mu.Unlock()
+			return err
+		}
+	}
 	srv.running = true
 	srv.mu.Unlock()
 
-	mux := http.NewServeMux()
-	mux.HandleFunc("/", srv.handle)
+	if srv.respListener != nil {
+		go func() {
+			if err := srv.serveRESP(srv.respListener); err != nil {
+				srv.log.Printf("resp listener stopped: %s", err)
+			}
+		}()
+	}
+	if srv.memcachedListener != nil {
+		go func() {
+			if err := srv.serveMemcached(srv.memcachedListener); err != nil {
+				srv.log.Printf("memcached listener stopped: %s", err)
+			}
+		}()
+	}
+	if srv.binaryListener != nil {
+		go func() {
+			if err := srv.serveBinary(srv.binaryListener); err != nil {
+				srv.log.Printf("binary listener stopped: %s", err)
+			}
+		}()
+	}
+	if srv.grpcListener != nil {
+		go func() {
+			if err := srv.ServeGRPC(srv.grpcListener); err != nil {
+				srv.log.Printf("grpc listener stopped: %s", err)
+			}
+		}()
+	}
 
-	return http.Serve(srv.listener, mux)
+	// handle routes every request itself, as http.ServeMux would clean paths holding keys such as "a/../b"
+	return http.Serve(srv.listener, http.HandlerFunc(srv.handle))
 }
 
 func (srv *Server) handle(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	if !srv.allowAuthAttempt(w, r) {
+		return
+	}
+	r, ok := srv.authenticate(w, r)
+	if !ok {
+		srv.authFailed(r)
+		return
+	}
+	if !srv.rateLimit(w, r) {
+		return
+	}
+
+	if strings.HasPrefix(r.URL.Path, "/admin/") {
+		srv.admin(w, r)
+		return
+	}
+
+	escaped := r.URL.EscapedPath()
+	name, path, ok := splitNamespace(escaped)
+	if !ok {
+		writeError(w, http.StatusNotFound, ErrorCodeUnknownRoute, http.StatusText(http.StatusNotFound))
+		return
+	}
+	split, ok := splitPath(path)
+	if !ok {
+		writeError(w, http.StatusNotFound, ErrorCodeUnknownRoute, http.StatusText(http.StatusNotFound))
+		return
+	}
+	if !srv.authorize(w, r, name, split) {
+		return
+	}
+	ns, ok := srv.namespace(name)
+	if !ok {
+		writeError(w, http.StatusNotFound, ErrorCodeNotFound, fmt.Sprintf("Namespace \"%s\" not found", name))
+		return
+	}
+	if path != escaped {
+		// handlers only see the path within the namespace, as with http.StripPrefix
+		r2 := new(http.Request)
+		*r2 = *r
+		r2.URL = new(url.URL)
+		*r2.URL = *r.URL
+		r2.URL.Path = strings.Join(split, "/")
+		r2.URL.RawPath = path
+		r = r2
+	}
+
 	switch r.Method {
 	case "GET":
-		srv.get(w, r)
+		switch split[1] {
+		case "get":
+			srv.get(w, r, ns)
+		case "has":
+			srv.has(w, r, ns)
+		case "len":
+			srv.len(w, r, ns)
+		case "stats":
+			srv.stats(w, r, ns)
+		case "invalidations":
+			srv.invalidations(w, r, ns)
+		case "watch":
+			srv.watch(w, r, ns)
+		case "key":
+			srv.getKey(w, r, ns)
+		default:
+			writeError(w, http.StatusNotFound, ErrorCodeUnknownRoute, http.StatusText(http.StatusNotFound))
+		}
 	case "PUT":
-		srv.put(w, r)
+		if split[1] == "key" {
+			srv.putKey(w, r, ns)
+		} else {
+			srv.put(w, r, ns)
+		}
+	case "DELETE":
+		if split[1] == "key" {
+			srv.deleteKey(w, r, ns)
+		} else if split[1] == "tag" {
+			srv.invalidateTag(w, r, ns)
+		} else {
+			srv.remove(w, r, ns)
+		}
+	case "POST":
+		srv.expunge(w, r, ns)
 	default:
-		defer r.Body.Close()
-		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		writeError(w, http.StatusNotFound, ErrorCodeUnknownRoute, http.StatusText(http.StatusNotFound))
 	}
 }
 
-func (srv *Server) get(w http.ResponseWriter, r *http.Request) {
-	defer r.Body.Close()
-	split := strings.Split(r.RequestURI, "/")
-	if len(split) != 3 {
-		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+// keyFromPath will attempt to extract the key from a request path in the format of /{action}/{key}, where key may
+// contain any character escaped with url.PathEscape
+func keyFromPath(r *http.Request, action string) (string, bool) {
+	split, ok := splitPath(r.URL.EscapedPath())
+	if !ok || len(split) != 3 || split[1] != action || split[2] == "" {
+		return "", false
+	}
+	return split[2], true
+}
+
+// writeValue encodes v with the codec negotiated from the request's Accept header
+func (srv *Server) writeValue(w http.ResponseWriter, r *http.Request, code int, v interface{}) {
+	codec := negotiateCodec(r.Header.Get("Accept"))
+	b, err := codec.Marshal(v)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, ErrorCodeUnprocessable, fmt.Sprintf("Unable to marshal value: %s", err))
 		return
 	}
+	w.Header().Set("Vary", "Accept")
+	srv.writeBody(w, r, code, codec.ContentType(), b)
+}
 
-	if split[1] != "get" {
-		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+// writeBody writes b as the response, compressing it if enabled and the client accepts gzip
+func (srv *Server) writeBody(w http.ResponseWriter, r *http.Request, code int, contentType string, b []byte) {
+	if srv.compressor != nil {
+		w.Header().Add("Vary", "Accept-Encoding")
+		if len(b) >= srv.compressor.threshold && acceptsGzip(r) {
+			if gz, err := gzipBytes(b, srv.compressor.level); err == nil {
+				w.Header().Set("Content-Encoding", "gzip")
+				b = gz
+			}
+		}
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(b)))
+	w.WriteHeader(code)
+	w.Write(b)
+}
+
+// store puts a value into the namespace's cache with any tags, compressing it if enabled.  Returns an error wrapping
+// ErrQuotaExceeded if the namespace has no room for the value, or any other error if it could not be encrypted.
+func (srv *Server) store(ns *namespace, key string, value interface{}, ttl time.Duration, tags []string) error {
+	unlock := srv.lock(ns)
+	defer unlock()
+	return srv.storeLocked(ns, key, value, ttl, tags)
+}
+
+// lock prevents any other stores to ns until the returned func is called, allowing a caller to check the current
+// value of a key before calling storeLocked
+func (srv *Server) lock(ns *namespace) func() {
+	if srv.fairShare {
+		srv.capMu.Lock()
+	}
+	ns.mu.Lock()
+	return func() {
+		ns.mu.Unlock()
+		if srv.fairShare {
+			srv.capMu.Unlock()
+		}
+	}
+}
+
+// storeLocked is store for callers holding lock
+func (srv *Server) storeLocked(ns *namespace, key string, value interface{}, ttl time.Duration, tags []string) error {
+	if srv.compressor != nil {
+		value = srv.compressor.compress(value)
+	}
+	if ns.enc != nil {
+		// encrypted here rather than by ns.cache, so the quota is checked against the same value mem's sizer measures
+		ev, err := ns.enc.encrypt(key, value)
+		if err != nil {
+			return fmt.Errorf("unable to encrypt value: %s", err)
+		}
+		value = ev
+	}
+
+	if err := ns.admit(key, sizeOf(value)); err != nil {
+		return err
+	}
+	if srv.fairShare && !ns.mem.Has(key) {
+		srv.reclaim()
+	}
+	ns.mem.PutWithTags(key, value, ttl, tags...)
+	return nil
+}
+
+// writeStoreError writes the response for an error returned by store.  Only quota errors are the client's to resolve,
+// anything else is a failure of the server.
+func writeStoreError(w http.ResponseWriter, err error) {
+	if errors.Is(err, ErrQuotaExceeded) {
+		writeError(w, http.StatusInsufficientStorage, ErrorCodeQuota, err.Error())
+		return
+	}
+	writeError(w, http.StatusInternalServerError, ErrorCodeStoreFailed, err.Error())
+}
+
+// load returns the value of key from the namespace's cache, decompressing it if necessary.  Returns nil if not found.
+func (srv *Server) load(ns *namespace, key string) interface{} {
+	return srv.decompress(key, ns.cache.Get(key))
+}
+
+// unload removes key from the namespace's cache, returning it's decompressed value.  Returns nil if not found.
+func (srv *Server) unload(ns *namespace, key string) interface{} {
+	return srv.decompress(key, ns.cache.Remove(key))
+}
+
+// purge removes every key from the namespace
+func (srv *Server) purge(ns *namespace) {
+	ns.mem.Purge()
+}
+
+func (srv *Server) decompress(key string, value interface{}) interface{} {
+	if srv.compressor == nil || value == nil {
+		return value
+	}
+	value, err := srv.compressor.decompress(value)
+	if err != nil {
+		srv.log.Printf("unable to decompress key \"%s\": %s", key, err)
+		return nil
+	}
+	return value
+}
+
+// ServerStats is the body of the /stats response
+type ServerStats struct {
+	Len         int               `json:"len"`
+	Namespaces  []NamespaceInfo   `json:"namespaces"`
+	Compression *CompressionStats `json:"compression,omitempty"`
+}
+
+func (srv *Server) Stats() ServerStats {
+	stats := ServerStats{Namespaces: srv.namespaceInfos()}
+	for _, ni := range stats.Namespaces {
+		stats.Len += ni.Len
+	}
+	if srv.compressor != nil {
+		cs := srv.compressor.Stats()
+		stats.Compression = &cs
+	}
+	return stats
+}
+
+func (srv *Server) stats(w http.ResponseWriter, r *http.Request, ns *namespace) {
+	if r.URL.Path != "/stats" {
+		writeError(w, http.StatusNotFound, ErrorCodeUnknownRoute, http.StatusText(http.StatusNotFound))
 		return
 	}
 
 	srv.log.Printf("handling: GET %s", r.RequestURI)
 
-	if value := srv.cache.Get(split[2]); value == nil {
-		http.Error(w, fmt.Sprintf("Key \"%s\" not found", split[2]), http.StatusNotFound)
-	} else if b, err := json.Marshal(value); err != nil {
-		http.Error(w, fmt.Sprintf("Unable to marshal value: %s", err), http.StatusUnprocessableEntity)
+	stats := srv.Stats()
+	if t := requestToken(r); t != nil && (len(t.Namespaces) > 0 || len(t.KeyPrefixes) > 0) {
+		// restricted tokens only see the namespace they asked about, rather than every tenant's
+		info := ns.info()
+		stats = ServerStats{Len: info.Len, Namespaces: []NamespaceInfo{info}}
+	}
+	srv.writeValue(w, r, http.StatusOK, stats)
+}
+
+func (srv *Server) get(w http.ResponseWriter, r *http.Request, ns *namespace) {
+	key, ok := keyFromPath(r, "get")
+	if !ok {
+		writeError(w, http.StatusNotFound, ErrorCodeUnknownRoute, http.StatusText(http.StatusNotFound))
+		return
+	}
+
+	srv.log.Printf("handling: GET %s", r.RequestURI)
+
+	if value := srv.load(ns, key); value == nil {
+		writeError(w, http.StatusNotFound, ErrorCodeNotFound, fmt.Sprintf("Key \"%s\" not found", key))
 	} else {
-		w.WriteHeader(http.StatusOK)
-		w.Header().Set("Content-Type", "application/json; charset=utf-8")
-		w.Header().Set("Content-Length", strconv.Itoa(len(b)))
-		w.Write(b)
+		srv.writeValue(w, r, http.StatusOK, value)
 	}
 }
 
-func (srv *Server) put(w http.ResponseWriter, r *http.Request) {
-	defer r.Body.Close()
-	if r.RequestURI != "/put" {
-		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+func (srv *Server) has(w http.ResponseWriter, r *http.Request, ns *namespace) {
+	key, ok := keyFromPath(r, "has")
+	if !ok {
+		writeError(w, http.StatusNotFound, ErrorCodeUnknownRoute, http.StatusText(http.StatusNotFound))
+		return
+	}
+
+	srv.log.Printf("handling: GET %s", r.RequestURI)
+
+	if ns.cache.Has(key) {
+		w.WriteHeader(http.StatusNoContent)
+	} else {
+		writeError(w, http.StatusNotFound, ErrorCodeNotFound, fmt.Sprintf("Key \"%s\" not found", key))
+	}
+}
+
+func (srv *Server) len(w http.ResponseWriter, r *http.Request, ns *namespace) {
+	if r.URL.Path != "/len" {
+		writeError(w, http.StatusNotFound, ErrorCodeUnknownRoute, http.StatusText(http.StatusNotFound))
+		return
+	}
+
+	srv.log.Printf("handling: GET %s", r.RequestURI)
+
+	srv.writeValue(w, r, http.StatusOK, ns.cache.Len())
+}
+
+func (srv *Server) put(w http.ResponseWriter, r *http.Request, ns *namespace) {
+	if r.URL.Path != "/put" {
+		writeError(w, http.StatusNotFound, ErrorCodeUnknownRoute, http.StatusText(http.StatusNotFound))
 		return
 	}
 
-	b, err := ioutil.ReadAll(r.Body)
+	b, err := readBody(w, r, srv.maxBodySize)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Unable to read body: %s", err), http.StatusUnprocessableEntity)
+		writeBodyError(w, err)
 		return
 	}
 
-	srv.log.Printf("handling: PUT %s", string(b))
+	codec, ok := codecForContentType(r.Header.Get("Content-Type"))
+	if !ok {
+		writeError(w, http.StatusUnsupportedMediaType, ErrorCodeUnsupported, fmt.Sprintf("Unsupported content type \"%s\"", r.Header.Get("Content-Type")))
+		return
+	}
 
 	item := new(Item)
-	err = json.Unmarshal(b, item)
+	err = codec.Unmarshal(b, item)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Unable to unmarshal body: %s", err), http.StatusUnprocessableEntity)
+		writeError(w, http.StatusUnprocessableEntity, ErrorCodeInvalidBody, fmt.Sprintf("Unable to unmarshal body: %s", err))
+		return
+	}
+
+	srv.log.Printf("handling: PUT %s (key=%s, ttl=%s, %s)", r.RequestURI, item.Key, item.TTL, codec.ContentType())
+
+	if !authorizeKey(w, r, item.Key) {
 		return
 	}
 
-	duration, err := time.ParseDuration(item.TTL)
+	duration, err := ns.ttl(item.TTL)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Invalid TTL format specified: %s", err), http.StatusNotAcceptable)
+		writeError(w, http.StatusNotAcceptable, ErrorCodeInvalidTTL, fmt.Sprintf("Invalid TTL format specified: %s", err))
 		return
 	}
 
-	srv.cache.Put(item.Key, item.Value, duration)
+	if err := srv.store(ns, item.Key, item.Value, duration, item.Tags); err != nil {
+		writeStoreError(w, err)
+		return
+	}
 	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 	w.Header().Set("Content-Length", "0")
 	w.WriteHeader(http.StatusNoContent)
 }
+
+func (srv *Server) remove(w http.ResponseWriter, r *http.Request, ns *namespace) {
+	key, ok := keyFromPath(r, "remove")
+	if !ok {
+		writeError(w, http.StatusNotFound, ErrorCodeUnknownRoute, http.StatusText(http.StatusNotFound))
+		return
+	}
+
+	srv.log.Printf("handling: DELETE %s", r.RequestURI)
+
+	if value := srv.unload(ns, key); value == nil {
+		writeError(w, http.StatusNotFound, ErrorCodeNotFound, fmt.Sprintf("Key \"%s\" not found", key))
+	} else {
+		srv.writeValue(w, r, http.StatusOK, value)
+	}
+}
+
+func (srv *Server) invalidateTag(w http.ResponseWriter, r *http.Request, ns *namespace) {
+	tag, ok := keyFromPath(r, "tag")
+	if !ok {
+		writeError(w, http.StatusNotFound, ErrorCodeUnknownRoute, http.StatusText(http.StatusNotFound))
+		return
+	}
+
+	srv.log.Printf("handling: DELETE %s", r.RequestURI)
+
+	removed := ns.cache.InvalidateTag(tag)
+	keys := make([]string, len(removed))
+	for i, key := range removed {
+		keys[i] = remoteKey(key)
+	}
+	srv.writeValue(w, r, http.StatusOK, keys)
+}
+
+func (srv *Server) expunge(w http.ResponseWriter, r *http.Request, ns *namespace) {
+	if r.URL.Path != "/expunge" {
+		writeError(w, http.StatusNotFound, ErrorCodeUnknownRoute, http.StatusText(http.StatusNotFound))
+		return
+	}
+
+	srv.log.Printf("handling: POST %s", r.RequestURI)
+
+	ns.cache.Expunge()
+	w.WriteHeader(http.StatusNoContent)
+}
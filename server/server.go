@@ -14,13 +14,22 @@ import (
 var (
 	flagSet             *flag.FlagSet
 	flagPort            uint
+	flagAddress         string
+	flagBind            string
+	flagTLSCert         string
+	flagTLSKey          string
+	flagTLSClientCA     string
+	flagTokenFile       string
+	flagRateRead        float64
+	flagRateWrite       float64
+	flagRateAdmin       float64
 	flagCacheSize       uint
 	flagConnectionLimit uint
 )
 
 func server() error {
-	if flagPort == 0 || flagPort > math.MaxUint16 {
-		return fmt.Errorf("port must be: 0 < port <= %d", math.MaxUint16)
+	if flagPort > math.MaxUint16 {
+		return fmt.Errorf("port must be: 0 <= port <= %d", math.MaxUint16)
 	}
 	if flagCacheSize == 0 || flagCacheSize > math.MaxInt64 {
 		return fmt.Errorf("cachesize must be: 0 < cachesize <= %d", math.MaxInt64)
@@ -29,11 +38,38 @@ func server() error {
 		return fmt.Errorf("connlimit must be: 0 < connlimit <= %d", math.MaxUint16)
 	}
 
+	port := int(flagPort)
+	if port == 0 {
+		port = lruchal.RandomPort
+	}
+
 	config := &lruchal.ServerConfig{
-		Port:            int(flagPort),
+		Port:            port,
+		Address:         flagAddress,
+		BindAddress:     flagBind,
+		TokenFile:       flagTokenFile,
 		CacheSize:       int(flagCacheSize),
 		ConnectionLimit: int(flagConnectionLimit),
 	}
+	if flagTLSCert != "" || flagTLSKey != "" {
+		config.TLS = &lruchal.ServerTLSConfig{
+			CertFile:     flagTLSCert,
+			KeyFile:      flagTLSKey,
+			ClientCAFile: flagTLSClientCA,
+		}
+	}
+	if flagRateRead > 0 || flagRateWrite > 0 || flagRateAdmin > 0 {
+		config.RateLimit = new(lruchal.RateLimitConfig)
+		if flagRateRead > 0 {
+			config.RateLimit.Read = &lruchal.RateLimit{Rate: flagRateRead}
+		}
+		if flagRateWrite > 0 {
+			config.RateLimit.Write = &lruchal.RateLimit{Rate: flagRateWrite}
+		}
+		if flagRateAdmin > 0 {
+			config.RateLimit.Admin = &lruchal.RateLimit{Rate: flagRateAdmin}
+		}
+	}
 	srv, err := lruchal.NewServer(config)
 	if err != nil {
 		return err
@@ -41,14 +77,26 @@ func server() error {
 
 	log.Printf("Using cache size: %d", flagCacheSize)
 	log.Printf("Limiting concurrent connections to %d", flagConnectionLimit)
-	log.Printf("Listening on port %d", flagPort)
+	if err = srv.Listen(); err != nil {
+		return err
+	}
+	log.Printf("Listening on %s", srv.Addr())
 
 	return srv.Serve()
 }
 
 func main() {
 	flagSet = flag.NewFlagSet("lrutest", flag.ContinueOnError)
-	flagSet.UintVar(&flagPort, "port", lruchal.DefaultPort, "Port to listen on")
+	flagSet.UintVar(&flagPort, "port", lruchal.DefaultPort, "Port to listen on, 0 to choose a free port")
+	flagSet.StringVar(&flagAddress, "address", "", "Address to listen on instead of port, e.g. unix:///run/lruchal.sock or systemd://")
+	flagSet.StringVar(&flagBind, "bind", "", "Host or ip to bind to, defaults to all interfaces")
+	flagSet.StringVar(&flagTLSCert, "tls-cert", "", "PEM certificate file, enables tls along with tls-key")
+	flagSet.StringVar(&flagTLSKey, "tls-key", "", "PEM private key file")
+	flagSet.StringVar(&flagTLSClientCA, "tls-client-ca", "", "PEM CA file clients must present a certificate signed by")
+	flagSet.StringVar(&flagTokenFile, "token-file", "", "JSON file of bearer tokens, if set every request must carry one")
+	flagSet.Float64Var(&flagRateRead, "rate-read", 0, "Read requests per second allowed per client, 0 for no limit")
+	flagSet.Float64Var(&flagRateWrite, "rate-write", 0, "Write requests per second allowed per client, 0 for no limit")
+	flagSet.Float64Var(&flagRateAdmin, "rate-admin", 0, "Admin requests per second allowed per client, 0 for no limit")
 	flagSet.UintVar(&flagCacheSize, "cachesize", lruchal.DefaultCacheSize, "Size of LRU cache")
 	flagSet.UintVar(&flagConnectionLimit, "connlimit", lruchal.DefaultConnectionLimit, "Max allowable concurrent connections")
 	flagSet.Parse(os.Args[1:])
@@ -0,0 +1,175 @@
+package lruchal
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// DefaultTLSReloadInterval is how often the server checks it's certificate and key files for changes
+const DefaultTLSReloadInterval = 10 * time.Second
+
+// ServerTLSConfig enables tls on every listener of Server
+type ServerTLSConfig struct {
+	CertFile       string        // pem encoded certificate, followed by any intermediates
+	KeyFile        string        // pem encoded private key
+	MinVersion     uint16        // minimum version accepted, e.g. tls.VersionTLS13.  Defaults to tls.VersionTLS12
+	ClientCAFile   string        // optional, if set clients must present a certificate signed by one of the pem encoded CAs within
+	ReloadInterval time.Duration // how often CertFile and KeyFile are checked for changes, defaults to DefaultTLSReloadInterval
+}
+
+// newServerTLSConfig builds the tls configuration shared by the server's listeners.  The certificate is loaded
+// immediately so a bad pair is reported at startup, and reloaded whenever it's files change.
+func newServerTLSConfig(config *ServerTLSConfig, log Logger) (*tls.Config, error) {
+	if config.CertFile == "" || config.KeyFile == "" {
+		return nil, errors.New("tls requires both a certificate and key file")
+	}
+
+	cr := &certReloader{
+		log:      log,
+		certFile: config.CertFile,
+		keyFile:  config.KeyFile,
+		interval: DefaultTLSReloadInterval,
+		mu:       new(sync.Mutex),
+		checked:  time.Now(),
+	}
+	if config.ReloadInterval > 0 {
+		cr.interval = config.ReloadInterval
+	}
+	if err := cr.reload(); err != nil {
+		return nil, err
+	}
+
+	tc := &tls.Config{
+		GetCertificate: cr.getCertificate,
+		MinVersion:     tls.VersionTLS12,
+	}
+	if config.MinVersion > 0 {
+		tc.MinVersion = config.MinVersion
+	}
+	if config.ClientCAFile != "" {
+		pool, err := loadCertPool(config.ClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		tc.ClientCAs = pool
+		tc.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tc, nil
+}
+
+// withNextProtos returns a copy of tc negotiating the given application protocols
+func withNextProtos(tc *tls.Config, protos ...string) *tls.Config {
+	out := tc.Clone()
+	out.NextProtos = protos
+	return out
+}
+
+// certReloader serves a certificate loaded from disk, replacing it once it's files change so certificates may be
+// rotated without restarting the server
+type certReloader struct {
+	log      Logger
+	certFile string
+	keyFile  string
+	interval time.Duration
+
+	mu      *sync.Mutex
+	cert    *tls.Certificate
+	modTime time.Time // latest modification time of the files cert was loaded from
+	checked time.Time
+}
+
+func (cr *certReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	if time.Since(cr.checked) >= cr.interval {
+		cr.checked = time.Now()
+		// the previous certificate is kept until a valid pair is in place, as the files are unlikely to be replaced at
+		// exactly the same time
+		if err := cr.reload(); err != nil {
+			cr.log.Printf("unable to reload tls certificate: %s", err)
+		}
+	}
+	return cr.cert, nil
+}
+
+// reload loads the certificate if either file has changed since it was last loaded.  Caller must hold lock.
+func (cr *certReloader) reload() error {
+	var modTime time.Time
+	for _, name := range []string{cr.certFile, cr.keyFile} {
+		fi, err := os.Stat(name)
+		if err != nil {
+			return fmt.Errorf("unable to stat \"%s\": %s", name, err)
+		}
+		if fi.ModTime().After(modTime) {
+			modTime = fi.ModTime()
+		}
+	}
+	if cr.cert != nil && modTime.Equal(cr.modTime) {
+		return nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(cr.certFile, cr.keyFile)
+	if err != nil {
+		return fmt.Errorf("unable to load key pair: %s", err)
+	}
+	cr.cert, cr.modTime = &cert, modTime
+	return nil
+}
+
+func loadCertPool(name string) (*x509.CertPool, error) {
+	b, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read \"%s\": %s", name, err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(b) {
+		return nil, fmt.Errorf("no certificates found in \"%s\"", name)
+	}
+	return pool, nil
+}
+
+// ClientTLSConfig enables tls on connections made by a client.  The same configuration is accepted by Client,
+// BinaryClient and GRPCClient.
+type ClientTLSConfig struct {
+	CAFile     string // optional, pem encoded CAs the server's certificate is verified with, defaults to the system's
+	CertFile   string // optional, pem encoded certificate presented to servers requiring mutual tls
+	KeyFile    string // optional, pem encoded private key of CertFile
+	ServerName string // name the server's certificate is verified against, defaults to the host dialed but required for unix sockets
+}
+
+// tlsConfig builds the tls configuration for connecting to addr
+func (c *ClientTLSConfig) tlsConfig(addr string) (*tls.Config, error) {
+	tc := &tls.Config{
+		ServerName: c.ServerName,
+		MinVersion: tls.VersionTLS12,
+	}
+	if tc.ServerName == "" {
+		if host, _, err := net.SplitHostPort(addr); err == nil {
+			tc.ServerName = host
+		} else {
+			tc.ServerName = addr
+		}
+	}
+	if c.CAFile != "" {
+		pool, err := loadCertPool(c.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		tc.RootCAs = pool
+	}
+	if c.CertFile != "" || c.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load key pair: %s", err)
+		}
+		tc.Certificates = []tls.Certificate{cert}
+	}
+	return tc, nil
+}
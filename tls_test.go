@@ -0,0 +1,258 @@
+package lruchal_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dcarbone/lruchal"
+)
+
+// testCA issues certificates for the tls tests, writing them as pem files within dir
+type testCA struct {
+	t    *testing.T
+	dir  string
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	file string
+}
+
+func newTestCA(t *testing.T, dir, name string) *testCA {
+	ca := &testCA{t: t, dir: dir}
+	ca.cert, ca.key = ca.issue(&x509.Certificate{
+		Subject:               pkix.Name{CommonName: name},
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}, name)
+	ca.file = filepath.Join(dir, name+".pem")
+	return ca
+}
+
+// issue signs template with the ca, or itself if the ca has not been created yet, writing name.pem and name-key.pem
+func (ca *testCA) issue(template *x509.Certificate, name string) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		ca.t.Logf("Unable to generate key: %s", err)
+		ca.t.FailNow()
+	}
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	template.SerialNumber = serial
+	template.NotBefore = time.Now().Add(-time.Minute)
+	template.NotAfter = time.Now().Add(time.Hour)
+
+	parent, signer := template, key
+	if ca.cert != nil {
+		parent, signer = ca.cert, ca.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, signer)
+	if err != nil {
+		ca.t.Logf("Unable to create certificate: %s", err)
+		ca.t.FailNow()
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		ca.t.Logf("Unable to marshal key: %s", err)
+		ca.t.FailNow()
+	}
+	ca.write(name+".pem", &pem.Block{Type: "CERTIFICATE", Bytes: der})
+	ca.write(name+"-key.pem", &pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+
+	cert, _ := x509.ParseCertificate(der)
+	return cert, key
+}
+
+func (ca *testCA) write(name string, block *pem.Block) {
+	if err := ioutil.WriteFile(filepath.Join(ca.dir, name), pem.EncodeToMemory(block), 0600); err != nil {
+		ca.t.Logf("Unable to write %s: %s", name, err)
+		ca.t.FailNow()
+	}
+}
+
+// server issues a certificate valid for 127.0.0.1, returning it's cert and key files
+func (ca *testCA) server(name string) (*x509.Certificate, string, string) {
+	cert, _ := ca.issue(&x509.Certificate{
+		Subject:     pkix.Name{CommonName: name},
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, name)
+	return cert, filepath.Join(ca.dir, name+".pem"), filepath.Join(ca.dir, name+"-key.pem")
+}
+
+// client issues a client certificate, returning it's cert and key files
+func (ca *testCA) client(name string) (string, string) {
+	ca.issue(&x509.Certificate{
+		Subject:     pkix.Name{CommonName: name},
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, name)
+	return filepath.Join(ca.dir, name+".pem"), filepath.Join(ca.dir, name+"-key.pem")
+}
+
+func TestTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lruchal")
+	if err != nil {
+		t.Logf("Unable to create temp dir: %s", err)
+		t.FailNow()
+	}
+	defer os.RemoveAll(dir)
+
+	ca := newTestCA(t, dir, "ca")
+	_, certFile, keyFile := ca.server("server")
+	clientCert, clientKey := ca.client("client")
+
+	startServer := func(t *testing.T, config *lruchal.ServerTLSConfig) *lruchal.Server {
+		srv := newTestServer(t, &lruchal.ServerConfig{
+			Port:        lruchal.RandomPort,
+			BinaryPort:  lruchal.RandomPort,
+			GRPCPort:    lruchal.RandomPort,
+			BindAddress: "127.0.0.1",
+			TLS:         config,
+		})
+		if err := srv.Listen(); err != nil {
+			t.Logf("Unable to listen: %s", err)
+			t.FailNow()
+		}
+		go srv.Serve()
+		t.Cleanup(func() { srv.Close() })
+		return srv
+	}
+
+	put := func(srv *lruchal.Server, config *lruchal.ClientTLSConfig) error {
+		client, err := lruchal.NewClient(&lruchal.ClientConfig{Address: srv.Addr().String(), TLS: config})
+		if err != nil {
+			return err
+		}
+		defer client.Close()
+		return client.Put(lruchal.Item{Key: "key1", Value: "value1", TTL: "1m"})
+	}
+
+	t.Run("Client", func(t *testing.T) {
+		srv := startServer(t, &lruchal.ServerTLSConfig{CertFile: certFile, KeyFile: keyFile})
+
+		if err := put(srv, &lruchal.ClientTLSConfig{CAFile: ca.file}); err != nil {
+			t.Logf("Unable to put over tls: %s", err)
+			t.FailNow()
+		}
+		if err := put(srv, nil); err == nil {
+			t.Log("Expected plaintext put to fail")
+			t.FailNow()
+		}
+		if err := put(srv, new(lruchal.ClientTLSConfig)); err == nil {
+			t.Log("Expected put to fail without trusting the ca")
+			t.FailNow()
+		}
+	})
+
+	t.Run("MutualTLS", func(t *testing.T) {
+		srv := startServer(t, &lruchal.ServerTLSConfig{CertFile: certFile, KeyFile: keyFile, ClientCAFile: ca.file, MinVersion: tls.VersionTLS13})
+
+		if err := put(srv, &lruchal.ClientTLSConfig{CAFile: ca.file}); err == nil {
+			t.Log("Expected put to fail without a client certificate")
+			t.FailNow()
+		}
+		if err := put(srv, &lruchal.ClientTLSConfig{CAFile: ca.file, CertFile: clientCert, KeyFile: clientKey}); err != nil {
+			t.Logf("Unable to put with client certificate: %s", err)
+			t.FailNow()
+		}
+
+		client, err := lruchal.NewBinaryClient(&lruchal.BinaryClientConfig{
+			Address: srv.BinaryAddr().String(),
+			TLS:     &lruchal.ClientTLSConfig{CAFile: ca.file, CertFile: clientCert, KeyFile: clientKey},
+		})
+		if err != nil {
+			t.Logf("Unable to create binary client: %s", err)
+			t.FailNow()
+		}
+		defer client.Close()
+		if v, err := client.Get("key1"); err != nil || v != "value1" {
+			t.Logf("Expected value1 over binary protocol, saw %v (err=%v)", v, err)
+			t.FailNow()
+		}
+	})
+
+	t.Run("GRPC", func(t *testing.T) {
+		srv := startServer(t, &lruchal.ServerTLSConfig{CertFile: certFile, KeyFile: keyFile})
+
+		client, err := lruchal.NewGRPCClient(&lruchal.GRPCClientConfig{
+			Address: srv.GRPCAddr().String(),
+			TLS:     &lruchal.ClientTLSConfig{CAFile: ca.file},
+		})
+		if err != nil {
+			t.Logf("Unable to create grpc client: %s", err)
+			t.FailNow()
+		}
+		defer client.Close()
+		if _, err := client.Len(); err != nil {
+			t.Logf("Unable to call over tls: %s", err)
+			t.FailNow()
+		}
+	})
+
+	t.Run("Reload", func(t *testing.T) {
+		_, reloadCert, reloadKey := ca.server("reload")
+		srv := startServer(t, &lruchal.ServerTLSConfig{CertFile: reloadCert, KeyFile: reloadKey, ReloadInterval: time.Millisecond})
+
+		serial := func() *big.Int {
+			pool := x509.NewCertPool()
+			pool.AddCert(ca.cert)
+			conn, err := tls.Dial("tcp", srv.Addr().String(), &tls.Config{RootCAs: pool})
+			if err != nil {
+				t.Logf("Unable to dial: %s", err)
+				t.FailNow()
+			}
+			defer conn.Close()
+			return conn.ConnectionState().PeerCertificates[0].SerialNumber
+		}
+
+		before := serial()
+		rotated, _, _ := ca.server("reload")
+		// ensure the change is seen on file systems with coarse modification times
+		future := time.Now().Add(time.Minute)
+		os.Chtimes(reloadCert, future, future)
+		os.Chtimes(reloadKey, future, future)
+		time.Sleep(5 * time.Millisecond)
+
+		if after := serial(); after.Cmp(rotated.SerialNumber) != 0 || after.Cmp(before) == 0 {
+			t.Logf("Expected rotated certificate %s, saw %s", rotated.SerialNumber, after)
+			t.FailNow()
+		}
+	})
+
+	t.Run("InvalidConfig", func(t *testing.T) {
+		for _, config := range []*lruchal.ServerTLSConfig{
+			{CertFile: certFile},
+			{CertFile: certFile, KeyFile: clientKey},
+			{CertFile: certFile, KeyFile: keyFile, ClientCAFile: keyFile},
+		} {
+			if _, err := lruchal.NewServer(&lruchal.ServerConfig{TLS: config}); err == nil {
+				t.Logf("Expected error with %+v", config)
+				t.FailNow()
+			}
+		}
+
+		// there is no host to verify a unix socket's certificate against
+		if _, err := lruchal.NewClient(&lruchal.ClientConfig{Address: "unix:///tmp/lruchal.sock", TLS: new(lruchal.ClientTLSConfig)}); err == nil {
+			t.Log("Expected error with tls over a unix socket without a ServerName")
+			t.FailNow()
+		}
+		client, err := lruchal.NewClient(&lruchal.ClientConfig{Address: "unix:///tmp/lruchal.sock", TLS: &lruchal.ClientTLSConfig{ServerName: "localhost"}})
+		if err != nil {
+			t.Logf("Unable to create client with tls over a unix socket: %s", err)
+			t.FailNow()
+		}
+		client.Close()
+	})
+}
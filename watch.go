@@ -0,0 +1,347 @@
+package lruchal
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WatchReset is sent in place of any events a watcher missed, either because it resumed from an event no longer
+// buffered or from a previous run of the server.  Watchers should assume any key may have changed.
+const WatchReset ChangeType = "reset"
+
+const (
+	// watchBacklogSize is the number of events each namespace buffers for watchers resuming with a last event id, and
+	// the number buffered per watcher.  Watchers that fall further behind than this are disconnected.
+	watchBacklogSize = 1024
+
+	// watchKeepAlive is how often a comment is written to idle /watch streams, so proxies and clients can tell a quiet
+	// stream from a dead one
+	watchKeepAlive = 15 * time.Second
+)
+
+// WatchEvent describes a change to a key, as streamed by /watch
+type WatchEvent struct {
+	ID   uint64     `json:"id"`
+	Type ChangeType `json:"type"`
+	Key  string     `json:"key,omitempty"`
+}
+
+// changeFeed numbers the changes made to a namespace's MemoryCache, buffering the most recent for watchers resuming
+// after a disconnect
+type changeFeed struct {
+	mu          *sync.Mutex
+	nextID      uint64
+	backlog     [watchBacklogSize]WatchEvent // ring buffer, indexed by id
+	subscribers map[chan WatchEvent]struct{}
+	closed      bool
+}
+
+func newChangeFeed() *changeFeed {
+	return &changeFeed{
+		mu: new(sync.Mutex),
+		// ids start from the current time so they keep increasing across restarts of the server and re-creations of the
+		// namespace, allowing ids from a previous feed to be recognized as such
+		nextID:      uint64(time.Now().UnixNano()),
+		subscribers: make(map[chan WatchEvent]struct{}),
+	}
+}
+
+// publish is set as the MemoryCache's change func, so must not block
+func (f *changeFeed) publish(c Change) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return
+	}
+	ev := WatchEvent{ID: f.nextID, Type: c.Type, Key: remoteKey(c.Key)}
+	f.backlog[ev.ID%watchBacklogSize] = ev
+	f.nextID++
+	for ch := range f.subscribers {
+		select {
+		case ch <- ev:
+		default:
+			delete(f.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// subscribe returns a channel receiving every event published from now on.  If resume is true, the events published
+// after lastID are returned as well, or a reset event if they are no longer buffered.  Returns a nil channel if the
+// feed has been closed.
+func (f *changeFeed) subscribe(lastID uint64, resume bool) (chan WatchEvent, []WatchEvent, *WatchEvent) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return nil, nil, nil
+	}
+
+	ch := make(chan WatchEvent, watchBacklogSize)
+	f.subscribers[ch] = struct{}{}
+	if !resume {
+		return ch, nil, nil
+	}
+
+	oldest := f.nextID - watchBacklogSize
+	if lastID >= f.nextID || lastID+1 < oldest {
+		return ch, nil, &WatchEvent{ID: f.nextID - 1, Type: WatchReset}
+	}
+	missed := make([]WatchEvent, 0, f.nextID-lastID-1)
+	for id := lastID + 1; id < f.nextID; id++ {
+		missed = append(missed, f.backlog[id%watchBacklogSize])
+	}
+	return ch, missed, nil
+}
+
+func (f *changeFeed) unsubscribe(ch chan WatchEvent) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.subscribers[ch]; ok {
+		delete(f.subscribers, ch)
+		close(ch)
+	}
+}
+
+// close disconnects every subscriber, called once the namespace has been deleted
+func (f *changeFeed) close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	for ch := range f.subscribers {
+		delete(f.subscribers, ch)
+		close(ch)
+	}
+}
+
+// watch streams every change to keys within the namespace beginning with the prefix query parameter as server-sent
+// events, until the client goes away.  Clients may resume from where they left off by sending the id of the last event
+// seen as the Last-Event-ID header or last_event_id query parameter.
+func (srv *Server) watch(w http.ResponseWriter, r *http.Request, ns *namespace) {
+	if r.URL.Path != "/watch" {
+		writeError(w, http.StatusNotFound, ErrorCodeUnknownRoute, http.StatusText(http.StatusNotFound))
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, ErrorCodeUnprocessable, "Streaming not supported")
+		return
+	}
+
+	srv.log.Printf("handling: GET %s", r.RequestURI)
+
+	query := r.URL.Query()
+	prefix := query.Get("prefix")
+	last := r.Header.Get("Last-Event-ID")
+	if last == "" {
+		last = query.Get("last_event_id")
+	}
+	// an unparseable id is treated as one that is no longer buffered
+	lastID, _ := strconv.ParseUint(last, 10, 64)
+
+	ch, missed, reset := ns.feed.subscribe(lastID, last != "")
+	if ch == nil {
+		writeError(w, http.StatusNotFound, ErrorCodeNotFound, fmt.Sprintf("Namespace \"%s\" not found", ns.config.Name))
+		return
+	}
+	defer ns.feed.unsubscribe(ch)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	if reset != nil {
+		if err := writeWatchEvent(w, *reset); err != nil {
+			return
+		}
+	}
+	for _, ev := range missed {
+		if !strings.HasPrefix(ev.Key, prefix) {
+			continue
+		}
+		if err := writeWatchEvent(w, ev); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	ticker := time.NewTicker(watchKeepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case ev, ok := <-ch:
+			if !ok {
+				return
+			}
+			if !strings.HasPrefix(ev.Key, prefix) {
+				continue
+			}
+			if err := writeWatchEvent(w, ev); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func writeWatchEvent(w http.ResponseWriter, ev WatchEvent) error {
+	b, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", ev.ID, ev.Type, b)
+	return err
+}
+
+// watchReconnectBackoff is how long Client.Watch waits between attempts to re-establish a closed stream
+const watchReconnectBackoff = time.Second
+
+// Watch streams changes to keys beginning with prefix until ctx is done or the client is closed, after which the
+// returned channel is closed.  The stream is established before returning, so errors such as the namespace not existing
+// are returned immediately.  If the stream is later interrupted it is resumed from the last event received, with a
+// WatchReset event sent in place of any events that could not be recovered.
+func (c *Client) Watch(ctx context.Context, prefix string) (<-chan WatchEvent, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		select {
+		case <-c.ctx.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	body, err := c.openWatch(ctx, prefix, "")
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	ch := make(chan WatchEvent)
+	go c.consumeWatch(ctx, cancel, prefix, body, ch)
+	return ch, nil
+}
+
+func (c *Client) openWatch(ctx context.Context, prefix, lastID string) (io.ReadCloser, error) {
+	req, err := c.newRequest("GET", "/watch?prefix="+url.QueryEscape(prefix), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	if lastID != "" {
+		req.Header.Set("Last-Event-ID", lastID)
+	}
+
+	resp, err := c.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != 200 {
+		defer resp.Body.Close()
+		b, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read response: %s", err)
+		}
+		return nil, newServerError(resp.StatusCode, b)
+	}
+	return resp.Body, nil
+}
+
+// consumeWatch delivers events from body to ch, reconnecting whenever the stream is closed until ctx is done
+func (c *Client) consumeWatch(ctx context.Context, cancel context.CancelFunc, prefix string, body io.ReadCloser, ch chan<- WatchEvent) {
+	defer close(ch)
+	defer cancel()
+
+	var lastID string
+	for {
+		err := readWatchEvents(ctx, body, ch, &lastID)
+		body.Close()
+		if ctx.Err() != nil {
+			return
+		}
+		c.log.Printf("watch stream closed: %s", err)
+
+		for {
+			timer := time.NewTimer(watchReconnectBackoff)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			}
+			if body, err = c.openWatch(ctx, prefix, lastID); err == nil {
+				break
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			c.log.Printf("unable to re-establish watch stream: %s", err)
+		}
+	}
+}
+
+// readWatchEvents parses server-sent events from r, sending each to ch and recording it's id in lastID.  Returns once
+// the stream ends or ctx is done.
+func readWatchEvents(ctx context.Context, r io.Reader, ch chan<- WatchEvent, lastID *string) error {
+	var id, data string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			if data == "" {
+				continue
+			}
+			ev := WatchEvent{}
+			if err := json.Unmarshal([]byte(data), &ev); err != nil {
+				return fmt.Errorf("unable to unmarshal event: %s", err)
+			}
+			data = ""
+			select {
+			case ch <- ev:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			if id != "" {
+				*lastID = id
+			}
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+		field, value := line, ""
+		if i := strings.IndexByte(line, ':'); i >= 0 {
+			field, value = line[:i], strings.TrimPrefix(line[i+1:], " ")
+		}
+		switch field {
+		case "id":
+			id = value
+		case "data":
+			if data != "" {
+				data += "\n"
+			}
+			data += value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return io.EOF
+}
@@ -0,0 +1,149 @@
+package lruchal_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dcarbone/lruchal"
+)
+
+func TestWatch(t *testing.T) {
+	srv, err := lruchal.NewServer(&lruchal.ServerConfig{
+		Port:        lruchal.RandomPort,
+		BindAddress: "127.0.0.1",
+		Logger:      log.New(ioutil.Discard, "", 0),
+	})
+	if err != nil {
+		t.Logf("Unable to create server: %s", err)
+		t.FailNow()
+	}
+	if err := srv.Listen(); err != nil {
+		t.Logf("Unable to listen: %s", err)
+		t.FailNow()
+	}
+	go srv.Serve()
+	defer srv.Close()
+
+	newClient := func(t *testing.T, namespace string) *lruchal.Client {
+		client, err := lruchal.NewClient(&lruchal.ClientConfig{Address: srv.Addr().String(), Namespace: namespace})
+		if err != nil {
+			t.Logf("Unable to create client: %s", err)
+			t.FailNow()
+		}
+		t.Cleanup(func() { client.Close() })
+		return client
+	}
+
+	next := func(t *testing.T, events <-chan lruchal.WatchEvent) lruchal.WatchEvent {
+		select {
+		case ev := <-events:
+			return ev
+		case <-time.After(5 * time.Second):
+			t.Log("Timed out waiting for event")
+			t.FailNow()
+		}
+		return lruchal.WatchEvent{}
+	}
+
+	t.Run("Events", func(t *testing.T) {
+		client := newClient(t, "")
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		events, err := client.Watch(ctx, "events-")
+		if err != nil {
+			t.Logf("Unable to watch: %s", err)
+			t.FailNow()
+		}
+
+		client.Put(lruchal.Item{Key: "ignored", Value: 1, TTL: "1m"})
+		client.Put(lruchal.Item{Key: "events-1", Value: 1, TTL: "1m"})
+		client.Remove("events-1")
+
+		if ev := next(t, events); ev.Type != lruchal.ChangePut || ev.Key != "events-1" {
+			t.Logf("Expected put of events-1, saw %+v", ev)
+			t.FailNow()
+		}
+		if ev := next(t, events); ev.Type != lruchal.ChangeRemove || ev.Key != "events-1" {
+			t.Logf("Expected remove of events-1, saw %+v", ev)
+			t.FailNow()
+		}
+
+		cancel()
+		if _, ok := <-events; ok {
+			t.Log("Expected events to be closed once cancelled")
+			t.FailNow()
+		}
+	})
+
+	t.Run("Resume", func(t *testing.T) {
+		client := newClient(t, "")
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		events, err := client.Watch(ctx, "resume-")
+		if err != nil {
+			t.Logf("Unable to watch: %s", err)
+			t.FailNow()
+		}
+		client.Put(lruchal.Item{Key: "resume-1", Value: 1, TTL: "1m"})
+		first := next(t, events)
+		cancel()
+
+		// changes made while disconnected are sent on resuming
+		client.Put(lruchal.Item{Key: "resume-2", Value: 2, TTL: "1m"})
+		if lines := watchFrom(t, srv.Addr().String(), "resume-", fmt.Sprint(first.ID)); lines[1] != "event: put" || !strings.Contains(lines[2], `"key":"resume-2"`) {
+			t.Logf("Expected put of resume-2, saw %q", lines)
+			t.FailNow()
+		}
+
+		// unknown ids are reset
+		if lines := watchFrom(t, srv.Addr().String(), "resume-", "1"); lines[1] != "event: reset" {
+			t.Logf("Expected reset, saw %q", lines)
+			t.FailNow()
+		}
+	})
+
+	t.Run("MissingNamespace", func(t *testing.T) {
+		client := newClient(t, "missing")
+		if _, err := client.Watch(context.Background(), ""); !errors.Is(err, lruchal.ErrNotFound) {
+			t.Logf("Expected ErrNotFound, saw %v", err)
+			t.FailNow()
+		}
+	})
+}
+
+// watchFrom opens /watch on the server at addr with a Last-Event-ID, returning the id, event and data lines of the first
+// event
+func watchFrom(t *testing.T, addr, prefix, lastID string) []string {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req, _ := http.NewRequest("GET", fmt.Sprintf("http://%s/watch?prefix=%s", addr, prefix), nil)
+	req.Header.Set("Last-Event-ID", lastID)
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		t.Logf("Unable to watch: %s", err)
+		t.FailNow()
+	}
+	defer resp.Body.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(resp.Body)
+	for len(lines) < 3 && scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if len(lines) < 3 {
+		t.Logf("Expected an event, saw %q", lines)
+		t.FailNow()
+	}
+	return lines
+}